@@ -14,6 +14,7 @@ import (
 	clientset "github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned"
 	informers "github.com/vgkowski/cassandra-operator/pkg/client/informers/externalversions"
 	cassandraController "github.com/vgkowski/cassandra-operator/pkg/controller"
+	"github.com/vgkowski/cassandra-operator/pkg/webhook"
 )
 
 var (
@@ -21,6 +22,10 @@ var (
 	kubeconfig string
 	baseImage string
 	namespace string
+	webhookAddr string
+	tlsCertFile string
+	tlsKeyFile string
+	jolokiaPort int
 )
 
 func main() {
@@ -48,11 +53,20 @@ func main() {
 	kubeInformerFactory := kubeinformers.NewFilteredSharedInformerFactory(kubeClient, time.Second*30,namespace,nil)
 	cassandraClusterInformerFactory := informers.NewFilteredSharedInformerFactory(cassandraClusterClient, time.Second*30,namespace,nil)
 
-	controller := cassandraController.NewController(cfg,kubeClient,namespace, cassandraClusterClient, kubeInformerFactory, cassandraClusterInformerFactory)
+	controller := cassandraController.NewController(cfg,kubeClient,namespace,baseImage,jolokiaPort, cassandraClusterClient, kubeInformerFactory, cassandraClusterInformerFactory)
 
 	go kubeInformerFactory.Start(stopCh)
 	go cassandraClusterInformerFactory.Start(stopCh)
 
+	// the admission webhook is only served when a certificate is provided
+	if tlsCertFile != "" {
+		go func() {
+			if err := webhook.NewServer(webhookAddr, tlsCertFile, tlsKeyFile, controller, controller).Run(stopCh); err != nil {
+				glog.Fatalf("Error running the admission webhook: %s", err.Error())
+			}
+		}()
+	}
+
 	if err = controller.Run(2, stopCh); err != nil {
 		glog.Fatalf("Error running controller: %s", err.Error())
 	}
@@ -63,4 +77,8 @@ func init() {
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&baseImage, "baseImage", "cassandra:3.0.15", "Base image to use when spinning up the Cassandra components.")
 	flag.StringVar(&namespace, "namespace", os.Getenv("NAMESPACE"), "namespace to deploy the controller")
+	flag.StringVar(&webhookAddr, "webhookAddr", ":8443", "Address of the admission webhook server.")
+	flag.StringVar(&tlsCertFile, "tlsCertFile", "", "Certificate of the admission webhook server. The webhook is disabled if empty.")
+	flag.StringVar(&tlsKeyFile, "tlsKeyFile", "", "Private key of the admission webhook server.")
+	flag.IntVar(&jolokiaPort, "jolokiaPort", 0, "Port of the Jolokia agent of the Cassandra nodes. nodetool is run in the pods if 0.")
 }
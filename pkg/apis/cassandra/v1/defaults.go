@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// DefaultNbNodes is the number of nodes of a cluster which doesn't set it
+	DefaultNbNodes = 3
+	// DefaultNbToken is the num_tokens of the nodes, the default of Cassandra
+	DefaultNbToken = 256
+	// PasswordAuthenticator is the authenticator of the new clusters, the clients must log in
+	PasswordAuthenticator = "PasswordAuthenticator"
+	// CassandraAuthorizer is the authorizer of the new clusters logging in the clients, the permissions of the
+	// roles are checked
+	CassandraAuthorizer = "CassandraAuthorizer"
+)
+
+// SetDefaults fills the fields of the spec left empty. It's used by the mutating webhook and by the controller
+// for the clusters created without the webhook, the defaulted values are stored back in the resource so they
+// don't change when the defaults of the operator change. defaultImage is the image of the operator configuration
+func SetDefaults(cc *CassandraCluster, defaultImage string) {
+	spec := &cc.Spec
+	if spec.BaseImage == "" {
+		spec.BaseImage = defaultImage
+	}
+	if spec.NbNodes == nil {
+		nbNodes := int32(DefaultNbNodes)
+		spec.NbNodes = &nbNodes
+	}
+	// the data outlives the resource unless its deletion is requested
+	if spec.DeletionPolicy == "" {
+		spec.DeletionPolicy = DeletionRetain
+	}
+	if spec.CassandraSpec.NbToken == 0 {
+		spec.CassandraSpec.NbToken = DefaultNbToken
+	}
+	if spec.CassandraSpec.MaxHeapSize == "" || spec.CassandraSpec.HeapNewSize == "" {
+		// the heap is shared by all the datacenters so it must fit in the smallest containers
+		memory := spec.Memory
+		for _, dc := range spec.Datacenters {
+			if dc.Memory != "" && smallerQuantity(dc.Memory, memory) {
+				memory = dc.Memory
+			}
+		}
+		maxHeap, heapNew, ok := heapSizes(memory, spec.Cpu)
+		if ok && spec.CassandraSpec.MaxHeapSize == "" {
+			spec.CassandraSpec.MaxHeapSize = maxHeap
+		}
+		if ok && spec.CassandraSpec.HeapNewSize == "" {
+			spec.CassandraSpec.HeapNewSize = heapNew
+		}
+	}
+}
+
+// SetAuthDefaults fills the authenticator and the authorizer of a new cluster. It's only used on creation: the
+// clusters already deployed keep the AllowAll authenticator of Cassandra, changing it would lock their clients out
+func SetAuthDefaults(cc *CassandraCluster) {
+	config := &cc.Spec.CassandraSpec.Config
+	if config.Authenticator == "" {
+		config.Authenticator = PasswordAuthenticator
+	}
+	// the authorizer needs authenticated clients
+	if config.Authorizer == "" && strings.HasSuffix(config.Authenticator, PasswordAuthenticator) {
+		config.Authorizer = CassandraAuthorizer
+	}
+}
+
+// heapSizes computes the heap sizes like cassandra-env.sh does from the memory and cpu of the containers:
+// the max heap is max(min(1/2 ram, 1024MB), min(1/4 ram, 8192MB)) and the young generation is
+// min(100MB per core, 1/4 max heap)
+func heapSizes(memory string, cpu string) (string, string, bool) {
+	mem, err := resource.ParseQuantity(memory)
+	if err != nil {
+		return "", "", false
+	}
+	ramMB := mem.Value() >> 20
+	maxHeapMB := max64(min64(ramMB/2, 1024), min64(ramMB/4, 8192))
+
+	cores := int64(1)
+	if q, err := resource.ParseQuantity(cpu); err == nil && q.MilliValue() > 1000 {
+		cores = (q.MilliValue() + 999) / 1000
+	}
+	heapNewMB := min64(100*cores, maxHeapMB/4)
+	if maxHeapMB <= 0 || heapNewMB <= 0 {
+		return "", "", false
+	}
+	return fmt.Sprintf("%dM", maxHeapMB), fmt.Sprintf("%dM", heapNewMB), true
+}
+
+// smallerQuantity returns true if a is smaller than b or if b isn't a valid quantity
+func smallerQuantity(a string, b string) bool {
+	qa, err := resource.ParseQuantity(a)
+	if err != nil {
+		return false
+	}
+	qb, err := resource.ParseQuantity(b)
+	return err != nil || qa.Cmp(qb) < 0
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
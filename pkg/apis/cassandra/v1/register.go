@@ -35,6 +35,14 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&CassandraCluster{},
 		&CassandraClusterList{},
+		&CassandraBackup{},
+		&CassandraBackupList{},
+		&CassandraKeyspace{},
+		&CassandraKeyspaceList{},
+		&CassandraRestore{},
+		&CassandraRestoreList{},
+		&CassandraRole{},
+		&CassandraRoleList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
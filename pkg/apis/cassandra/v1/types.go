@@ -1,15 +1,18 @@
 package v1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type CassandraCluster struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 	Spec CassandraClusterSpec `json:"spec"`
+	Status CassandraClusterStatus `json:"status,omitempty"`
 }
 
 type CassandraClusterSpec struct {
@@ -21,12 +24,139 @@ type CassandraClusterSpec struct {
 	AntiAffinity bool `json:"antiAffinity"`
 	RackLabel string `json:"rackLabel"`
 	DCLabel string `json:"dcLabel"`
+	// Racks of the cluster, each rack is deployed in its own statefulset.
+	// If empty, all the nodes are deployed in a single default rack
+	Racks []Rack `json:"racks,omitempty"`
+	// Datacenters of the cluster. If empty, the cluster has a single datacenter built from NbNodes and Racks
+	Datacenters []Datacenter `json:"datacenters,omitempty"`
+	// SeedsPerDatacenter is the number of seeds of each datacenter, spread across its racks. Defaults to 3
+	SeedsPerDatacenter int32 `json:"seedsPerDatacenter,omitempty"`
 	CassandraSpec CassandraSpec `json:"spec"`
+	Repair RepairSpec `json:"repair,omitempty"`
+	Upgrade UpgradeSpec `json:"upgrade,omitempty"`
+	Readiness ReadinessSpec `json:"readiness,omitempty"`
+	ClientService ClientServiceSpec `json:"clientService,omitempty"`
+	DisruptionBudget DisruptionBudgetSpec `json:"disruptionBudget,omitempty"`
+	// DeletionPolicy is what happens to the data of the nodes when the CassandraCluster is deleted. Defaults to Retain
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// FinalBackup is the storage of the backup taken before the deletion with the SnapshotThenDelete policy
+	FinalBackup *BackupStorage `json:"finalBackup,omitempty"`
+}
+
+type Datacenter struct {
+	// Name of the datacenter as seen by Cassandra
+	Name string `json:"name"`
+	// LabelValue is the value of the DCLabel node label where the pods of the datacenter are scheduled
+	LabelValue string `json:"labelValue,omitempty"`
+	// NbNodes in the datacenter, spread across its racks. Defaults to the cluster NbNodes
+	NbNodes *int32 `json:"nbNodes,omitempty"`
+	Racks []Rack `json:"racks,omitempty"`
+	// Cpu, Memory and Data override the cluster resources for the datacenter
+	Cpu string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+	Data *Storage `json:"data,omitempty"`
+}
+
+type Rack struct {
+	// Name of the rack as seen by Cassandra
+	Name string `json:"name"`
+	// NbNodes in the rack. If not set, NbNodes of the cluster are spread evenly across the racks
+	NbNodes *int32 `json:"nbNodes,omitempty"`
+	// LabelValue is the value of the RackLabel node label where the pods of the rack are scheduled
+	LabelValue string `json:"labelValue,omitempty"`
 }
 
 type Storage struct {
 	StorageVolume string `json:"storageVolume"`
 	StorageClass string `json:"storageClass"`
+	// DeleteOnScaleDown deletes the PVC of a node once it has been decommissioned
+	DeleteOnScaleDown bool `json:"deleteOnScaleDown,omitempty"`
+}
+
+type RepairSpec struct {
+	// TimeoutSeconds is the maximum duration of the repair of a keyspace on a node. Defaults to 6 hours
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+	// Retries of the repair of a keyspace on a node before it's considered as failed
+	Retries int32 `json:"retries,omitempty"`
+	// Schedule of the repairs in cron format ("0 2 * * 0"). No scheduled repair if empty
+	Schedule string `json:"schedule,omitempty"`
+	// Keyspaces to repair. All the replicated keyspaces if empty
+	Keyspaces []string `json:"keyspaces,omitempty"`
+	// ExcludeKeyspaces are never repaired
+	ExcludeKeyspaces []string `json:"excludeKeyspaces,omitempty"`
+	// Incremental repairs only the data not repaired yet instead of running a full repair
+	Incremental bool `json:"incremental,omitempty"`
+	// Parallelism of the validation compactions on the replicas. Defaults to parallel
+	Parallelism RepairParallelism `json:"parallelism,omitempty"`
+	// MaxAgeSeconds after which a keyspace not repaired successfully is reported as overdue.
+	// It must be lower than the gc_grace_seconds of the tables. Defaults to 10 days
+	MaxAgeSeconds int64 `json:"maxAgeSeconds,omitempty"`
+}
+
+// RepairParallelism is the nodetool repair parallelism option
+type RepairParallelism string
+
+const (
+	RepairSequential RepairParallelism = "sequential"
+	RepairParallel   RepairParallelism = "parallel"
+	RepairDCParallel RepairParallelism = "dc-parallel"
+)
+
+type UpgradeSpec struct {
+	// Paused stops the rolling upgrade once the node being upgraded is back in the ring
+	Paused bool `json:"paused,omitempty"`
+	// TimeoutSeconds is the time given to an upgraded node to be back Up and Normal in the ring. Defaults to 10 minutes
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+	// FailurePolicy is applied when an upgraded node doesn't come back in time. Defaults to Pause
+	FailurePolicy UpgradeFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// UpgradeFailurePolicy is the action taken when a node fails to upgrade
+type UpgradeFailurePolicy string
+
+const (
+	// UpgradeFailurePause waits for the failed node to come back before upgrading the next one
+	UpgradeFailurePause UpgradeFailurePolicy = "Pause"
+	// UpgradeFailureRollback restarts the upgraded nodes with the previous image
+	UpgradeFailureRollback UpgradeFailurePolicy = "Rollback"
+)
+
+// ClientServiceSpec configures the service exposing the CQL port of the nodes to the clients
+type ClientServiceSpec struct {
+	// Type of the service: ClusterIP, NodePort or LoadBalancer. Defaults to ClusterIP
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// Annotations of the service, like the ones configuring the load balancer of the cloud provider
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// NodePort of the CQL port with the NodePort and LoadBalancer types. Allocated by Kubernetes if not set
+	NodePort int32 `json:"nodePort,omitempty"`
+	// LoadBalancerSourceRanges restricts the clients allowed by the load balancer
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+}
+
+// DisruptionBudgetSpec configures the PodDisruptionBudgets of the nodes
+type DisruptionBudgetSpec struct {
+	// MaxUnavailable is the number of nodes of the cluster, or of each rack, disrupted at the same time. Defaults to 1
+	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+}
+
+// DeletionPolicy is what happens to the PVCs of the nodes when the CassandraCluster is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionRetain keeps the PVCs, a cluster created again with the same name and topology finds its data
+	DeletionRetain DeletionPolicy = "Retain"
+	// DeletionSnapshotThenDelete backs up the cluster to the final backup storage, then deletes the PVCs
+	DeletionSnapshotThenDelete DeletionPolicy = "SnapshotThenDelete"
+	// DeletionDelete deletes the PVCs
+	DeletionDelete DeletionPolicy = "Delete"
+)
+
+// ReadinessSpec configures the wait for the ring to settle before the next step of a scaling, an upgrade or a repair
+type ReadinessSpec struct {
+	// TimeoutSeconds is the time given to the ring to settle before a warning is raised. Defaults to 10 minutes
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+	// MaxBackoffSeconds is the maximum delay between two checks of the ring. Defaults to 60 seconds
+	MaxBackoffSeconds int64 `json:"maxBackoffSeconds,omitempty"`
 }
 
 type CassandraSpec struct {
@@ -35,6 +165,268 @@ type CassandraSpec struct {
 	HeapNewSize string `json:"heapNewSize"`
 	InterNodeTLS bool `json:"interNodeTLS"`
 	ClientTLS bool `json:"clientTLS"`
+	// Config is rendered in the cassandra.yaml and jvm.options of the nodes
+	Config CassandraConfig `json:"config,omitempty"`
+	// TLS configures the certificates of the nodes used by InterNodeTLS and ClientTLS
+	TLS TLSSpec `json:"tls,omitempty"`
+}
+
+type TLSSpec struct {
+	// CASecretName is a secret with the tls.crt and tls.key of the CA signing the certificates of the nodes.
+	// The operator generates a CA in the <cluster>-ca secret if empty
+	CASecretName string `json:"caSecretName,omitempty"`
+	// CertificateValidityDays is the validity of the certificates of the nodes. Defaults to 365
+	CertificateValidityDays int32 `json:"certificateValidityDays,omitempty"`
+	// RenewBeforeDays is how long before their expiration the certificates are renewed. Defaults to 30
+	RenewBeforeDays int32 `json:"renewBeforeDays,omitempty"`
+}
+
+type CassandraConfig struct {
+	ConcurrentReads *int32 `json:"concurrentReads,omitempty"`
+	ConcurrentWrites *int32 `json:"concurrentWrites,omitempty"`
+	ConcurrentCounterWrites *int32 `json:"concurrentCounterWrites,omitempty"`
+	CompactionThroughputMBPerSec *int32 `json:"compactionThroughputMBPerSec,omitempty"`
+	ReadRequestTimeoutMs *int64 `json:"readRequestTimeoutMs,omitempty"`
+	WriteRequestTimeoutMs *int64 `json:"writeRequestTimeoutMs,omitempty"`
+	RangeRequestTimeoutMs *int64 `json:"rangeRequestTimeoutMs,omitempty"`
+	RequestTimeoutMs *int64 `json:"requestTimeoutMs,omitempty"`
+	// Authenticator and Authorizer are the class names of the authentication and authorization backends
+	Authenticator string `json:"authenticator,omitempty"`
+	Authorizer string `json:"authorizer,omitempty"`
+	// Overrides are set in cassandra.yaml after all the other settings, the values are YAML ("true", "64", "[a, b]")
+	Overrides map[string]string `json:"overrides,omitempty"`
+	// JVMOptions are appended to jvm.options
+	JVMOptions []string `json:"jvmOptions,omitempty"`
+}
+
+// CassandraClusterPhase is a label for the lifecycle step the cluster is in
+type CassandraClusterPhase string
+
+const (
+	ClusterPhaseCreating  CassandraClusterPhase = "Creating"
+	ClusterPhaseRunning   CassandraClusterPhase = "Running"
+	ClusterPhaseScaling   CassandraClusterPhase = "Scaling"
+	ClusterPhaseUpgrading CassandraClusterPhase = "Upgrading"
+	ClusterPhaseFailed    CassandraClusterPhase = "Failed"
+)
+
+type CassandraClusterStatus struct {
+	// ObservedGeneration is the generation of the spec the status was computed from
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	Phase CassandraClusterPhase `json:"phase,omitempty"`
+	DesiredNodes int32 `json:"desiredNodes"`
+	ReadyNodes int32 `json:"readyNodes"`
+	Nodes []CassandraNodeStatus `json:"nodes,omitempty"`
+	Conditions []CassandraClusterCondition `json:"conditions,omitempty"`
+	// Decommission is the node currently leaving the ring during a scale down
+	Decommission *DecommissionStatus `json:"decommission,omitempty"`
+	// PendingRepair is the reason of a repair waiting for the cluster to be stable to start
+	PendingRepair string `json:"pendingRepair,omitempty"`
+	// PendingRepairKeyspaces restricts the pending repair to these keyspaces, all the keyspaces are repaired if empty
+	PendingRepairKeyspaces []string `json:"pendingRepairKeyspaces,omitempty"`
+	// LastRepairRequest is the last value of the repair annotation handled by the operator
+	LastRepairRequest string `json:"lastRepairRequest,omitempty"`
+	// Repair is the last or current repair of the cluster
+	Repair *RepairStatus `json:"repair,omitempty"`
+	// LastScheduledRepair is the last time a repair was requested by the repair schedule
+	LastScheduledRepair *metav1.Time `json:"lastScheduledRepair,omitempty"`
+	// KeyspaceRepairs are the last successful repairs of each keyspace
+	KeyspaceRepairs []KeyspaceRepairStatus `json:"keyspaceRepairs,omitempty"`
+	// Image is the Cassandra image running on the nodes, it only changes once an upgrade completes
+	Image string `json:"image,omitempty"`
+	// ConfigHash is the hash of the configuration of the nodes, it only changes once the rolling restart completes
+	ConfigHash string `json:"configHash,omitempty"`
+	// Upgrade is the upgrade in progress or the last one which didn't complete
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+	// CertificatesExpiry is the expiration of the certificates of the nodes when TLS is enabled
+	CertificatesExpiry *metav1.Time `json:"certificatesExpiry,omitempty"`
+	// AuthBootstrapped is true once the superuser of the secret replaced the default cassandra superuser
+	AuthBootstrapped bool `json:"authBootstrapped,omitempty"`
+	// RingNotSettledSince is the time since when the next step waits for the ring to settle
+	RingNotSettledSince *metav1.Time `json:"ringNotSettledSince,omitempty"`
+	// RingMembers is how the node of each pod was last seen Up and Normal in the ring
+	RingMembers []RingMember `json:"ringMembers,omitempty"`
+	// Replacement is the dead node being replaced by a pod restarted with an empty volume
+	Replacement *ReplacementStatus `json:"replacement,omitempty"`
+	// Seeds are the pods selected as seeds, published by the seed service
+	Seeds []string `json:"seeds,omitempty"`
+	// Legacy is true when the cluster was deployed before the racks, the first rack keeps the statefulset <name>
+	// and the headless service <name>-node
+	Legacy bool `json:"legacy,omitempty"`
+}
+
+// UpgradePhase is the progress of an upgrade
+type UpgradePhase string
+
+const (
+	// UpgradeRunning means the nodes are restarted one at a time with the new image
+	UpgradeRunning UpgradePhase = "Running"
+	// UpgradePaused means the upgrade is paused by the spec or waits for a failed node
+	UpgradePaused UpgradePhase = "Paused"
+	// UpgradeSSTables means all the nodes run the new image and their sstables are rewritten in the new format
+	UpgradeSSTables UpgradePhase = "UpgradingSSTables"
+	// UpgradeRollingBack means the upgraded nodes are restarted with the previous image
+	UpgradeRollingBack UpgradePhase = "RollingBack"
+	// UpgradeRolledBack means all the nodes run the previous image again
+	UpgradeRolledBack UpgradePhase = "RolledBack"
+	// UpgradeRejected means the version jump isn't supported
+	UpgradeRejected UpgradePhase = "Rejected"
+)
+
+type UpgradeStatus struct {
+	FromImage string `json:"fromImage"`
+	ToImage string `json:"toImage"`
+	// FromConfigHash and ToConfigHash are the configuration of the nodes before and after the rolling restart
+	FromConfigHash string `json:"fromConfigHash,omitempty"`
+	ToConfigHash string `json:"toConfigHash,omitempty"`
+	Phase UpgradePhase `json:"phase"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// StatefulSet is the rack being upgraded or rolled back and Partition the ordinal of its last pod not upgraded
+	// or not rolled back plus one
+	StatefulSet string `json:"statefulSet,omitempty"`
+	Partition int32 `json:"partition,omitempty"`
+	// UpgradedStatefulSets are the racks running the new image, or left to roll back
+	UpgradedStatefulSets []string `json:"upgradedStatefulSets,omitempty"`
+	// Pod is the node being restarted or whose sstables are being upgraded
+	Pod string `json:"pod,omitempty"`
+	PodStartTime *metav1.Time `json:"podStartTime,omitempty"`
+	// Command is the number of the sstables upgrade of the pod, following its progress
+	Command int64 `json:"command,omitempty"`
+	// Attempts are the failed sstables upgrades of the pod, PodStartTime is then the time of the last failure
+	Attempts int32 `json:"attempts,omitempty"`
+	// SSTablesUpgradedPods are the nodes whose sstables were upgraded
+	SSTablesUpgradedPods []string `json:"sstablesUpgradedPods,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type KeyspaceRepairStatus struct {
+	Keyspace string `json:"keyspace"`
+	LastSuccessTime metav1.Time `json:"lastSuccessTime"`
+}
+
+// DecommissionPhase is the progress of a node decommission
+type DecommissionPhase string
+
+const (
+	// DecommissionRunning means the node is streaming its data to the other nodes
+	DecommissionRunning DecommissionPhase = "Decommissioning"
+	// DecommissionDone means the node left the ring and its pod can be removed
+	DecommissionDone DecommissionPhase = "Decommissioned"
+)
+
+type DecommissionStatus struct {
+	Pod string `json:"pod"`
+	StatefulSet string `json:"statefulSet"`
+	// HostID identifies the node in the ring, its IP changes when the pod restarts
+	HostID string `json:"hostID,omitempty"`
+	// Issued is the instance of the pod the decommission was issued to, as "<pod uid>/<restarts>"
+	Issued string `json:"issued,omitempty"`
+	Phase DecommissionPhase `json:"phase"`
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// RingMember is the identity of the node of a pod in the ring
+type RingMember struct {
+	Pod string `json:"pod"`
+	Address string `json:"address"`
+	HostID string `json:"hostID"`
+	// VolumeUID is the UID of the PVC of the data of the node
+	VolumeUID string `json:"volumeUID"`
+}
+
+// ReplacementPhase is the progress of the replacement of a dead node
+type ReplacementPhase string
+
+const (
+	// ReplacementRestarting means the pod must be restarted with the address of the dead node
+	ReplacementRestarting ReplacementPhase = "Restarting"
+	// ReplacementStreaming means the node streams the data of the dead node before joining the ring
+	ReplacementStreaming ReplacementPhase = "Streaming"
+)
+
+type ReplacementStatus struct {
+	Pod string `json:"pod"`
+	// DeadAddress and HostID are the address and the host ID of the dead node in the ring
+	DeadAddress string `json:"deadAddress"`
+	HostID string `json:"hostID"`
+	Phase ReplacementPhase `json:"phase"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+}
+
+// CassandraNodeState is the state of a single Cassandra pod as seen by the operator
+type CassandraNodeState string
+
+const (
+	NodeStatePending  CassandraNodeState = "Pending"
+	NodeStateStarting CassandraNodeState = "Starting"
+	NodeStateReady    CassandraNodeState = "Ready"
+	NodeStateFailed   CassandraNodeState = "Failed"
+)
+
+type CassandraNodeStatus struct {
+	Name string `json:"name"`
+	PodIP string `json:"podIP,omitempty"`
+	HostIP string `json:"hostIP,omitempty"`
+	State CassandraNodeState `json:"state"`
+}
+
+// RepairPhase is the progress of a repair or of one of its tasks
+type RepairPhase string
+
+const (
+	RepairPending   RepairPhase = "Pending"
+	RepairRunning   RepairPhase = "Running"
+	RepairSucceeded RepairPhase = "Succeeded"
+	RepairFailed    RepairPhase = "Failed"
+)
+
+type RepairStatus struct {
+	// Trigger is the reason of the repair
+	Trigger string `json:"trigger"`
+	Phase RepairPhase `json:"phase"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Tasks are run sequentially, one keyspace of one node at a time
+	Tasks []RepairTask `json:"tasks,omitempty"`
+}
+
+type RepairTask struct {
+	Pod string `json:"pod"`
+	Keyspace string `json:"keyspace"`
+	Phase RepairPhase `json:"phase"`
+	Attempts int32 `json:"attempts,omitempty"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Command is the number of the repair on the node, following its progress
+	Command int64 `json:"command,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// CassandraClusterConditionType is the type of a CassandraCluster condition
+type CassandraClusterConditionType string
+
+const (
+	// ClusterReady is true when all the desired nodes are ready
+	ClusterReady CassandraClusterConditionType = "Ready"
+	// ClusterScaling is true while the number of nodes converges to the desired one
+	ClusterScaling CassandraClusterConditionType = "Scaling"
+	// ClusterUpgrading is true while pods are rolled to a new template
+	ClusterUpgrading CassandraClusterConditionType = "Upgrading"
+	// ClusterRepairing is true while a repair is running
+	ClusterRepairing CassandraClusterConditionType = "Repairing"
+	// ClusterRepairOverdue is true when a keyspace wasn't repaired successfully within the repair max age
+	ClusterRepairOverdue CassandraClusterConditionType = "RepairOverdue"
+	// ClusterRingNotSettled is true when the ring didn't settle within the readiness timeout
+	ClusterRingNotSettled CassandraClusterConditionType = "RingNotSettled"
+	// ClusterSpecInvalid is true when the spec of the cluster is rejected by the operator, nothing is deployed
+	ClusterSpecInvalid CassandraClusterConditionType = "SpecInvalid"
+)
+
+type CassandraClusterCondition struct {
+	Type CassandraClusterConditionType `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -44,3 +436,299 @@ type CassandraClusterList struct {
 	metav1.ListMeta `json:"metadata"`
 	Items           []CassandraCluster `json:"items"`
 }
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec CassandraBackupSpec `json:"spec"`
+	Status CassandraBackupStatus `json:"status,omitempty"`
+}
+
+type CassandraBackupSpec struct {
+	// Cluster is the name of the CassandraCluster to backup, in the same namespace
+	Cluster string `json:"cluster"`
+	// Keyspaces to backup. All the keyspaces if empty
+	Keyspaces []string `json:"keyspaces,omitempty"`
+	Storage BackupStorage `json:"storage"`
+}
+
+type BackupStorage struct {
+	// Endpoint of the S3 compatible object storage (host:port)
+	Endpoint string `json:"endpoint"`
+	Bucket string `json:"bucket"`
+	// Prefix of the keys of the objects in the bucket
+	Prefix string `json:"prefix,omitempty"`
+	Region string `json:"region,omitempty"`
+	// Insecure uses HTTP instead of HTTPS to reach the endpoint
+	Insecure bool `json:"insecure,omitempty"`
+	// SecretName is the secret holding the accessKeyId and secretAccessKey of the object storage
+	SecretName string `json:"secretName"`
+}
+
+// BackupPhase is the progress of a backup
+type BackupPhase string
+
+const (
+	BackupSnapshotting BackupPhase = "Snapshotting"
+	BackupUploading    BackupPhase = "Uploading"
+	BackupCompleted    BackupPhase = "Completed"
+	BackupFailed       BackupPhase = "Failed"
+)
+
+// BackupNodePhase is the progress of the backup of a node
+type BackupNodePhase string
+
+const (
+	BackupNodePending     BackupNodePhase = "Pending"
+	BackupNodeSnapshotted BackupNodePhase = "Snapshotted"
+	BackupNodeUploaded    BackupNodePhase = "Uploaded"
+	BackupNodeFailed      BackupNodePhase = "Failed"
+)
+
+type CassandraBackupStatus struct {
+	Phase BackupPhase `json:"phase,omitempty"`
+	// SnapshotName is the tag of the snapshot taken on every node
+	SnapshotName string `json:"snapshotName,omitempty"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Manifest is the key of the backup manifest in the bucket
+	Manifest string `json:"manifest,omitempty"`
+	Nodes []BackupNodeStatus `json:"nodes,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type BackupNodeStatus struct {
+	Pod string `json:"pod"`
+	Phase BackupNodePhase `json:"phase"`
+	Files int32 `json:"files,omitempty"`
+	Bytes int64 `json:"bytes,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CassandraBackup `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec CassandraRestoreSpec `json:"spec"`
+	Status CassandraRestoreStatus `json:"status,omitempty"`
+}
+
+// RestoreMode is the way the backup is restored
+type RestoreMode string
+
+const (
+	// RestoreNewCluster rebuilds a CassandraCluster not deployed yet with the tokens of the backup,
+	// the snapshot files are copied on the nodes owning the same tokens and loaded with nodetool refresh
+	RestoreNewCluster RestoreMode = "NewCluster"
+	// RestoreExistingCluster streams the tables of the backup into a running CassandraCluster with sstableloader
+	RestoreExistingCluster RestoreMode = "ExistingCluster"
+)
+
+type CassandraRestoreSpec struct {
+	// Cluster is the name of the CassandraCluster to restore into, in the same namespace
+	Cluster string `json:"cluster"`
+	// Mode is NewCluster or ExistingCluster
+	Mode RestoreMode `json:"mode"`
+	// SourceCluster and Backup are the names of the backed up cluster and of the backup, they locate the
+	// manifest of the backup in the storage
+	SourceCluster string `json:"sourceCluster"`
+	Backup string `json:"backup"`
+	// Keyspaces to restore. All the keyspaces of the backup if empty
+	Keyspaces []string `json:"keyspaces,omitempty"`
+	Storage BackupStorage `json:"storage"`
+}
+
+// RestorePhase is the progress of a restore
+type RestorePhase string
+
+const (
+	// RestoreSeeding waits for the new cluster to be deployed with the tokens of the backup
+	RestoreSeeding   RestorePhase = "Seeding"
+	RestoreLoading   RestorePhase = "Loading"
+	RestoreCompleted RestorePhase = "Completed"
+	RestoreFailed    RestorePhase = "Failed"
+)
+
+// RestoreNodePhase is the progress of the restore of the files of a node of the backup
+type RestoreNodePhase string
+
+const (
+	RestoreNodePending  RestoreNodePhase = "Pending"
+	RestoreNodeRestored RestoreNodePhase = "Restored"
+	RestoreNodeFailed   RestoreNodePhase = "Failed"
+)
+
+type CassandraRestoreStatus struct {
+	Phase RestorePhase `json:"phase,omitempty"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// SchemaRestored is true once the schema of the backup is created in the new cluster
+	SchemaRestored bool `json:"schemaRestored,omitempty"`
+	Nodes []RestoreNodeStatus `json:"nodes,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type RestoreNodeStatus struct {
+	// SourcePod is the node of the backup
+	SourcePod string `json:"sourcePod"`
+	// Pod is the node of the cluster where the files are restored
+	Pod string `json:"pod"`
+	Phase RestoreNodePhase `json:"phase"`
+	Files int32 `json:"files,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CassandraRestore `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec CassandraRoleSpec `json:"spec"`
+	Status CassandraRoleStatus `json:"status,omitempty"`
+}
+
+type CassandraRoleSpec struct {
+	// Cluster is the name of the CassandraCluster of the role, in the same namespace
+	Cluster string `json:"cluster"`
+	// RoleName is the name of the role in Cassandra. Defaults to the name of the resource
+	RoleName string `json:"roleName,omitempty"`
+	Login bool `json:"login,omitempty"`
+	Superuser bool `json:"superuser,omitempty"`
+	// PasswordSecret is the key of the secret holding the password of the role
+	PasswordSecret *corev1.SecretKeySelector `json:"passwordSecret,omitempty"`
+	// Grants are the permissions of the role on the keyspaces and tables, the other permissions are revoked
+	Grants []CassandraGrant `json:"grants,omitempty"`
+}
+
+type CassandraGrant struct {
+	// Permissions granted: ALL, CREATE, ALTER, DROP, SELECT, MODIFY or AUTHORIZE
+	Permissions []string `json:"permissions"`
+	// Keyspace of the grant, all the keyspaces if empty
+	Keyspace string `json:"keyspace,omitempty"`
+	// Table of the keyspace, all its tables if empty
+	Table string `json:"table,omitempty"`
+}
+
+// RolePhase is the state of a CassandraRole
+type RolePhase string
+
+const (
+	// RolePending waits for the cluster to be running
+	RolePending RolePhase = "Pending"
+	RoleReady   RolePhase = "Ready"
+	RoleFailed  RolePhase = "Failed"
+)
+
+type CassandraRoleStatus struct {
+	Phase RolePhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec applied to the role
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// PasswordVersion is the resource version of the password secret applied to the role
+	PasswordVersion string `json:"passwordVersion,omitempty"`
+	// LastSyncTime is the last time the permissions of the role were checked
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CassandraRole `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraKeyspace struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec CassandraKeyspaceSpec `json:"spec"`
+	Status CassandraKeyspaceStatus `json:"status,omitempty"`
+}
+
+type CassandraKeyspaceSpec struct {
+	// Cluster is the name of the CassandraCluster of the keyspace, in the same namespace
+	Cluster string `json:"cluster"`
+	// KeyspaceName is the name of the keyspace in Cassandra. Defaults to the name of the resource
+	KeyspaceName string `json:"keyspaceName,omitempty"`
+	Replication KeyspaceReplication `json:"replication"`
+	// DurableWrites defaults to true
+	DurableWrites *bool `json:"durableWrites,omitempty"`
+	// Tables are created if they don't exist, they aren't altered afterwards
+	Tables []CassandraTable `json:"tables,omitempty"`
+}
+
+// ReplicationStrategy is the replication class of a keyspace
+type ReplicationStrategy string
+
+const (
+	SimpleStrategy          ReplicationStrategy = "SimpleStrategy"
+	NetworkTopologyStrategy ReplicationStrategy = "NetworkTopologyStrategy"
+)
+
+type KeyspaceReplication struct {
+	// Strategy defaults to the NetworkTopologyStrategy
+	Strategy ReplicationStrategy `json:"strategy,omitempty"`
+	// ReplicationFactor is the replication factor of the SimpleStrategy
+	ReplicationFactor int32 `json:"replicationFactor,omitempty"`
+	// Datacenters are the replication factors of the NetworkTopologyStrategy by datacenter
+	Datacenters map[string]int32 `json:"datacenters,omitempty"`
+}
+
+type CassandraTable struct {
+	Name string `json:"name"`
+	// Schema is the end of the CREATE TABLE statement following the name of the table:
+	// the columns, the primary key and the options
+	Schema string `json:"schema"`
+}
+
+// KeyspacePhase is the state of a CassandraKeyspace
+type KeyspacePhase string
+
+const (
+	// KeyspacePending waits for the cluster to be running
+	KeyspacePending KeyspacePhase = "Pending"
+	KeyspaceReady   KeyspacePhase = "Ready"
+	KeyspaceFailed  KeyspacePhase = "Failed"
+)
+
+type CassandraKeyspaceStatus struct {
+	Phase KeyspacePhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec applied to the keyspace
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Replication is the replication factor applied by datacenter, under the empty datacenter for the SimpleStrategy
+	Replication map[string]int32 `json:"replication,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CassandraKeyspaceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CassandraKeyspace `json:"items"`
+}
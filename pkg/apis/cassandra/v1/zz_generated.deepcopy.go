@@ -21,15 +21,174 @@ limitations under the License.
 package v1
 
 import (
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupNodeStatus) DeepCopyInto(out *BackupNodeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupNodeStatus.
+func (in *BackupNodeStatus) DeepCopy() *BackupNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStorage) DeepCopyInto(out *BackupStorage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorage.
+func (in *BackupStorage) DeepCopy() *BackupStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraBackup) DeepCopyInto(out *CassandraBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraBackup.
+func (in *CassandraBackup) DeepCopy() *CassandraBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraBackupList) DeepCopyInto(out *CassandraBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CassandraBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraBackupList.
+func (in *CassandraBackupList) DeepCopy() *CassandraBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraBackupSpec) DeepCopyInto(out *CassandraBackupSpec) {
+	*out = *in
+	if in.Keyspaces != nil {
+		in, out := &in.Keyspaces, &out.Keyspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Storage = in.Storage
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraBackupSpec.
+func (in *CassandraBackupSpec) DeepCopy() *CassandraBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraBackupStatus) DeepCopyInto(out *CassandraBackupStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]BackupNodeStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraBackupStatus.
+func (in *CassandraBackupStatus) DeepCopy() *CassandraBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CassandraCluster) DeepCopyInto(out *CassandraCluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -52,6 +211,23 @@ func (in *CassandraCluster) DeepCopyObject() runtime.Object {
 	}
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraClusterCondition) DeepCopyInto(out *CassandraClusterCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraClusterCondition.
+func (in *CassandraClusterCondition) DeepCopy() *CassandraClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CassandraClusterList) DeepCopyInto(out *CassandraClusterList) {
 	*out = *in
@@ -99,7 +275,35 @@ func (in *CassandraClusterSpec) DeepCopyInto(out *CassandraClusterSpec) {
 			**out = **in
 		}
 	}
-	out.CassandraSpec = in.CassandraSpec
+	if in.Racks != nil {
+		in, out := &in.Racks, &out.Racks
+		*out = make([]Rack, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Datacenters != nil {
+		in, out := &in.Datacenters, &out.Datacenters
+		*out = make([]Datacenter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.CassandraSpec.DeepCopyInto(&out.CassandraSpec)
+	in.Repair.DeepCopyInto(&out.Repair)
+	out.Upgrade = in.Upgrade
+	out.Readiness = in.Readiness
+	in.ClientService.DeepCopyInto(&out.ClientService)
+	out.DisruptionBudget = in.DisruptionBudget
+	if in.FinalBackup != nil {
+		in, out := &in.FinalBackup, &out.FinalBackup
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(BackupStorage)
+			**out = **in
+		}
+	}
 	return
 }
 
@@ -114,33 +318,1069 @@ func (in *CassandraClusterSpec) DeepCopy() *CassandraClusterSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CassandraSpec) DeepCopyInto(out *CassandraSpec) {
+func (in *CassandraClusterStatus) DeepCopyInto(out *CassandraClusterStatus) {
 	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]CassandraNodeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]CassandraClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Decommission != nil {
+		in, out := &in.Decommission, &out.Decommission
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(DecommissionStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PendingRepairKeyspaces != nil {
+		in, out := &in.PendingRepairKeyspaces, &out.PendingRepairKeyspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repair != nil {
+		in, out := &in.Repair, &out.Repair
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RepairStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.LastScheduledRepair != nil {
+		in, out := &in.LastScheduledRepair, &out.LastScheduledRepair
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.KeyspaceRepairs != nil {
+		in, out := &in.KeyspaceRepairs, &out.KeyspaceRepairs
+		*out = make([]KeyspaceRepairStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(UpgradeStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.CertificatesExpiry != nil {
+		in, out := &in.CertificatesExpiry, &out.CertificatesExpiry
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.RingNotSettledSince != nil {
+		in, out := &in.RingNotSettledSince, &out.RingNotSettledSince
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.RingMembers != nil {
+		in, out := &in.RingMembers, &out.RingMembers
+		*out = make([]RingMember, len(*in))
+		copy(*out, *in)
+	}
+	if in.Replacement != nil {
+		in, out := &in.Replacement, &out.Replacement
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ReplacementStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Seeds != nil {
+		in, out := &in.Seeds, &out.Seeds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraSpec.
-func (in *CassandraSpec) DeepCopy() *CassandraSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraClusterStatus.
+func (in *CassandraClusterStatus) DeepCopy() *CassandraClusterStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(CassandraSpec)
+	out := new(CassandraClusterStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Storage) DeepCopyInto(out *Storage) {
+func (in *CassandraConfig) DeepCopyInto(out *CassandraConfig) {
 	*out = *in
+	if in.ConcurrentReads != nil {
+		in, out := &in.ConcurrentReads, &out.ConcurrentReads
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.ConcurrentWrites != nil {
+		in, out := &in.ConcurrentWrites, &out.ConcurrentWrites
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.ConcurrentCounterWrites != nil {
+		in, out := &in.ConcurrentCounterWrites, &out.ConcurrentCounterWrites
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.CompactionThroughputMBPerSec != nil {
+		in, out := &in.CompactionThroughputMBPerSec, &out.CompactionThroughputMBPerSec
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.ReadRequestTimeoutMs != nil {
+		in, out := &in.ReadRequestTimeoutMs, &out.ReadRequestTimeoutMs
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int64)
+			**out = **in
+		}
+	}
+	if in.WriteRequestTimeoutMs != nil {
+		in, out := &in.WriteRequestTimeoutMs, &out.WriteRequestTimeoutMs
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int64)
+			**out = **in
+		}
+	}
+	if in.RangeRequestTimeoutMs != nil {
+		in, out := &in.RangeRequestTimeoutMs, &out.RangeRequestTimeoutMs
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int64)
+			**out = **in
+		}
+	}
+	if in.RequestTimeoutMs != nil {
+		in, out := &in.RequestTimeoutMs, &out.RequestTimeoutMs
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int64)
+			**out = **in
+		}
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.JVMOptions != nil {
+		in, out := &in.JVMOptions, &out.JVMOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Storage.
-func (in *Storage) DeepCopy() *Storage {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraConfig.
+func (in *CassandraConfig) DeepCopy() *CassandraConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(Storage)
+	out := new(CassandraConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraGrant) DeepCopyInto(out *CassandraGrant) {
+	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraGrant.
+func (in *CassandraGrant) DeepCopy() *CassandraGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraKeyspace) DeepCopyInto(out *CassandraKeyspace) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraKeyspace.
+func (in *CassandraKeyspace) DeepCopy() *CassandraKeyspace {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraKeyspace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraKeyspace) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraKeyspaceList) DeepCopyInto(out *CassandraKeyspaceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CassandraKeyspace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraKeyspaceList.
+func (in *CassandraKeyspaceList) DeepCopy() *CassandraKeyspaceList {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraKeyspaceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraKeyspaceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraKeyspaceSpec) DeepCopyInto(out *CassandraKeyspaceSpec) {
+	*out = *in
+	in.Replication.DeepCopyInto(&out.Replication)
+	if in.DurableWrites != nil {
+		in, out := &in.DurableWrites, &out.DurableWrites
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
+		}
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]CassandraTable, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraKeyspaceSpec.
+func (in *CassandraKeyspaceSpec) DeepCopy() *CassandraKeyspaceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraKeyspaceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraKeyspaceStatus) DeepCopyInto(out *CassandraKeyspaceStatus) {
+	*out = *in
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraKeyspaceStatus.
+func (in *CassandraKeyspaceStatus) DeepCopy() *CassandraKeyspaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraKeyspaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraNodeStatus) DeepCopyInto(out *CassandraNodeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraNodeStatus.
+func (in *CassandraNodeStatus) DeepCopy() *CassandraNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRestore) DeepCopyInto(out *CassandraRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRestore.
+func (in *CassandraRestore) DeepCopy() *CassandraRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRestoreList) DeepCopyInto(out *CassandraRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CassandraRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRestoreList.
+func (in *CassandraRestoreList) DeepCopy() *CassandraRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRestoreSpec) DeepCopyInto(out *CassandraRestoreSpec) {
+	*out = *in
+	if in.Keyspaces != nil {
+		in, out := &in.Keyspaces, &out.Keyspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Storage = in.Storage
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRestoreSpec.
+func (in *CassandraRestoreSpec) DeepCopy() *CassandraRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRestoreStatus) DeepCopyInto(out *CassandraRestoreStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]RestoreNodeStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRestoreStatus.
+func (in *CassandraRestoreStatus) DeepCopy() *CassandraRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRole) DeepCopyInto(out *CassandraRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRole.
+func (in *CassandraRole) DeepCopy() *CassandraRole {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRoleList) DeepCopyInto(out *CassandraRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CassandraRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRoleList.
+func (in *CassandraRoleList) DeepCopy() *CassandraRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CassandraRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRoleSpec) DeepCopyInto(out *CassandraRoleSpec) {
+	*out = *in
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.SecretKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]CassandraGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRoleSpec.
+func (in *CassandraRoleSpec) DeepCopy() *CassandraRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraRoleStatus) DeepCopyInto(out *CassandraRoleStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraRoleStatus.
+func (in *CassandraRoleStatus) DeepCopy() *CassandraRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraSpec) DeepCopyInto(out *CassandraSpec) {
+	*out = *in
+	in.Config.DeepCopyInto(&out.Config)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraSpec.
+func (in *CassandraSpec) DeepCopy() *CassandraSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CassandraTable) DeepCopyInto(out *CassandraTable) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CassandraTable.
+func (in *CassandraTable) DeepCopy() *CassandraTable {
+	if in == nil {
+		return nil
+	}
+	out := new(CassandraTable)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientServiceSpec) DeepCopyInto(out *ClientServiceSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientServiceSpec.
+func (in *ClientServiceSpec) DeepCopy() *ClientServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Datacenter) DeepCopyInto(out *Datacenter) {
+	*out = *in
+	if in.NbNodes != nil {
+		in, out := &in.NbNodes, &out.NbNodes
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.Racks != nil {
+		in, out := &in.Racks, &out.Racks
+		*out = make([]Rack, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(Storage)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Datacenter.
+func (in *Datacenter) DeepCopy() *Datacenter {
+	if in == nil {
+		return nil
+	}
+	out := new(Datacenter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecommissionStatus) DeepCopyInto(out *DecommissionStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecommissionStatus.
+func (in *DecommissionStatus) DeepCopy() *DecommissionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DecommissionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DisruptionBudgetSpec) DeepCopyInto(out *DisruptionBudgetSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DisruptionBudgetSpec.
+func (in *DisruptionBudgetSpec) DeepCopy() *DisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyspaceRepairStatus) DeepCopyInto(out *KeyspaceRepairStatus) {
+	*out = *in
+	in.LastSuccessTime.DeepCopyInto(&out.LastSuccessTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyspaceRepairStatus.
+func (in *KeyspaceRepairStatus) DeepCopy() *KeyspaceRepairStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyspaceRepairStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyspaceReplication) DeepCopyInto(out *KeyspaceReplication) {
+	*out = *in
+	if in.Datacenters != nil {
+		in, out := &in.Datacenters, &out.Datacenters
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyspaceReplication.
+func (in *KeyspaceReplication) DeepCopy() *KeyspaceReplication {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyspaceReplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rack) DeepCopyInto(out *Rack) {
+	*out = *in
+	if in.NbNodes != nil {
+		in, out := &in.NbNodes, &out.NbNodes
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rack.
+func (in *Rack) DeepCopy() *Rack {
+	if in == nil {
+		return nil
+	}
+	out := new(Rack)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessSpec) DeepCopyInto(out *ReadinessSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessSpec.
+func (in *ReadinessSpec) DeepCopy() *ReadinessSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepairSpec) DeepCopyInto(out *RepairSpec) {
+	*out = *in
+	if in.Keyspaces != nil {
+		in, out := &in.Keyspaces, &out.Keyspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeKeyspaces != nil {
+		in, out := &in.ExcludeKeyspaces, &out.ExcludeKeyspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepairSpec.
+func (in *RepairSpec) DeepCopy() *RepairSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepairSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepairStatus) DeepCopyInto(out *RepairStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = make([]RepairTask, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepairStatus.
+func (in *RepairStatus) DeepCopy() *RepairStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepairStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepairTask) DeepCopyInto(out *RepairTask) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepairTask.
+func (in *RepairTask) DeepCopy() *RepairTask {
+	if in == nil {
+		return nil
+	}
+	out := new(RepairTask)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplacementStatus) DeepCopyInto(out *ReplacementStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplacementStatus.
+func (in *ReplacementStatus) DeepCopy() *ReplacementStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplacementStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreNodeStatus) DeepCopyInto(out *RestoreNodeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreNodeStatus.
+func (in *RestoreNodeStatus) DeepCopy() *RestoreNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RingMember) DeepCopyInto(out *RingMember) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RingMember.
+func (in *RingMember) DeepCopy() *RingMember {
+	if in == nil {
+		return nil
+	}
+	out := new(RingMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Storage) DeepCopyInto(out *Storage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Storage.
+func (in *Storage) DeepCopy() *Storage {
+	if in == nil {
+		return nil
+	}
+	out := new(Storage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSpec) DeepCopyInto(out *TLSSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSpec.
+func (in *TLSSpec) DeepCopy() *TLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeSpec) DeepCopyInto(out *UpgradeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeSpec.
+func (in *UpgradeSpec) DeepCopy() *UpgradeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.UpgradedStatefulSets != nil {
+		in, out := &in.UpgradedStatefulSets, &out.UpgradedStatefulSets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodStartTime != nil {
+		in, out := &in.PodStartTime, &out.PodStartTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.SSTablesUpgradedPods != nil {
+		in, out := &in.SSTablesUpgradedPods, &out.SSTablesUpgradedPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Fake is an ObjectStore keeping the objects in memory, for the tests of the controller. Put fails with Err
+// when it's set
+type Fake struct {
+	sync.Mutex
+	Objects map[string][]byte
+	Err     error
+}
+
+// NewFake returns an empty Fake
+func NewFake() *Fake {
+	return &Fake{Objects: map[string][]byte{}}
+}
+
+func (f *Fake) Put(key string, reader io.Reader, size int64) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return fmt.Errorf("got %d bytes for %s, want %d", len(data), key, size)
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.Objects[key] = data
+	return nil
+}
+
+func (f *Fake) Get(key string) (io.ReadCloser, error) {
+	f.Lock()
+	defer f.Unlock()
+	data, ok := f.Objects[key]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (f *Fake) Exists(key string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	_, ok := f.Objects[key]
+	return ok, nil
+}
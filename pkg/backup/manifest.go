@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+const (
+	// name of the manifest objects
+	manifestFile = "manifest.json"
+	// name of the object holding the CQL schema of the backed up keyspaces
+	schemaFile = "schema.cql"
+)
+
+// Manifest describes the content of a backup: the nodes with their tokens and the files of each table
+type Manifest struct {
+	Cluster  string    `json:"cluster"`
+	Backup   string    `json:"backup"`
+	Snapshot string    `json:"snapshot"`
+	Time     time.Time `json:"time"`
+	// Schema is the key of the CQL statements creating the keyspaces and tables of the backup
+	Schema string         `json:"schema"`
+	Nodes  []NodeManifest `json:"nodes"`
+}
+
+// NodeManifest describes the backup of a single node
+type NodeManifest struct {
+	Pod    string          `json:"pod"`
+	DC     string          `json:"dc"`
+	Rack   string          `json:"rack"`
+	HostID string          `json:"hostID"`
+	Tokens []string        `json:"tokens"`
+	Tables []TableManifest `json:"tables"`
+}
+
+// TableManifest lists the snapshot files of a table on a node
+type TableManifest struct {
+	Keyspace string `json:"keyspace"`
+	// Table is the name of the table without the id suffix of its directory
+	Table string `json:"table"`
+	// Directory is the name of the table directory in the data directory of the node
+	Directory string         `json:"directory"`
+	Files     []FileManifest `json:"files"`
+}
+
+// FileManifest is a file of a snapshot
+type FileManifest struct {
+	Name string `json:"name"`
+	// Key of the object holding the file in the object store
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+// BackupKey returns the key where all the objects of a backup are stored
+func BackupKey(cluster string, backup string) string {
+	return path.Join(cluster, backup)
+}
+
+// ManifestKey returns the key of the manifest of a backup
+func ManifestKey(cluster string, backup string) string {
+	return path.Join(BackupKey(cluster, backup), manifestFile)
+}
+
+// SchemaKey returns the key of the CQL schema of a backup
+func SchemaKey(cluster string, backup string) string {
+	return path.Join(BackupKey(cluster, backup), schemaFile)
+}
+
+// NodeManifestKey returns the key of the manifest of a node, written once the files of the node are uploaded
+func NodeManifestKey(cluster string, backup string, pod string) string {
+	return path.Join(BackupKey(cluster, backup), pod, manifestFile)
+}
+
+// FileKey returns the key of a file of a table of a node
+func FileKey(cluster string, backup string, pod string, keyspace string, directory string, file string) string {
+	return path.Join(BackupKey(cluster, backup), pod, keyspace, directory, file)
+}
+
+// WriteJSON stores the value as a JSON object under the key
+func WriteJSON(store ObjectStore, key string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(key, bytes.NewReader(data), int64(len(data)))
+}
+
+// ReadJSON loads the JSON object stored under the key in the value
+func ReadJSON(store ObjectStore, key string, value interface{}) error {
+	reader, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
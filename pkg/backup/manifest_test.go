@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestManifestKeys(t *testing.T) {
+	if key := ManifestKey("prod", "daily"); key != "prod/daily/manifest.json" {
+		t.Errorf("got %s", key)
+	}
+	if key := NodeManifestKey("prod", "daily", "prod-dc1-rack1-0"); key != "prod/daily/prod-dc1-rack1-0/manifest.json" {
+		t.Errorf("got %s", key)
+	}
+	if key := FileKey("prod", "daily", "prod-dc1-rack1-0", "orders", "items-1234", "mc-1-big-Data.db"); key != "prod/daily/prod-dc1-rack1-0/orders/items-1234/mc-1-big-Data.db" {
+		t.Errorf("got %s", key)
+	}
+}
+
+func TestWriteReadJSON(t *testing.T) {
+	store := NewFake()
+	manifest := Manifest{Cluster: "prod", Backup: "daily", Nodes: []NodeManifest{{Pod: "prod-dc1-rack1-0", Tokens: []string{"-100"}}}}
+	if err := WriteJSON(store, ManifestKey("prod", "daily"), manifest); err != nil {
+		t.Fatal(err)
+	}
+	var read Manifest
+	if err := ReadJSON(store, ManifestKey("prod", "daily"), &read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Cluster != "prod" || len(read.Nodes) != 1 || read.Nodes[0].Tokens[0] != "-100" {
+		t.Errorf("got %+v, want the manifest written", read)
+	}
+
+	if err := ReadJSON(store, ManifestKey("prod", "weekly"), &read); err == nil {
+		t.Error("got no error reading a missing manifest")
+	}
+	store.Err = errors.New("access denied")
+	if err := WriteJSON(store, ManifestKey("prod", "weekly"), manifest); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("got %v, want the error of the store", err)
+	}
+}
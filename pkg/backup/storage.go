@@ -0,0 +1,55 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/minio/minio-go"
+)
+
+// ObjectStore stores the files of the backups
+type ObjectStore interface {
+	// Put uploads the content of the reader under the key. The size is -1 when unknown
+	Put(key string, reader io.Reader, size int64) error
+	// Get returns the content stored under the key, it must be closed by the caller
+	Get(key string) (io.ReadCloser, error)
+	// Exists returns true if an object is stored under the key
+	Exists(key string) (bool, error)
+}
+
+// s3Store is an ObjectStore backed by an S3 compatible object storage
+type s3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Store returns an ObjectStore on the bucket of an S3 compatible endpoint. All the keys are relative to the prefix
+func NewS3Store(endpoint string, accessKeyID string, secretAccessKey string, region string, secure bool, bucket string, prefix string) (ObjectStore, error) {
+	client, err := minio.NewWithRegion(endpoint, accessKeyID, secretAccessKey, secure, region)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the S3 client for %s: %v", endpoint, err)
+	}
+	return &s3Store{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *s3Store) Put(key string, reader io.Reader, size int64) error {
+	_, err := s.client.PutObject(s.bucket, path.Join(s.prefix, key), reader, size, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *s3Store) Get(key string) (io.ReadCloser, error) {
+	return s.client.GetObject(s.bucket, path.Join(s.prefix, key), minio.GetObjectOptions{})
+}
+
+func (s *s3Store) Exists(key string) (bool, error) {
+	_, err := s.client.StatObject(s.bucket, path.Join(s.prefix, key), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
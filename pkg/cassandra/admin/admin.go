@@ -0,0 +1,163 @@
+// Package admin manages the Cassandra nodes: the state of the ring, the decommission, the drain, the repairs and the
+// snapshots. The operations are run through a Jolokia agent exposing the JMX MBeans of the node over HTTP, or
+// through nodetool executed in the pod
+package admin
+
+import "fmt"
+
+// Client runs the management operations of a node
+type Client interface {
+	// Status returns the state of the nodes of the ring as seen by the node
+	Status() ([]NodeStatus, error)
+	// Info returns the host ID and the tokens of the node
+	Info() (*NodeInfo, error)
+	// Keyspaces returns the keyspaces of the cluster, including the system ones
+	Keyspaces() ([]string, error)
+	// SchemaVersions returns the addresses of the nodes by schema version, the nodes which didn't answer are listed
+	// under UnreachableVersion
+	SchemaVersions() (map[string][]string, error)
+	// Streams returns the streaming sessions of the node in progress
+	Streams() ([]Stream, error)
+	// Decommission starts the decommission of the node, it streams its data to the other nodes and leaves the ring.
+	// It doesn't wait for the end of the decommission, the node is Leaving until then
+	Decommission() error
+	// Drain flushes the memtables and stops accepting writes before the node is stopped
+	Drain() error
+	// Repair starts the repair of a keyspace on the node and returns the command number following its progress
+	Repair(keyspace string, options RepairOptions) (int, error)
+	// RepairStatus returns the state of a repair started by Repair
+	RepairStatus(command int) (OperationState, error)
+	// StopRepair stops a repair started by Repair
+	StopRepair(command int) error
+	// UpgradeSSTables starts rewriting the sstables of the node in the format of its version and returns the
+	// command number following its progress
+	UpgradeSSTables() (int, error)
+	// UpgradeSSTablesStatus returns the state of a rewrite started by UpgradeSSTables
+	UpgradeSSTablesStatus(command int) (OperationState, error)
+	// Snapshot takes a snapshot of the keyspaces, of all of them if empty
+	Snapshot(tag string, keyspaces ...string) error
+	// ClearSnapshot removes a snapshot from the node
+	ClearSnapshot(tag string) error
+	// CompactionStats returns the compactions running on the node
+	CompactionStats() ([]Compaction, error)
+}
+
+// NodeState is the operating mode of a node in the ring
+type NodeState string
+
+const (
+	NodeNormal  NodeState = "N"
+	NodeLeaving NodeState = "L"
+	NodeJoining NodeState = "J"
+	NodeMoving  NodeState = "M"
+)
+
+// NodeStatus is the state of a node of the ring
+type NodeStatus struct {
+	Address    string
+	HostID     string
+	Datacenter string
+	Rack       string
+	Up         bool
+	State      NodeState
+}
+
+// Code returns the state of the node as printed by nodetool status: UN, DN, UL...
+func (n NodeStatus) Code() string {
+	if n.Up {
+		return "U" + string(n.State)
+	}
+	return "D" + string(n.State)
+}
+
+// FindNode returns the status of the node with the address, or nil if it isn't part of the ring
+func FindNode(nodes []NodeStatus, address string) *NodeStatus {
+	for i := range nodes {
+		if nodes[i].Address == address {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+// UnreachableVersion is the schema version of the nodes which didn't answer
+const UnreachableVersion = "UNREACHABLE"
+
+// SchemaAgreement returns true when all the nodes answered with the same schema version
+func SchemaAgreement(versions map[string][]string) bool {
+	_, unreachable := versions[UnreachableVersion]
+	return len(versions) == 1 && !unreachable
+}
+
+// NodeInfo is the identity of a node in the ring
+type NodeInfo struct {
+	HostID string
+	Tokens []string
+}
+
+// Stream is one direction of a streaming session of the node with a peer
+type Stream struct {
+	// Operation is the description of the stream plan: Bootstrap, Rebuild, Repair, Unbootstrap...
+	Operation string
+	Peer      string
+	// Receiving is true when the node receives the files from the peer, false when it sends them
+	Receiving bool
+	Files     int64
+	Bytes     int64
+	FilesDone int64
+	BytesDone int64
+}
+
+// RepairOptions are the options of the repair of a keyspace
+type RepairOptions struct {
+	// PrimaryRange only repairs the ranges of which the node is the primary replica
+	PrimaryRange bool
+	Incremental  bool
+	// Parallelism is sequential, parallel or dc_parallel
+	Parallelism string
+}
+
+// OperationState is the progress of an operation running in the background, like a repair
+type OperationState string
+
+const (
+	// OperationStarting is returned while the process of the operation isn't running yet, it's lost if it lasts
+	OperationStarting   OperationState = "STARTING"
+	OperationInProgress OperationState = "IN_PROGRESS"
+	OperationCompleted  OperationState = "COMPLETED"
+	OperationFailed     OperationState = "FAILED"
+	// OperationUnknown is returned when the operation was lost, after a restart of the node or of the operator
+	OperationUnknown OperationState = "UNKNOWN"
+)
+
+// UpgradeSSTablesType is the type of the compactions rewriting the sstables in the format of the version
+const UpgradeSSTablesType = "Upgrade sstables"
+
+// Compaction is a compaction running on a node
+type Compaction struct {
+	ID        string
+	Type      string
+	Keyspace  string
+	Table     string
+	Completed int64
+	Total     int64
+	Unit      string
+}
+
+// Progress returns the percentage of the compaction done
+func (c Compaction) Progress() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Completed) * 100 / float64(c.Total)
+}
+
+// Error is an error of a management operation
+type Error struct {
+	Operation string
+	Message   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Operation, e.Message)
+}
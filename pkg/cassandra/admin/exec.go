@@ -0,0 +1,239 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/nodetool"
+)
+
+// Executor runs a command in the Cassandra container of the node and returns its standard output and error
+type Executor func(cmd []string) (string, string, error)
+
+// execClient runs nodetool in the pod of the node
+type execClient struct {
+	exec Executor
+}
+
+// NewExecClient returns a Client running nodetool with the executor
+func NewExecClient(exec Executor) Client {
+	return &execClient{exec: exec}
+}
+
+func (e *execClient) nodetool(args ...string) (string, error) {
+	stdout, stderr, err := e.exec(append([]string{"nodetool"}, args...))
+	if err != nil {
+		return "", &Error{Operation: args[0], Message: strings.TrimSpace(fmt.Sprintf("%v %s", err, stderr))}
+	}
+	return stdout, nil
+}
+
+func (e *execClient) Status() ([]NodeStatus, error) {
+	stdout, err := e.nodetool("status")
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := nodetool.ParseStatus(stdout)
+	if err != nil {
+		return nil, &Error{Operation: "status", Message: err.Error()}
+	}
+	var result []NodeStatus
+	for _, node := range nodes {
+		result = append(result, NodeStatus{
+			Address:    node.Address,
+			HostID:     node.HostID,
+			Datacenter: node.Datacenter,
+			Rack:       node.Rack,
+			Up:         node.Up,
+			State:      NodeState(node.State),
+		})
+	}
+	return result, nil
+}
+
+// Info runs nodetool info -T, which lists the tokens of the node
+func (e *execClient) Info() (*NodeInfo, error) {
+	stdout, err := e.nodetool("info", "-T")
+	if err != nil {
+		return nil, err
+	}
+	info, err := nodetool.ParseInfo(stdout)
+	if err != nil {
+		return nil, &Error{Operation: "info", Message: err.Error()}
+	}
+	return &NodeInfo{HostID: info.ID, Tokens: info.Tokens}, nil
+}
+
+func (e *execClient) Keyspaces() ([]string, error) {
+	stdout, err := e.nodetool("cfstats")
+	if err != nil {
+		return nil, err
+	}
+	keyspaces, err := nodetool.ParseKeyspaces(stdout)
+	if err != nil {
+		return nil, &Error{Operation: "cfstats", Message: err.Error()}
+	}
+	return keyspaces, nil
+}
+
+func (e *execClient) SchemaVersions() (map[string][]string, error) {
+	stdout, err := e.nodetool("describecluster")
+	if err != nil {
+		return nil, err
+	}
+	description, err := nodetool.ParseDescribeCluster(stdout)
+	if err != nil {
+		return nil, &Error{Operation: "describecluster", Message: err.Error()}
+	}
+	versions := map[string][]string{}
+	for version, addresses := range description.SchemaVersions {
+		versions[version] = addresses
+	}
+	if len(description.Unreachable) > 0 {
+		versions[UnreachableVersion] = description.Unreachable
+	}
+	return versions, nil
+}
+
+func (e *execClient) Streams() ([]Stream, error) {
+	stdout, err := e.nodetool("netstats")
+	if err != nil {
+		return nil, err
+	}
+	stats, err := nodetool.ParseNetstats(stdout)
+	if err != nil {
+		return nil, &Error{Operation: "netstats", Message: err.Error()}
+	}
+	var streams []Stream
+	for _, stream := range stats.Streams {
+		streams = append(streams, Stream{
+			Operation: stream.Operation,
+			Peer:      stream.Peer,
+			Receiving: stream.Receiving,
+			Files:     stream.Files,
+			Bytes:     stream.Bytes,
+			FilesDone: stream.FilesDone,
+			BytesDone: stream.BytesDone,
+		})
+	}
+	return streams, nil
+}
+
+// Decommission runs nodetool in the background as it returns at the end of the decommission
+func (e *execClient) Decommission() error {
+	_, stderr, err := e.exec([]string{"/bin/sh", "-c", "nohup nodetool decommission > /tmp/decommission.log 2>&1 &"})
+	if err != nil {
+		return &Error{Operation: "decommission", Message: strings.TrimSpace(fmt.Sprintf("%v %s", err, stderr))}
+	}
+	return nil
+}
+
+func (e *execClient) Drain() error {
+	_, err := e.nodetool("drain")
+	return err
+}
+
+// Repair runs nodetool in the background as the repair can last for hours
+func (e *execClient) Repair(keyspace string, options RepairOptions) (int, error) {
+	args := []string{"nodetool", "repair"}
+	if options.PrimaryRange {
+		args = append(args, "-pr")
+	}
+	if !options.Incremental {
+		args = append(args, "-full")
+	}
+	switch options.Parallelism {
+	case "sequential":
+		args = append(args, "-seq")
+	case "dc_parallel":
+		args = append(args, "-dcpar")
+	}
+	return e.background("repair", append(args, keyspace))
+}
+
+func (e *execClient) RepairStatus(command int) (OperationState, error) {
+	return e.backgroundState("repair", command)
+}
+
+func (e *execClient) StopRepair(command int) error {
+	script := fmt.Sprintf("kill $(cat %s.pid)", backgroundPrefix("repair", command))
+	if _, stderr, err := e.exec([]string{"/bin/sh", "-c", script}); err != nil {
+		return &Error{Operation: "stop repair", Message: strings.TrimSpace(fmt.Sprintf("%v %s", err, stderr))}
+	}
+	return nil
+}
+
+func (e *execClient) UpgradeSSTables() (int, error) {
+	return e.background("upgradesstables", []string{"nodetool", "upgradesstables"})
+}
+
+func (e *execClient) UpgradeSSTablesStatus(command int) (OperationState, error) {
+	return e.backgroundState("upgradesstables", command)
+}
+
+// background starts the command in the background, its pid and exit code are kept in files named after the
+// command number so the progress is known after a restart of the operator. The args are passed as positional
+// parameters so they are never interpreted by the shell
+func (e *execClient) background(operation string, args []string) (int, error) {
+	command := int(time.Now().UnixNano())
+	prefix := backgroundPrefix(operation, command)
+	inner := fmt.Sprintf(`"$@" > %[1]s.log 2>&1 & echo $! > %[1]s.pid; wait $!; echo $? > %[1]s.exit`, prefix)
+	script := fmt.Sprintf(`rm -f %[1]s.exit %[1]s.pid; nohup sh -c '%[2]s' sh "$@" > /dev/null 2>&1 &`, prefix, inner)
+	if _, stderr, err := e.exec(append([]string{"/bin/sh", "-c", script, "sh"}, args...)); err != nil {
+		return 0, &Error{Operation: operation, Message: strings.TrimSpace(fmt.Sprintf("%v %s", err, stderr))}
+	}
+	return command, nil
+}
+
+// backgroundState returns the state of a command started by background, it's lost when the node restarted
+func (e *execClient) backgroundState(operation string, command int) (OperationState, error) {
+	script := fmt.Sprintf("if [ -f %[1]s.exit ]; then cat %[1]s.exit; elif [ ! -f %[1]s.pid ]; then echo starting; elif kill -0 $(cat %[1]s.pid) 2>/dev/null; then echo running; else echo lost; fi", backgroundPrefix(operation, command))
+	stdout, stderr, err := e.exec([]string{"/bin/sh", "-c", script})
+	if err != nil {
+		return "", &Error{Operation: operation + " status", Message: strings.TrimSpace(fmt.Sprintf("%v %s", err, stderr))}
+	}
+	switch state := strings.TrimSpace(stdout); state {
+	case "starting":
+		return OperationStarting, nil
+	case "running":
+		return OperationInProgress, nil
+	case "lost":
+		return OperationUnknown, nil
+	case "0":
+		return OperationCompleted, nil
+	default:
+		return OperationFailed, nil
+	}
+}
+
+func backgroundPrefix(operation string, command int) string {
+	return "/tmp/admin-" + operation + "-" + strconv.Itoa(command)
+}
+
+func (e *execClient) Snapshot(tag string, keyspaces ...string) error {
+	_, err := e.nodetool(append([]string{"snapshot", "-t", tag}, keyspaces...)...)
+	return err
+}
+
+func (e *execClient) ClearSnapshot(tag string) error {
+	_, err := e.nodetool("clearsnapshot", "-t", tag)
+	return err
+}
+
+func (e *execClient) CompactionStats() ([]Compaction, error) {
+	stdout, err := e.nodetool("compactionstats")
+	if err != nil {
+		return nil, err
+	}
+	stats, err := nodetool.ParseCompactionStats(stdout)
+	if err != nil {
+		return nil, &Error{Operation: "compactionstats", Message: err.Error()}
+	}
+	var compactions []Compaction
+	for _, compaction := range stats.Compactions {
+		compactions = append(compactions, Compaction(compaction))
+	}
+	return compactions, nil
+}
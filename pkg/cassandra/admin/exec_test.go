@@ -0,0 +1,186 @@
+package admin
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goldenExecutor answers nodetool with the outputs captured in the testdata of the parsers
+func goldenExecutor(t *testing.T, files map[string]string) Executor {
+	return func(cmd []string) (string, string, error) {
+		file, ok := files[strings.Join(cmd, " ")]
+		if !ok {
+			t.Fatalf("unexpected command %v", cmd)
+		}
+		data, err := ioutil.ReadFile(filepath.Join("..", "nodetool", "testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data), "", nil
+	}
+}
+
+func TestExecStatus(t *testing.T) {
+	client := NewExecClient(goldenExecutor(t, map[string]string{"nodetool status": "status-3.11.txt"}))
+	nodes, err := client.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 4 {
+		t.Fatalf("got %d nodes, want 4", len(nodes))
+	}
+	node := FindNode(nodes, "10.1.0.2")
+	if node == nil || node.Code() != "UJ" || node.Datacenter != "dc2" || node.Rack != "rack2" {
+		t.Errorf("got %+v, want the joining node 10.1.0.2 in dc2/rack2", node)
+	}
+}
+
+func TestExecInfo(t *testing.T) {
+	client := NewExecClient(goldenExecutor(t, map[string]string{"nodetool info -T": "info-3.11.txt"}))
+	info, err := client.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &NodeInfo{
+		HostID: "3c7e1f2a-0b4d-4e8f-a6c5-7d9e1b2c3a4f",
+		Tokens: []string{"-9223372036854775808", "-3074457345618258603", "3074457345618258602"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestExecKeyspaces(t *testing.T) {
+	client := NewExecClient(goldenExecutor(t, map[string]string{"nodetool cfstats": "cfstats-4.0.txt"}))
+	keyspaces, err := client.Keyspaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"system", "system_schema", "orders", "system_distributed"}
+	if !reflect.DeepEqual(keyspaces, want) {
+		t.Errorf("got %v, want %v", keyspaces, want)
+	}
+}
+
+func TestExecRepair(t *testing.T) {
+	var cmd []string
+	client := NewExecClient(func(c []string) (string, string, error) {
+		cmd = c
+		return "", "", nil
+	})
+	command, err := client.Repair("orders", RepairOptions{PrimaryRange: true, Parallelism: "sequential"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(cmd[4:], " "); args != "nodetool repair -pr -full -seq orders" {
+		t.Errorf("got args %q, want a full sequential repair of the primary ranges of orders", args)
+	}
+	if script := cmd[2]; !strings.Contains(script, backgroundPrefix("repair", command)+".pid") {
+		t.Errorf("got script %q, want the pid kept in the files of command %d", script, command)
+	}
+
+	next, err := client.Repair("orders", RepairOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == command {
+		t.Errorf("got command %d twice", command)
+	}
+}
+
+func TestExecBackgroundArgs(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	dir, err := ioutil.TempDir("", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	marker := filepath.Join(dir, "injected")
+	// the command runs in the local shell instead of the pod, echo stands for nodetool
+	client := NewExecClient(func(cmd []string) (string, string, error) {
+		cmd = append([]string(nil), cmd...)
+		for i := 4; i < len(cmd); i++ {
+			if cmd[i] == "nodetool" {
+				cmd[i] = "echo"
+			}
+		}
+		var stderr bytes.Buffer
+		c := exec.Command(cmd[0], cmd[1:]...)
+		c.Stderr = &stderr
+		err := c.Run()
+		return "", stderr.String(), err
+	})
+	keyspace := "orders'; touch " + marker + "; echo '$(touch " + marker + ")"
+	command, err := client.Repair(keyspace, RepairOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := backgroundPrefix("repair", command)
+	defer os.Remove(prefix + ".log")
+	defer os.Remove(prefix + ".pid")
+	defer os.Remove(prefix + ".exit")
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(prefix + ".exit"); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log, err := ioutil.ReadFile(prefix + ".log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(log)) != "repair -full "+keyspace {
+		t.Errorf("got output %q, want the keyspace passed as is", log)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("the keyspace name ran in the shell")
+	}
+}
+
+func TestExecBackgroundState(t *testing.T) {
+	tests := []struct {
+		output string
+		state  OperationState
+	}{
+		{"starting\n", OperationStarting},
+		{"running\n", OperationInProgress},
+		{"lost\n", OperationUnknown},
+		{"0\n", OperationCompleted},
+		{"2\n", OperationFailed},
+	}
+	for _, test := range tests {
+		client := NewExecClient(func(cmd []string) (string, string, error) {
+			return test.output, "", nil
+		})
+		state, err := client.UpgradeSSTablesStatus(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state != test.state {
+			t.Errorf("%q: got %s, want %s", test.output, state, test.state)
+		}
+	}
+}
+
+func TestExecError(t *testing.T) {
+	client := NewExecClient(func(cmd []string) (string, string, error) {
+		return "", "nodetool: Failed to connect to '127.0.0.1:7199'", errors.New("command terminated with exit code 1")
+	})
+	_, err := client.Status()
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("got %v, want an *Error", err)
+	}
+	if !strings.Contains(err.Error(), "Failed to connect") {
+		t.Errorf("got %v, want the standard error of nodetool", err)
+	}
+}
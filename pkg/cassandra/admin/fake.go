@@ -0,0 +1,200 @@
+package admin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fake is a Client keeping the state of a node in memory, for the tests of the controller. The operations are
+// recorded in Calls and fail with Err when it's set
+type Fake struct {
+	sync.Mutex
+	Nodes  []NodeStatus
+	HostID string
+	// Tokens are the addresses of the nodes by token
+	Tokens map[string]string
+	// Schema are the addresses of the nodes by schema version
+	Schema      map[string][]string
+	Streaming   []Stream
+	Compactions []Compaction
+	Snapshots   map[string][]string
+	// Operations are the states of the repairs and sstables upgrades by command number
+	Operations   map[int]OperationState
+	KeyspaceList []string
+	Drained      bool
+	Calls        []string
+	Err          error
+	// Address is the address of the node, it leaves Nodes when it's decommissioned
+	Address string
+}
+
+// NewFake returns a Fake of the node with the address
+func NewFake(address string) *Fake {
+	return &Fake{
+		Address:    address,
+		Tokens:     map[string]string{},
+		Schema:     map[string][]string{},
+		Snapshots:  map[string][]string{},
+		Operations: map[int]OperationState{},
+	}
+}
+
+func (f *Fake) call(format string, args ...interface{}) error {
+	f.Lock()
+	defer f.Unlock()
+	f.Calls = append(f.Calls, fmt.Sprintf(format, args...))
+	return f.Err
+}
+
+func (f *Fake) Status() ([]NodeStatus, error) {
+	if err := f.call("status"); err != nil {
+		return nil, err
+	}
+	return append([]NodeStatus(nil), f.Nodes...), nil
+}
+
+func (f *Fake) Info() (*NodeInfo, error) {
+	if err := f.call("info"); err != nil {
+		return nil, err
+	}
+	info := &NodeInfo{HostID: f.HostID}
+	for token, address := range f.Tokens {
+		if address == f.Address {
+			info.Tokens = append(info.Tokens, token)
+		}
+	}
+	sort.Strings(info.Tokens)
+	return info, nil
+}
+
+func (f *Fake) Keyspaces() ([]string, error) {
+	if err := f.call("keyspaces"); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), f.KeyspaceList...), nil
+}
+
+func (f *Fake) SchemaVersions() (map[string][]string, error) {
+	if err := f.call("describecluster"); err != nil {
+		return nil, err
+	}
+	versions := map[string][]string{}
+	for version, addresses := range f.Schema {
+		versions[version] = append([]string(nil), addresses...)
+	}
+	return versions, nil
+}
+
+func (f *Fake) Streams() ([]Stream, error) {
+	if err := f.call("netstats"); err != nil {
+		return nil, err
+	}
+	return append([]Stream(nil), f.Streaming...), nil
+}
+
+// Decommission removes the node from the ring and its tokens
+func (f *Fake) Decommission() error {
+	if err := f.call("decommission"); err != nil {
+		return err
+	}
+	var nodes []NodeStatus
+	for _, node := range f.Nodes {
+		if node.Address != f.Address {
+			nodes = append(nodes, node)
+		}
+	}
+	f.Nodes = nodes
+	for token, address := range f.Tokens {
+		if address == f.Address {
+			delete(f.Tokens, token)
+		}
+	}
+	return nil
+}
+
+func (f *Fake) Drain() error {
+	if err := f.call("drain"); err != nil {
+		return err
+	}
+	f.Drained = true
+	return nil
+}
+
+// Repair completes the repair immediately unless the state of the next command is already set
+func (f *Fake) Repair(keyspace string, options RepairOptions) (int, error) {
+	if err := f.call("repair %s", keyspace); err != nil {
+		return 0, err
+	}
+	return f.start(), nil
+}
+
+func (f *Fake) RepairStatus(command int) (OperationState, error) {
+	if err := f.call("repair status %d", command); err != nil {
+		return "", err
+	}
+	return f.state(command), nil
+}
+
+func (f *Fake) StopRepair(command int) error {
+	if err := f.call("stop repair %d", command); err != nil {
+		return err
+	}
+	f.Operations[command] = OperationFailed
+	return nil
+}
+
+// UpgradeSSTables completes the rewrite immediately unless the state of the next command is already set
+func (f *Fake) UpgradeSSTables() (int, error) {
+	if err := f.call("upgradesstables"); err != nil {
+		return 0, err
+	}
+	return f.start(), nil
+}
+
+func (f *Fake) UpgradeSSTablesStatus(command int) (OperationState, error) {
+	if err := f.call("upgradesstables status %d", command); err != nil {
+		return "", err
+	}
+	return f.state(command), nil
+}
+
+// start returns the command number of a new operation, the number of calls so far
+func (f *Fake) start() int {
+	command := len(f.Calls)
+	if _, ok := f.Operations[command]; !ok {
+		f.Operations[command] = OperationCompleted
+	}
+	return command
+}
+
+func (f *Fake) state(command int) OperationState {
+	if state, ok := f.Operations[command]; ok {
+		return state
+	}
+	return OperationUnknown
+}
+
+func (f *Fake) Snapshot(tag string, keyspaces ...string) error {
+	if err := f.call("snapshot %s %s", tag, strings.Join(keyspaces, ",")); err != nil {
+		return err
+	}
+	f.Snapshots[tag] = keyspaces
+	return nil
+}
+
+func (f *Fake) ClearSnapshot(tag string) error {
+	if err := f.call("clearsnapshot %s", tag); err != nil {
+		return err
+	}
+	delete(f.Snapshots, tag)
+	return nil
+}
+
+func (f *Fake) CompactionStats() ([]Compaction, error) {
+	if err := f.call("compactionstats"); err != nil {
+		return nil, err
+	}
+	return append([]Compaction(nil), f.Compactions...), nil
+}
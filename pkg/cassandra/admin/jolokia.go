@@ -0,0 +1,405 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJolokiaPort is the port of the Jolokia JVM agent
+	DefaultJolokiaPort = 8778
+	storageService     = "org.apache.cassandra.db:type=StorageService"
+	endpointSnitchInfo = "org.apache.cassandra.db:type=EndpointSnitchInfo"
+	compactionManager  = "org.apache.cassandra.db:type=CompactionManager"
+	storageProxy       = "org.apache.cassandra.db:type=StorageProxy"
+	streamManager      = "org.apache.cassandra.net:type=StreamManager"
+	// asyncWait is the time given to an operation lasting until its end, like the decommission, to fail
+	asyncWait = 5 * time.Second
+	// backgroundTimeout bounds the requests of the operations run in the background so the goroutine waiting for
+	// a node which stopped answering ends
+	backgroundTimeout = 24 * time.Hour
+)
+
+// jolokiaClient calls the MBeans of the node through the Jolokia agent
+type jolokiaClient struct {
+	url  string
+	http *http.Client
+}
+
+// NewJolokiaClient returns a Client of the Jolokia agent of the node, the url is like http://10.0.0.1:8778/jolokia/.
+// The timeout is the maximum duration of the synchronous operations like the drain
+func NewJolokiaClient(url string, timeout time.Duration) Client {
+	return &jolokiaClient{url: url, http: &http.Client{Timeout: timeout}}
+}
+
+// jolokiaRequest is a read of attributes or the execution of an operation of an MBean
+type jolokiaRequest struct {
+	Type      string        `json:"type"`
+	MBean     string        `json:"mbean"`
+	Attribute interface{}   `json:"attribute,omitempty"`
+	Operation string        `json:"operation,omitempty"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+type jolokiaResponse struct {
+	Status int             `json:"status"`
+	Error  string          `json:"error"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// call sends a bulk request and decodes the values of the responses in the same order
+func (j *jolokiaClient) call(operation string, requests []jolokiaRequest, values ...interface{}) error {
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return err
+	}
+	resp, err := j.http.Post(j.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return &Error{Operation: operation, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &Error{Operation: operation, Message: resp.Status}
+	}
+	var responses []jolokiaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&responses); err != nil {
+		return &Error{Operation: operation, Message: fmt.Sprintf("invalid response: %v", err)}
+	}
+	if len(responses) != len(requests) {
+		return &Error{Operation: operation, Message: fmt.Sprintf("%d responses to %d requests", len(responses), len(requests))}
+	}
+	for i, response := range responses {
+		if response.Status != http.StatusOK {
+			return &Error{Operation: operation, Message: response.Error}
+		}
+		if i < len(values) && values[i] != nil && len(response.Value) > 0 {
+			if err := json.Unmarshal(response.Value, values[i]); err != nil {
+				return &Error{Operation: operation, Message: fmt.Sprintf("invalid value: %v", err)}
+			}
+		}
+	}
+	return nil
+}
+
+func (j *jolokiaClient) exec(mbean string, operation string, value interface{}, arguments ...interface{}) error {
+	request := jolokiaRequest{Type: "exec", MBean: mbean, Operation: operation, Arguments: arguments}
+	return j.call(operation, []jolokiaRequest{request}, value)
+}
+
+func (j *jolokiaClient) Status() ([]NodeStatus, error) {
+	var attributes struct {
+		LiveNodes        []string
+		UnreachableNodes []string
+		JoiningNodes     []string
+		LeavingNodes     []string
+		MovingNodes      []string
+		HostIdMap        map[string]string
+	}
+	read := jolokiaRequest{
+		Type:      "read",
+		MBean:     storageService,
+		Attribute: []string{"LiveNodes", "UnreachableNodes", "JoiningNodes", "LeavingNodes", "MovingNodes", "HostIdMap"},
+	}
+	if err := j.call("status", []jolokiaRequest{read}, &attributes); err != nil {
+		return nil, err
+	}
+
+	// the joining nodes don't have a host ID yet
+	addresses := map[string]bool{}
+	for _, list := range [][]string{attributes.LiveNodes, attributes.UnreachableNodes, attributes.JoiningNodes} {
+		for _, address := range list {
+			addresses[address] = true
+		}
+	}
+	for address := range attributes.HostIdMap {
+		addresses[address] = true
+	}
+	var sorted []string
+	for address := range addresses {
+		sorted = append(sorted, address)
+	}
+	sort.Strings(sorted)
+	var nodes []NodeStatus
+	var requests []jolokiaRequest
+	for _, address := range sorted {
+		nodes = append(nodes, NodeStatus{
+			Address: address,
+			HostID:  attributes.HostIdMap[address],
+			Up:      contains(attributes.LiveNodes, address),
+			State:   NodeNormal,
+		})
+		requests = append(requests,
+			jolokiaRequest{Type: "exec", MBean: endpointSnitchInfo, Operation: "getDatacenter", Arguments: []interface{}{address}},
+			jolokiaRequest{Type: "exec", MBean: endpointSnitchInfo, Operation: "getRack", Arguments: []interface{}{address}},
+		)
+	}
+	var values []interface{}
+	for i := range nodes {
+		node := &nodes[i]
+		switch {
+		case contains(attributes.LeavingNodes, node.Address):
+			node.State = NodeLeaving
+		case contains(attributes.JoiningNodes, node.Address):
+			node.State = NodeJoining
+		case contains(attributes.MovingNodes, node.Address):
+			node.State = NodeMoving
+		}
+		values = append(values, &node.Datacenter, &node.Rack)
+	}
+	if len(requests) > 0 {
+		if err := j.call("status", requests, values...); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+func (j *jolokiaClient) Info() (*NodeInfo, error) {
+	var attributes struct {
+		LocalHostId string
+		Tokens      []string
+	}
+	read := jolokiaRequest{Type: "read", MBean: storageService, Attribute: []string{"LocalHostId", "Tokens"}}
+	if err := j.call("info", []jolokiaRequest{read}, &attributes); err != nil {
+		return nil, err
+	}
+	return &NodeInfo{HostID: attributes.LocalHostId, Tokens: attributes.Tokens}, nil
+}
+
+func (j *jolokiaClient) Keyspaces() ([]string, error) {
+	var keyspaces []string
+	read := jolokiaRequest{Type: "read", MBean: storageService, Attribute: "Keyspaces"}
+	if err := j.call("keyspaces", []jolokiaRequest{read}, &keyspaces); err != nil {
+		return nil, err
+	}
+	return keyspaces, nil
+}
+
+func (j *jolokiaClient) SchemaVersions() (map[string][]string, error) {
+	versions := map[string][]string{}
+	read := jolokiaRequest{Type: "read", MBean: storageProxy, Attribute: "SchemaVersions"}
+	if err := j.call("describecluster", []jolokiaRequest{read}, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// streamSummary is the number of files and bytes to stream for a table
+type streamSummary struct {
+	Files     int64 `json:"files"`
+	TotalSize int64 `json:"totalSize"`
+}
+
+// streamProgress is the progress of a file being streamed
+type streamProgress struct {
+	CurrentBytes int64 `json:"currentBytes"`
+	TotalBytes   int64 `json:"totalBytes"`
+}
+
+// Streams reads the stream plans in progress, the progress of the files already streamed is summed up per session
+func (j *jolokiaClient) Streams() ([]Stream, error) {
+	var plans []struct {
+		Description string `json:"description"`
+		Sessions    []struct {
+			Peer               string           `json:"peer"`
+			ReceivingSummaries []streamSummary  `json:"receivingSummaries"`
+			SendingSummaries   []streamSummary  `json:"sendingSummaries"`
+			ReceivingFiles     []streamProgress `json:"receivingFiles"`
+			SendingFiles       []streamProgress `json:"sendingFiles"`
+		} `json:"sessions"`
+	}
+	read := jolokiaRequest{Type: "read", MBean: streamManager, Attribute: "CurrentStreams"}
+	if err := j.call("netstats", []jolokiaRequest{read}, &plans); err != nil {
+		return nil, err
+	}
+	var streams []Stream
+	for _, plan := range plans {
+		for _, session := range plan.Sessions {
+			peer := strings.TrimPrefix(session.Peer, "/")
+			streams = append(streams,
+				streamOf(plan.Description, peer, true, session.ReceivingSummaries, session.ReceivingFiles),
+				streamOf(plan.Description, peer, false, session.SendingSummaries, session.SendingFiles))
+		}
+	}
+	return streams, nil
+}
+
+func streamOf(operation string, peer string, receiving bool, summaries []streamSummary, files []streamProgress) Stream {
+	stream := Stream{Operation: operation, Peer: peer, Receiving: receiving}
+	for _, summary := range summaries {
+		stream.Files += summary.Files
+		stream.Bytes += summary.TotalSize
+	}
+	for _, file := range files {
+		if file.CurrentBytes >= file.TotalBytes {
+			stream.FilesDone++
+		}
+		stream.BytesDone += file.CurrentBytes
+	}
+	return stream
+}
+
+// Decommission runs the operation in the background as the call returns at the end of the decommission, only
+// the errors happening within a few seconds are returned
+func (j *jolokiaClient) Decommission() error {
+	done := make(chan error, 1)
+	go func() {
+		client := &jolokiaClient{url: j.url, http: &http.Client{Timeout: backgroundTimeout}}
+		done <- client.exec(storageService, "decommission", nil)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(asyncWait):
+		return nil
+	}
+}
+
+func (j *jolokiaClient) Drain() error {
+	return j.exec(storageService, "drain", nil)
+}
+
+func (j *jolokiaClient) Repair(keyspace string, options RepairOptions) (int, error) {
+	parallelism := options.Parallelism
+	if parallelism == "" {
+		parallelism = "parallel"
+	}
+	arguments := map[string]string{
+		"primaryRange": strconv.FormatBool(options.PrimaryRange),
+		"incremental":  strconv.FormatBool(options.Incremental),
+		"parallelism":  parallelism,
+	}
+	var command int
+	err := j.exec(storageService, "repairAsync(java.lang.String,java.util.Map)", &command, keyspace, arguments)
+	return command, err
+}
+
+// RepairStatus reads the status of the parent repair session, which is kept by the node until it restarts
+func (j *jolokiaClient) RepairStatus(command int) (OperationState, error) {
+	var status []string
+	if err := j.exec(storageService, "getParentRepairStatus", &status, command); err != nil {
+		return "", err
+	}
+	if len(status) == 0 {
+		return OperationUnknown, nil
+	}
+	return OperationState(status[0]), nil
+}
+
+// StopRepair terminates all the repair sessions of the node, it only runs the repairs of the operator
+func (j *jolokiaClient) StopRepair(command int) error {
+	return j.exec(storageService, "forceTerminateAllRepairSessions", nil)
+}
+
+// UpgradeSSTables rewrites the sstables of each keyspace in the background as the operation returns at its end
+func (j *jolokiaClient) UpgradeSSTables() (int, error) {
+	keyspaces, err := j.Keyspaces()
+	if err != nil {
+		return 0, err
+	}
+	command := int(time.Now().Unix())
+	op := &operation{}
+	operations.Lock()
+	operations.m[operationKey(j.url, command)] = op
+	operations.Unlock()
+	go func() {
+		client := &jolokiaClient{url: j.url, http: &http.Client{Timeout: backgroundTimeout}}
+		var err error
+		for _, keyspace := range keyspaces {
+			err = client.exec(storageService, "upgradeSSTables(java.lang.String,boolean,int,[Ljava.lang.String;)", nil, keyspace, true, 0, []string{})
+			if err != nil {
+				break
+			}
+		}
+		operations.Lock()
+		op.done, op.err = true, err
+		operations.Unlock()
+	}()
+	return command, nil
+}
+
+// UpgradeSSTablesStatus is unknown when the operator restarted since UpgradeSSTables was called. The final state is
+// only returned once, the operation is forgotten when it's read
+func (j *jolokiaClient) UpgradeSSTablesStatus(command int) (OperationState, error) {
+	operations.Lock()
+	defer operations.Unlock()
+	key := operationKey(j.url, command)
+	op, ok := operations.m[key]
+	switch {
+	case !ok:
+		return OperationUnknown, nil
+	case !op.done:
+		return OperationInProgress, nil
+	}
+	delete(operations.m, key)
+	if op.err != nil {
+		return OperationFailed, nil
+	}
+	return OperationCompleted, nil
+}
+
+// operation is an operation of a node run in the background by the operator
+type operation struct {
+	done bool
+	err  error
+}
+
+// operations are the operations run in the background, by node url and command number
+var operations = struct {
+	sync.Mutex
+	m map[string]*operation
+}{m: map[string]*operation{}}
+
+func operationKey(url string, command int) string {
+	return url + "#" + strconv.Itoa(command)
+}
+
+func (j *jolokiaClient) Snapshot(tag string, keyspaces ...string) error {
+	if keyspaces == nil {
+		keyspaces = []string{}
+	}
+	return j.exec(storageService, "takeSnapshot(java.lang.String,[Ljava.lang.String;)", nil, tag, keyspaces)
+}
+
+func (j *jolokiaClient) ClearSnapshot(tag string) error {
+	return j.exec(storageService, "clearSnapshot(java.lang.String,[Ljava.lang.String;)", nil, tag, []string{})
+}
+
+func (j *jolokiaClient) CompactionStats() ([]Compaction, error) {
+	var compactions []map[string]string
+	read := jolokiaRequest{Type: "read", MBean: compactionManager, Attribute: "Compactions"}
+	if err := j.call("compactionstats", []jolokiaRequest{read}, &compactions); err != nil {
+		return nil, err
+	}
+	var result []Compaction
+	for _, compaction := range compactions {
+		completed, _ := strconv.ParseInt(compaction["completed"], 10, 64)
+		total, _ := strconv.ParseInt(compaction["total"], 10, 64)
+		result = append(result, Compaction{
+			ID:        compaction["compactionId"],
+			Type:      compaction["taskType"],
+			Keyspace:  compaction["keyspace"],
+			Table:     compaction["columnfamily"],
+			Completed: completed,
+			Total:     total,
+			Unit:      compaction["unit"],
+		})
+	}
+	return result, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
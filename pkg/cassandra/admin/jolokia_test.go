@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeJolokia answers the requests with the values returned by the handler, by operation or attribute
+type fakeJolokia struct {
+	sync.Mutex
+	requests []jolokiaRequest
+	values   func(request jolokiaRequest) (int, interface{})
+}
+
+func (f *fakeJolokia) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var requests []jolokiaRequest
+	if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var responses []map[string]interface{}
+	for _, request := range requests {
+		f.Lock()
+		f.requests = append(f.requests, request)
+		f.Unlock()
+		status, value := f.values(request)
+		responses = append(responses, map[string]interface{}{"status": status, "value": value, "error": "error"})
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func newJolokia(values func(request jolokiaRequest) (int, interface{})) (*fakeJolokia, Client, func()) {
+	fake := &fakeJolokia{values: values}
+	server := httptest.NewServer(fake)
+	return fake, NewJolokiaClient(server.URL+"/jolokia/", time.Second), server.Close
+}
+
+func TestJolokiaInfo(t *testing.T) {
+	_, client, stop := newJolokia(func(request jolokiaRequest) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"LocalHostId": "9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b",
+			"Tokens":      []string{"-9223372036854775808", "0"},
+		}
+	})
+	defer stop()
+	info, err := client.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &NodeInfo{HostID: "9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b", Tokens: []string{"-9223372036854775808", "0"}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestJolokiaRepairStatus(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		state OperationState
+	}{
+		{[]string{"IN_PROGRESS"}, OperationInProgress},
+		{[]string{"COMPLETED", "Repair completed"}, OperationCompleted},
+		{[]string{"FAILED", "Repair failed"}, OperationFailed},
+		// the node restarted
+		{nil, OperationUnknown},
+	}
+	for _, test := range tests {
+		_, client, stop := newJolokia(func(request jolokiaRequest) (int, interface{}) {
+			return http.StatusOK, test.value
+		})
+		state, err := client.RepairStatus(1)
+		stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state != test.state {
+			t.Errorf("%v: got %s, want %s", test.value, state, test.state)
+		}
+	}
+}
+
+func TestJolokiaUpgradeSSTables(t *testing.T) {
+	fake, client, stop := newJolokia(func(request jolokiaRequest) (int, interface{}) {
+		if request.Type == "read" {
+			return http.StatusOK, []string{"system", "orders"}
+		}
+		return http.StatusOK, nil
+	})
+	defer stop()
+	command, err := client.UpgradeSSTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := OperationInProgress
+	for i := 0; i < 100 && state == OperationInProgress; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if state, err = client.UpgradeSSTablesStatus(command); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if state != OperationCompleted {
+		t.Fatalf("got %s, want %s", state, OperationCompleted)
+	}
+	var upgraded []interface{}
+	for _, request := range fake.requests {
+		if request.Type == "exec" {
+			upgraded = append(upgraded, request.Arguments[0])
+		}
+	}
+	if !reflect.DeepEqual(upgraded, []interface{}{"system", "orders"}) {
+		t.Errorf("got the sstables of %v upgraded, want all the keyspaces", upgraded)
+	}
+	// the completed operation is forgotten once read
+	if state, _ := client.UpgradeSSTablesStatus(command); state != OperationUnknown {
+		t.Errorf("got %s for a completed command already read, want %s", state, OperationUnknown)
+	}
+	operations.Lock()
+	if _, ok := operations.m[operationKey(client.(*jolokiaClient).url, command)]; ok {
+		t.Error("the completed operation is kept")
+	}
+	operations.Unlock()
+	// the operations run by another operator are lost
+	if state, _ := client.UpgradeSSTablesStatus(command + 1); state != OperationUnknown {
+		t.Errorf("got %s for an unknown command, want %s", state, OperationUnknown)
+	}
+}
+
+func TestJolokiaError(t *testing.T) {
+	_, client, stop := newJolokia(func(request jolokiaRequest) (int, interface{}) {
+		return http.StatusNotFound, nil
+	})
+	defer stop()
+	if err := client.Drain(); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,76 @@
+package nodetool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Compaction is a compaction running on a node
+type Compaction struct {
+	ID        string
+	Type      string
+	Keyspace  string
+	Table     string
+	Completed int64
+	Total     int64
+	Unit      string
+}
+
+// CompactionStats is the output of nodetool compactionstats
+type CompactionStats struct {
+	PendingTasks int
+	Compactions  []Compaction
+}
+
+// ParseCompactionStats parses the output of nodetool compactionstats. The pending tasks by table of Cassandra 3.11
+// and 4 are ignored:
+//
+//	pending tasks: 1
+//	- orders.audit: 1
+//
+//	id                                   compaction type  keyspace  table  completed  total    unit   progress
+//	2b3c4d5e-1f2a-11e9-8b5c-0d1e2f3a4b5c Compaction       orders    audit  1048576    4194304  bytes  25.00%
+//	Active compaction remaining time :   0h00m03s
+func ParseCompactionStats(output string) (*CompactionStats, error) {
+	stats := &CompactionStats{}
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "pending tasks:") {
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "pending tasks:")))
+			if err != nil {
+				return nil, fmt.Errorf("invalid pending tasks %q", line)
+			}
+			stats.PendingTasks = n
+			found = true
+			continue
+		}
+		fields := strings.Fields(line)
+		// the type of the compaction may contain spaces like "Anticompaction after repair"
+		if len(fields) < 8 || !strings.HasSuffix(fields[len(fields)-1], "%") {
+			continue
+		}
+		n := len(fields)
+		completed, err := strconv.ParseInt(fields[n-4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compaction line %q", strings.TrimSpace(line))
+		}
+		total, err := strconv.ParseInt(fields[n-3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compaction line %q", strings.TrimSpace(line))
+		}
+		stats.Compactions = append(stats.Compactions, Compaction{
+			ID:        fields[0],
+			Type:      strings.Join(fields[1:n-6], " "),
+			Keyspace:  fields[n-6],
+			Table:     fields[n-5],
+			Completed: completed,
+			Total:     total,
+			Unit:      fields[n-2],
+		})
+	}
+	if !found {
+		return nil, fmt.Errorf("no pending tasks in compactionstats: %s", firstLine(output))
+	}
+	return stats, nil
+}
@@ -0,0 +1,81 @@
+package nodetool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unreachableVersion is the schema version of the nodes which didn't answer
+const unreachableVersion = "UNREACHABLE"
+
+// ClusterDescription is the output of nodetool describecluster
+type ClusterDescription struct {
+	Name        string
+	Snitch      string
+	Partitioner string
+	// SchemaVersions are the addresses of the nodes by schema version
+	SchemaVersions map[string][]string
+	// Unreachable are the nodes whose schema version is unknown
+	Unreachable []string
+}
+
+// SchemaAgreement returns true when all the nodes answered with the same schema version
+func (d *ClusterDescription) SchemaAgreement() bool {
+	return len(d.SchemaVersions) == 1 && len(d.Unreachable) == 0
+}
+
+// ParseDescribeCluster parses the output of nodetool describecluster. The addresses are printed with their port
+// by Cassandra 4, which also adds sections after the schema versions:
+//
+//	Cluster Information:
+//		Name: my-cluster
+//		Snitch: org.apache.cassandra.locator.DynamicEndpointSnitch
+//		Partitioner: org.apache.cassandra.dht.Murmur3Partitioner
+//		Schema versions:
+//			86afa796-d883-3932-aa73-6b017cef0d19: [10.0.0.1, 10.0.0.2]
+//
+//			UNREACHABLE: [10.0.0.3]
+func ParseDescribeCluster(output string) (*ClusterDescription, error) {
+	d := &ClusterDescription{SchemaVersions: map[string][]string{}}
+	inVersions := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		parts := strings.SplitN(trimmed, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if inVersions && strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			var addresses []string
+			for _, address := range strings.Split(strings.Trim(value, "[]"), ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					addresses = append(addresses, stripPort(address))
+				}
+			}
+			if key == unreachableVersion {
+				d.Unreachable = append(d.Unreachable, addresses...)
+			} else {
+				d.SchemaVersions[key] = append(d.SchemaVersions[key], addresses...)
+			}
+			continue
+		}
+		inVersions = false
+		switch key {
+		case "Name":
+			d.Name = value
+		case "Snitch":
+			d.Snitch = value
+		case "Partitioner":
+			d.Partitioner = value
+		case "Schema versions":
+			inVersions = true
+		}
+	}
+	if d.Name == "" {
+		return nil, fmt.Errorf("no cluster name in describecluster: %s", firstLine(output))
+	}
+	return d, nil
+}
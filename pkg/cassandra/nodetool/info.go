@@ -0,0 +1,109 @@
+package nodetool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Info is the output of nodetool info
+type Info struct {
+	ID                    string
+	GossipActive          bool
+	NativeTransportActive bool
+	// Load is the size of the data of the node in bytes
+	Load         int64
+	GenerationNo int64
+	Uptime       time.Duration
+	HeapUsedMB   float64
+	HeapMaxMB    float64
+	Datacenter   string
+	Rack         string
+	Exceptions   int64
+	// PercentRepaired is the percentage of the data repaired incrementally, -1 when unknown
+	PercentRepaired float64
+	// Tokens are only listed with nodetool info -T
+	Tokens []string
+}
+
+// ParseInfo parses the output of nodetool info, with the tokens of nodetool info -T:
+//
+//	ID                     : 9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b
+//	Gossip active          : true
+//	Native Transport active: true
+//	Load                   : 108.45 KiB
+//	Generation No          : 1539856312
+//	Uptime (seconds)       : 3600
+//	Heap Memory (MB)       : 245.10 / 1004.00
+//	Data Center            : dc1
+//	Rack                   : rack1
+//	Exceptions             : 0
+//	Percent Repaired       : 100.0%
+//	Token                  : -9223372036854775808
+func ParseInfo(output string) (*Info, error) {
+	info := &Info{PercentRepaired: -1}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		var err error
+		switch key {
+		case "ID":
+			info.ID = value
+		case "Gossip active":
+			info.GossipActive = value == "true"
+		case "Native Transport active":
+			info.NativeTransportActive = value == "true"
+		case "Load":
+			info.Load, _, err = parseLoad(strings.Fields(value), 0)
+		case "Generation No":
+			info.GenerationNo, err = strconv.ParseInt(value, 10, 64)
+		case "Uptime (seconds)":
+			var seconds int64
+			seconds, err = strconv.ParseInt(value, 10, 64)
+			info.Uptime = time.Duration(seconds) * time.Second
+		case "Heap Memory (MB)":
+			info.HeapUsedMB, info.HeapMaxMB, err = parseUsage(value)
+		case "Data Center":
+			info.Datacenter = value
+		case "Rack":
+			info.Rack = value
+		case "Exceptions":
+			info.Exceptions, err = strconv.ParseInt(value, 10, 64)
+		case "Percent Repaired":
+			info.PercentRepaired, err = parsePercent(value)
+		case "Token":
+			// without -T the line tells how to list the tokens
+			if !strings.HasPrefix(value, "(") {
+				info.Tokens = append(info.Tokens, value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in info: %v", key, err)
+		}
+	}
+	if info.ID == "" {
+		return nil, fmt.Errorf("no ID in the info: %s", firstLine(output))
+	}
+	return info, nil
+}
+
+// parseUsage parses a memory usage like "245.10 / 1004.00"
+func parseUsage(value string) (float64, float64, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid usage %q", value)
+	}
+	used, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid usage %q", value)
+	}
+	max, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid usage %q", value)
+	}
+	return used, max, nil
+}
@@ -0,0 +1,35 @@
+package nodetool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseKeyspaces parses the keyspaces listed by nodetool cfstats, with a space before the colon from Cassandra 4:
+//
+//	Total number of tables: 37
+//	----------------
+//	Keyspace : orders
+//		Read Count: 12
+//		...
+//			Table: audit
+func ParseKeyspaces(output string) ([]string, error) {
+	var keyspaces []string
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "Keyspace") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != "Keyspace" {
+			continue
+		}
+		if keyspace := strings.TrimSpace(parts[1]); keyspace != "" {
+			keyspaces = append(keyspaces, keyspace)
+		}
+	}
+	// the system keyspaces are always listed
+	if len(keyspaces) == 0 {
+		return nil, fmt.Errorf("no keyspace in the cfstats: %s", firstLine(output))
+	}
+	return keyspaces, nil
+}
@@ -0,0 +1,102 @@
+package nodetool
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// planRegexp matches the first line of a stream plan like "Bootstrap 5e6b3f10-d3c5-11e8-9f8b-01e2c3d4e5f6"
+	planRegexp = regexp.MustCompile(`^(\S.*?)\s+([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+	// streamRegexp matches the progress of a session, Cassandra 4 adds the percentages in parentheses
+	streamRegexp = regexp.MustCompile(`^(Receiving|Sending) (\d+) files, (\d+) bytes total\. Already (?:received|sent) (\d+) files(?: \([^)]*\))?, (\d+) bytes total`)
+)
+
+// Stream is one direction of a streaming session of the node with a peer
+type Stream struct {
+	// Operation is the description of the stream plan: Bootstrap, Rebuild, Repair, Unbootstrap...
+	Operation string
+	PlanID    string
+	Peer      string
+	// Receiving is true when the node receives the files from the peer, false when it sends them
+	Receiving bool
+	Files     int64
+	Bytes     int64
+	FilesDone int64
+	BytesDone int64
+}
+
+// Netstats is the output of nodetool netstats
+type Netstats struct {
+	// Mode is the operating mode of the node: STARTING, JOINING, NORMAL, LEAVING, DECOMMISSIONED, DRAINED...
+	Mode    string
+	Streams []Stream
+}
+
+// ParseNetstats parses the streams of the output of nodetool netstats, the statistics of the messages are ignored:
+//
+//	Mode: JOINING
+//	Bootstrap 5e6b3f10-d3c5-11e8-9f8b-01e2c3d4e5f6
+//	    /10.0.0.2
+//	        Receiving 12 files, 104857600 bytes total. Already received 3 files, 26214400 bytes total
+//	            /var/lib/cassandra/data/ks/t/mc-1-big-Data.db 1048576/4194304 bytes(25%) received from idx:0/10.0.0.2
+//	        Sending 0 files, 0 bytes total. Already sent 0 files, 0 bytes total
+//	Read Repair Statistics:
+func ParseNetstats(output string) (*Netstats, error) {
+	stats := &Netstats{}
+	operation, planID, peer := "", "", ""
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Mode:") {
+			stats.Mode = strings.TrimSpace(strings.TrimPrefix(trimmed, "Mode:"))
+			continue
+		}
+		if stats.Mode == "" || trimmed == "" {
+			continue
+		}
+		if m := planRegexp.FindStringSubmatch(line); m != nil {
+			operation, planID, peer = m[1], m[2], ""
+			continue
+		}
+		if planID == "" {
+			continue
+		}
+		if m := streamRegexp.FindStringSubmatch(trimmed); m != nil {
+			var values [4]int64
+			for i := range values {
+				n, err := strconv.ParseInt(m[i+2], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid stream line %q", trimmed)
+				}
+				values[i] = n
+			}
+			stats.Streams = append(stats.Streams, Stream{
+				Operation: operation,
+				PlanID:    planID,
+				Peer:      peer,
+				Receiving: m[1] == "Receiving",
+				Files:     values[0],
+				Bytes:     values[1],
+				FilesDone: values[2],
+				BytesDone: values[3],
+			})
+			continue
+		}
+		// the peer of the next sessions, followed by its connecting address when it differs. The files being
+		// streamed are listed after the sessions with their progress
+		if fields := strings.Fields(trimmed); len(fields) == 1 || len(fields) > 1 && fields[1] == "(using" {
+			peer = stripPort(fields[0])
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			// end of the stream plans
+			operation, planID, peer = "", "", ""
+		}
+	}
+	if stats.Mode == "" {
+		return nil, fmt.Errorf("no mode in netstats: %s", firstLine(output))
+	}
+	return stats, nil
+}
@@ -0,0 +1,348 @@
+package nodetool
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readGolden(t *testing.T, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		file  string
+		nodes []Node
+	}{
+		{"status-2.2.txt", []Node{
+			{Datacenter: "dc1", Address: "10.0.0.1", Up: true, State: "N", Load: 111052, Tokens: 256, Owns: 65.4, HostID: "9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b", Rack: "rack1"},
+			{Datacenter: "dc1", Address: "10.0.0.2", Up: true, State: "L", Load: 100474, Tokens: 256, Owns: 67.1, HostID: "3c7e1f2a-0b4d-4e8f-a6c5-7d9e1b2c3a4f", Rack: "rack1"},
+			{Datacenter: "dc1", Address: "10.0.0.3", Up: false, State: "N", Load: -1, Tokens: 256, Owns: 67.5, HostID: "5f0a2b3c-4d5e-4f6a-8b7c-9d0e1f2a3b4c", Rack: "rack1"},
+		}},
+		{"status-3.11.txt", []Node{
+			{Datacenter: "dc1", Address: "10.0.0.1", Up: true, State: "N", Load: 1268776, Tokens: 256, Owns: -1, HostID: "9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b", Rack: "rack1"},
+			{Datacenter: "dc1", Address: "10.0.0.2", Up: true, State: "N", Load: 1247805, Tokens: 256, Owns: -1, HostID: "3c7e1f2a-0b4d-4e8f-a6c5-7d9e1b2c3a4f", Rack: "rack2"},
+			{Datacenter: "dc2", Address: "10.1.0.1", Up: true, State: "N", Load: 1258291, Tokens: 256, Owns: -1, HostID: "5f0a2b3c-4d5e-4f6a-8b7c-9d0e1f2a3b4c", Rack: "rack1"},
+			{Datacenter: "dc2", Address: "10.1.0.2", Up: true, State: "J", Load: 96614, Tokens: 256, Owns: -1, HostID: "7a8b9c0d-1e2f-4a3b-8c4d-5e6f7a8b9c0d", Rack: "rack2"},
+		}},
+		{"status-4.0.txt", []Node{
+			{Datacenter: "dc1", Address: "10.0.0.1", Up: true, State: "N", Load: 240742, Tokens: 16, Owns: 100, HostID: "9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b", Rack: "rack1"},
+			{Datacenter: "dc1", Address: "10.0.0.2", Up: true, State: "N", Load: 246548, Tokens: 16, Owns: 100, HostID: "3c7e1f2a-0b4d-4e8f-a6c5-7d9e1b2c3a4f", Rack: "rack2"},
+			{Datacenter: "dc1", Address: "10.0.0.3", Up: false, State: "N", Load: -1, Tokens: 16, Owns: 100, HostID: "5f0a2b3c-4d5e-4f6a-8b7c-9d0e1f2a3b4c", Rack: "rack3"},
+		}},
+	}
+	for _, test := range tests {
+		nodes, err := ParseStatus(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(nodes, test.nodes) {
+			t.Errorf("%s: got %+v, want %+v", test.file, nodes, test.nodes)
+		}
+	}
+}
+
+func TestNodeCode(t *testing.T) {
+	tests := []struct {
+		node Node
+		code string
+	}{
+		{Node{Up: true, State: "N"}, "UN"},
+		{Node{Up: true, State: "J"}, "UJ"},
+		{Node{Up: true, State: "L"}, "UL"},
+		{Node{Up: false, State: "N"}, "DN"},
+	}
+	for _, test := range tests {
+		if code := test.node.Code(); code != test.code {
+			t.Errorf("%+v: got %s, want %s", test.node, code, test.code)
+		}
+	}
+}
+
+func TestParseRing(t *testing.T) {
+	tests := []struct {
+		file    string
+		entries []RingEntry
+	}{
+		{"ring-2.2.txt", []RingEntry{
+			{Datacenter: "dc1", Address: "10.0.0.1", Rack: "rack1", Up: true, State: "Normal", Load: 111052, Owns: 33.33, Token: "-9223372036854775808"},
+			{Datacenter: "dc1", Address: "10.0.0.2", Rack: "rack1", Up: true, State: "Normal", Load: 100474, Owns: 33.33, Token: "-3074457345618258603"},
+			{Datacenter: "dc1", Address: "10.0.0.3", Rack: "rack1", Up: false, State: "Normal", Load: -1, Owns: 33.33, Token: "3074457345618258602"},
+		}},
+		{"ring-3.11.txt", []RingEntry{
+			{Datacenter: "dc1", Address: "10.0.0.1", Rack: "rack1", Up: true, State: "Normal", Load: 1268776, Owns: -1, Token: "-9223372036854775808"},
+			{Datacenter: "dc1", Address: "10.0.0.2", Rack: "rack2", Up: true, State: "Joining", Load: 96614, Owns: -1, Token: "-4611686018427387904"},
+			{Datacenter: "dc2", Address: "10.1.0.1", Rack: "rack1", Up: true, State: "Normal", Load: 1258291, Owns: -1, Token: "0"},
+			{Datacenter: "dc2", Address: "10.1.0.2", Rack: "rack2", Up: true, State: "Leaving", Load: 1237319, Owns: -1, Token: "4611686018427387904"},
+		}},
+		{"ring-4.0.txt", []RingEntry{
+			{Datacenter: "dc1", Address: "10.0.0.1", Rack: "rack1", Up: true, State: "Normal", Load: 240742, Owns: 100, Token: "-9223372036854775808"},
+			{Datacenter: "dc1", Address: "10.0.0.2", Rack: "rack2", Up: true, State: "Normal", Load: 246548, Owns: 100, Token: "-3074457345618258603"},
+			{Datacenter: "dc1", Address: "10.0.0.3", Rack: "rack3", Up: false, State: "Normal", Load: -1, Owns: 100, Token: "3074457345618258602"},
+		}},
+	}
+	for _, test := range tests {
+		entries, err := ParseRing(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(entries, test.entries) {
+			t.Errorf("%s: got %+v, want %+v", test.file, entries, test.entries)
+		}
+	}
+}
+
+func TestParseTPStats(t *testing.T) {
+	tests := []struct {
+		file     string
+		pools    int
+		pool     ThreadPool
+		dropped  map[string]int64
+		blocked  string
+		nBlocked int64
+	}{
+		{"tpstats-2.2.txt", 18, ThreadPool{Name: "CompactionExecutor", Active: 1, Pending: 3, Completed: 873},
+			map[string]int64{"MUTATION": 14, "READ": 0}, "Native-Transport-Requests", 7},
+		{"tpstats-3.11.txt", 23, ThreadPool{Name: "MutationStage", Active: 3, Pending: 12, Completed: 981234},
+			map[string]int64{"MUTATION": 231, "REQUEST_RESPONSE": 2}, "Native-Transport-Requests", 21},
+		{"tpstats-4.0.txt", 16, ThreadPool{Name: "ReadStage", Pending: 4, Completed: 11020},
+			map[string]int64{"MUTATION_REQ": 5, "READ_REQ": 3, "READ_RSP": 0}, "GossipStage", 0},
+	}
+	for _, test := range tests {
+		stats, err := ParseTPStats(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if len(stats.Pools) != test.pools {
+			t.Errorf("%s: got %d pools, want %d", test.file, len(stats.Pools), test.pools)
+		}
+		if pool := stats.Pool(test.pool.Name); pool == nil || *pool != test.pool {
+			t.Errorf("%s: got %+v, want %+v", test.file, pool, test.pool)
+		}
+		if pool := stats.Pool(test.blocked); pool == nil || pool.AllTimeBlocked != test.nBlocked {
+			t.Errorf("%s: got %+v, want %d all time blocked", test.file, pool, test.nBlocked)
+		}
+		for message, dropped := range test.dropped {
+			if stats.Dropped[message] != dropped {
+				t.Errorf("%s: got %d %s dropped, want %d", test.file, stats.Dropped[message], message, dropped)
+			}
+		}
+	}
+}
+
+func TestParseDescribeCluster(t *testing.T) {
+	tests := []struct {
+		file        string
+		description *ClusterDescription
+		agreement   bool
+	}{
+		{"describecluster-2.2.txt", &ClusterDescription{
+			Name:           "my-cluster",
+			Snitch:         "org.apache.cassandra.locator.DynamicEndpointSnitch",
+			Partitioner:    "org.apache.cassandra.dht.Murmur3Partitioner",
+			SchemaVersions: map[string][]string{"86afa796-d883-3932-aa73-6b017cef0d19": {"10.0.0.1", "10.0.0.2"}},
+			Unreachable:    []string{"10.0.0.3"},
+		}, false},
+		{"describecluster-3.11.txt", &ClusterDescription{
+			Name:        "my-cluster",
+			Snitch:      "org.apache.cassandra.locator.DynamicEndpointSnitch",
+			Partitioner: "org.apache.cassandra.dht.Murmur3Partitioner",
+			SchemaVersions: map[string][]string{
+				"86afa796-d883-3932-aa73-6b017cef0d19": {"10.0.0.1", "10.1.0.1"},
+				"ea63e099-37c5-3d7b-9ace-32f4c833653d": {"10.0.0.2"},
+			},
+		}, false},
+		{"describecluster-4.0.txt", &ClusterDescription{
+			Name:           "my-cluster",
+			Snitch:         "org.apache.cassandra.locator.SimpleSnitch",
+			Partitioner:    "org.apache.cassandra.dht.Murmur3Partitioner",
+			SchemaVersions: map[string][]string{"2207c2a9-f598-3971-986b-2926e09e239d": {"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		}, true},
+	}
+	for _, test := range tests {
+		description, err := ParseDescribeCluster(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(description, test.description) {
+			t.Errorf("%s: got %+v, want %+v", test.file, description, test.description)
+		}
+		if agreement := description.SchemaAgreement(); agreement != test.agreement {
+			t.Errorf("%s: got schema agreement %v, want %v", test.file, agreement, test.agreement)
+		}
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		file string
+		info *Info
+	}{
+		{"info-2.2.txt", &Info{
+			ID:                    "9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b",
+			GossipActive:          true,
+			NativeTransportActive: true,
+			Load:                  111052,
+			GenerationNo:          1539856312,
+			Uptime:                time.Hour,
+			HeapUsedMB:            245.10,
+			HeapMaxMB:             1004,
+			Datacenter:            "dc1",
+			Rack:                  "rack1",
+			PercentRepaired:       -1,
+		}},
+		{"info-3.11.txt", &Info{
+			ID:                    "3c7e1f2a-0b4d-4e8f-a6c5-7d9e1b2c3a4f",
+			GossipActive:          true,
+			NativeTransportActive: true,
+			Load:                  1268776,
+			GenerationNo:          1539856312,
+			Uptime:                24 * time.Hour,
+			HeapUsedMB:            512.43,
+			HeapMaxMB:             2008,
+			Datacenter:            "dc1",
+			Rack:                  "rack2",
+			Exceptions:            2,
+			// no data was repaired yet
+			PercentRepaired: -1,
+			Tokens:          []string{"-9223372036854775808", "-3074457345618258603", "3074457345618258602"},
+		}},
+		{"info-4.0.txt", &Info{
+			ID:                    "5f0a2b3c-4d5e-4f6a-8b7c-9d0e1f2a3b4c",
+			GossipActive:          true,
+			NativeTransportActive: true,
+			Load:                  240742,
+			GenerationNo:          1633072800,
+			Uptime:                2 * time.Minute,
+			HeapUsedMB:            181.56,
+			HeapMaxMB:             1004,
+			Datacenter:            "dc1",
+			Rack:                  "rack3",
+			PercentRepaired:       87.5,
+		}},
+	}
+	for _, test := range tests {
+		info, err := ParseInfo(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(info, test.info) {
+			t.Errorf("%s: got %+v, want %+v", test.file, info, test.info)
+		}
+	}
+}
+
+func TestParseCompactionStats(t *testing.T) {
+	tests := []struct {
+		file  string
+		stats *CompactionStats
+	}{
+		{"compactionstats-2.2.txt", &CompactionStats{PendingTasks: 2, Compactions: []Compaction{
+			{ID: "2b3c4d5e-1f2a-11e9-8b5c-0d1e2f3a4b5c", Type: "Compaction", Keyspace: "orders", Table: "audit", Completed: 1048576, Total: 4194304, Unit: "bytes"},
+		}}},
+		{"compactionstats-3.11.txt", &CompactionStats{PendingTasks: 1, Compactions: []Compaction{
+			{ID: "2b3c4d5e-1f2a-11e9-8b5c-0d1e2f3a4b5c", Type: "Compaction", Keyspace: "orders", Table: "audit", Completed: 1048576, Total: 4194304, Unit: "bytes"},
+		}}},
+		{"compactionstats-4.0.txt", &CompactionStats{PendingTasks: 0, Compactions: []Compaction{
+			{ID: "6a7b8c9d-0e1f-11ec-9a2b-3c4d5e6f7a8b", Type: "Anticompaction after repair", Keyspace: "orders", Table: "audit", Completed: 524288, Total: 2097152, Unit: "bytes"},
+		}}},
+	}
+	for _, test := range tests {
+		stats, err := ParseCompactionStats(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(stats, test.stats) {
+			t.Errorf("%s: got %+v, want %+v", test.file, stats, test.stats)
+		}
+	}
+}
+
+func TestParseNetstats(t *testing.T) {
+	tests := []struct {
+		file  string
+		stats *Netstats
+	}{
+		{"netstats-2.2.txt", &Netstats{Mode: "NORMAL"}},
+		{"netstats-3.11.txt", &Netstats{Mode: "JOINING", Streams: []Stream{
+			{Operation: "Bootstrap", PlanID: "5e6b3f10-d3c5-11e8-9f8b-01e2c3d4e5f6", Peer: "10.0.0.2", Receiving: true, Files: 12, Bytes: 104857600, FilesDone: 3, BytesDone: 26214400},
+			{Operation: "Bootstrap", PlanID: "5e6b3f10-d3c5-11e8-9f8b-01e2c3d4e5f6", Peer: "10.0.0.2"},
+		}}},
+		{"netstats-4.0.txt", &Netstats{Mode: "NORMAL", Streams: []Stream{
+			{Operation: "Repair", PlanID: "7c1d2e3f-0a1b-11ec-8c9d-0e1f2a3b4c5d", Peer: "10.0.0.3", Receiving: true, Files: 2, Bytes: 2097152, FilesDone: 1, BytesDone: 1048576},
+			{Operation: "Repair", PlanID: "7c1d2e3f-0a1b-11ec-8c9d-0e1f2a3b4c5d", Peer: "10.0.0.3", Files: 1, Bytes: 1048576, FilesDone: 1, BytesDone: 1048576},
+		}}},
+	}
+	for _, test := range tests {
+		stats, err := ParseNetstats(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(stats, test.stats) {
+			t.Errorf("%s: got %+v, want %+v", test.file, stats, test.stats)
+		}
+	}
+}
+
+func TestParseKeyspaces(t *testing.T) {
+	tests := []struct {
+		file      string
+		keyspaces []string
+	}{
+		{"cfstats-2.2.txt", []string{"system_traces", "orders", "system", "system_auth"}},
+		{"cfstats-3.11.txt", []string{"system_traces", "system", "system_schema", "orders"}},
+		{"cfstats-4.0.txt", []string{"system", "system_schema", "orders", "system_distributed"}},
+	}
+	for _, test := range tests {
+		keyspaces, err := ParseKeyspaces(readGolden(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(keyspaces, test.keyspaces) {
+			t.Errorf("%s: got %v, want %v", test.file, keyspaces, test.keyspaces)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	output := "nodetool: Failed to connect to '127.0.0.1:7199' - ConnectException: 'Connection refused (Connection refused)'."
+	if _, err := ParseStatus(output); err == nil {
+		t.Error("ParseStatus: expected an error")
+	}
+	if _, err := ParseRing(output); err == nil {
+		t.Error("ParseRing: expected an error")
+	}
+	if _, err := ParseTPStats(output); err == nil {
+		t.Error("ParseTPStats: expected an error")
+	}
+	if _, err := ParseDescribeCluster(output); err == nil {
+		t.Error("ParseDescribeCluster: expected an error")
+	}
+	if _, err := ParseInfo(output); err == nil {
+		t.Error("ParseInfo: expected an error")
+	}
+	if _, err := ParseCompactionStats(output); err == nil {
+		t.Error("ParseCompactionStats: expected an error")
+	}
+	if _, err := ParseNetstats(output); err == nil {
+		t.Error("ParseNetstats: expected an error")
+	}
+	if _, err := ParseKeyspaces(output); err == nil {
+		t.Error("ParseKeyspaces: expected an error")
+	}
+}
@@ -0,0 +1,75 @@
+package nodetool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RingEntry is a token of the output of nodetool ring
+type RingEntry struct {
+	Datacenter string
+	Address    string
+	Rack       string
+	Up         bool
+	// State is Normal, Leaving, Joining or Moving
+	State string
+	// Load is the size of the data of the node in bytes, -1 when unknown
+	Load int64
+	// Owns is the percentage of the data owned by the node, -1 when unknown
+	Owns  float64
+	Token string
+}
+
+// ParseRing parses the output of nodetool ring:
+//
+//	Datacenter: dc1
+//	==========
+//	Address     Rack        Status State   Load            Owns                Token
+//	                                                                           3074457345618258602
+//	10.0.0.1    rack1       Up     Normal  108.45 KiB      33.33%              -9223372036854775808
+func ParseRing(output string) ([]RingEntry, error) {
+	var entries []RingEntry
+	datacenter, header := "", false
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Datacenter:") {
+			datacenter = strings.TrimSpace(strings.TrimPrefix(line, "Datacenter:"))
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[0] == "Address" && fields[1] == "Rack" {
+			header = true
+			continue
+		}
+		// the line before the tokens of each datacenter only has its last token
+		if !header || len(fields) < 3 || (fields[2] != "Up" && fields[2] != "Down") {
+			continue
+		}
+		entry := RingEntry{
+			Datacenter: datacenter,
+			Address:    stripPort(fields[0]),
+			Rack:       fields[1],
+			Up:         fields[2] == "Up",
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("invalid ring line %q", strings.TrimSpace(line))
+		}
+		entry.State = fields[3]
+		load, i, err := parseLoad(fields, 4)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ring line %q: %v", strings.TrimSpace(line), err)
+		}
+		entry.Load = load
+		if len(fields) != i+2 {
+			return nil, fmt.Errorf("invalid ring line %q: %d columns", strings.TrimSpace(line), len(fields))
+		}
+		if entry.Owns, err = parsePercent(fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid ring line %q: %v", strings.TrimSpace(line), err)
+		}
+		entry.Token = fields[i+1]
+		entries = append(entries, entry)
+	}
+	if !header {
+		return nil, fmt.Errorf("no token in the ring: %s", firstLine(output))
+	}
+	return entries, nil
+}
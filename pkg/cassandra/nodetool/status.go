@@ -0,0 +1,106 @@
+// Package nodetool parses the output of the nodetool commands of Cassandra 2.2, 3.x and 4.x
+package nodetool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Node is a node of the output of nodetool status
+type Node struct {
+	Datacenter string
+	Address    string
+	Up         bool
+	// State is N (Normal), L (Leaving), J (Joining) or M (Moving)
+	State string
+	// Load is the size of the data of the node in bytes, -1 when unknown
+	Load int64
+	// Tokens is the number of tokens of the node
+	Tokens int
+	// Owns is the percentage of the data owned by the node, -1 when unknown
+	Owns   float64
+	HostID string
+	Rack   string
+}
+
+// Code returns the state of the node like nodetool: UN, DN, UL...
+func (n Node) Code() string {
+	if n.Up {
+		return "U" + n.State
+	}
+	return "D" + n.State
+}
+
+// ParseStatus parses the output of nodetool status:
+//
+//	Datacenter: dc1
+//	===============
+//	Status=Up/Down
+//	|/ State=Normal/Leaving/Joining/Moving
+//	--  Address    Load       Tokens       Owns (effective)  Host ID                               Rack
+//	UN  10.0.0.1   108.45 KiB  256          65.4%             9a1b7f04-7ed6-4f4e-9c3e-2a1e0f4d5c6b  rack1
+//	DN  10.0.0.2   ?          256          ?                 3c7e1f2a-0b4d-4e8f-a6c5-7d9e1b2c3a4f  rack2
+func ParseStatus(output string) ([]Node, error) {
+	var nodes []Node
+	datacenter, header := "", false
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Datacenter:") {
+			datacenter = strings.TrimSpace(strings.TrimPrefix(line, "Datacenter:"))
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[0] == "--" && fields[1] == "Address" {
+			header = true
+			continue
+		}
+		if !header || len(fields) == 0 || !isStateCode(fields[0]) {
+			continue
+		}
+		node, err := parseStatusLine(fields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status line %q: %v", strings.TrimSpace(line), err)
+		}
+		node.Datacenter = datacenter
+		nodes = append(nodes, node)
+	}
+	if !header {
+		return nil, fmt.Errorf("no node in the status: %s", firstLine(output))
+	}
+	return nodes, nil
+}
+
+func parseStatusLine(fields []string) (Node, error) {
+	node := Node{
+		Address: fields[1],
+		Up:      fields[0][0] == 'U',
+		State:   fields[0][1:],
+	}
+	load, i, err := parseLoad(fields, 2)
+	if err != nil {
+		return node, err
+	}
+	node.Load = load
+	// the host ID and the rack follow the tokens and the ownership
+	if len(fields) < i+4 {
+		return node, fmt.Errorf("%d columns", len(fields))
+	}
+	if node.Tokens, err = strconv.Atoi(fields[i]); err != nil {
+		return node, fmt.Errorf("invalid tokens %q", fields[i])
+	}
+	if node.Owns, err = parsePercent(fields[i+1]); err != nil {
+		return node, err
+	}
+	node.HostID = fields[i+2]
+	node.Rack = strings.Join(fields[i+3:], " ")
+	return node, nil
+}
+
+// isStateCode returns true for the states of the nodes like UN or DL
+func isStateCode(s string) bool {
+	return len(s) == 2 && strings.ContainsAny(s[:1], "UD") && strings.ContainsAny(s[1:], "NLJM")
+}
+
+func firstLine(output string) string {
+	return strings.SplitN(strings.TrimSpace(output), "\n", 2)[0]
+}
@@ -0,0 +1,103 @@
+package nodetool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ThreadPool is a thread pool of the output of nodetool tpstats
+type ThreadPool struct {
+	Name           string
+	Active         int64
+	Pending        int64
+	Completed      int64
+	Blocked        int64
+	AllTimeBlocked int64
+}
+
+// TPStats is the output of nodetool tpstats
+type TPStats struct {
+	Pools []ThreadPool
+	// Dropped is the number of dropped messages by message type
+	Dropped map[string]int64
+}
+
+// Pool returns the thread pool with the name, or nil
+func (t *TPStats) Pool(name string) *ThreadPool {
+	for i := range t.Pools {
+		if t.Pools[i].Name == name {
+			return &t.Pools[i]
+		}
+	}
+	return nil
+}
+
+// ParseTPStats parses the output of nodetool tpstats. The latency columns of the dropped messages of Cassandra 4
+// are ignored:
+//
+//	Pool Name                         Active   Pending      Completed   Blocked  All time blocked
+//	MutationStage                          0         0          12345         0                 0
+//
+//	Message type           Dropped
+//	READ                         0
+func ParseTPStats(output string) (*TPStats, error) {
+	stats := &TPStats{Dropped: map[string]int64{}}
+	section := ""
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			section = ""
+			continue
+		case strings.HasPrefix(line, "Pool Name"):
+			section = "pools"
+			continue
+		case strings.HasPrefix(line, "Message type"):
+			section = "dropped"
+			continue
+		}
+		switch section {
+		case "pools":
+			if len(fields) < 6 {
+				return nil, fmt.Errorf("invalid thread pool line %q", strings.TrimSpace(line))
+			}
+			var values [5]int64
+			for i := range values {
+				// Cassandra 2.2 prints "n/a" for the pools without blocked tasks
+				if v := fields[i+1]; v != "n/a" {
+					n, err := strconv.ParseInt(v, 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("invalid thread pool line %q", strings.TrimSpace(line))
+					}
+					values[i] = n
+				}
+			}
+			stats.Pools = append(stats.Pools, ThreadPool{
+				Name:           fields[0],
+				Active:         values[0],
+				Pending:        values[1],
+				Completed:      values[2],
+				Blocked:        values[3],
+				AllTimeBlocked: values[4],
+			})
+		case "dropped":
+			// second line of the header of the latencies
+			if strings.HasSuffix(fields[0], "%") {
+				continue
+			}
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid dropped messages line %q", strings.TrimSpace(line))
+			}
+			n, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dropped messages line %q", strings.TrimSpace(line))
+			}
+			stats.Dropped[fields[0]] = n
+		}
+	}
+	if len(stats.Pools) == 0 {
+		return nil, fmt.Errorf("no thread pool in tpstats: %s", firstLine(output))
+	}
+	return stats, nil
+}
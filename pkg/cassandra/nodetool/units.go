@@ -0,0 +1,79 @@
+package nodetool
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the units of the sizes printed by nodetool, the KB of Cassandra 2.2 are KiB
+var sizeUnits = map[string]int64{
+	"bytes": 1,
+	"KB":    1 << 10,
+	"KiB":   1 << 10,
+	"MB":    1 << 20,
+	"MiB":   1 << 20,
+	"GB":    1 << 30,
+	"GiB":   1 << 30,
+	"TB":    1 << 40,
+	"TiB":   1 << 40,
+}
+
+// parseLoad parses the load starting at the field i, like "108.45 KiB" or "?" for an unknown load. It returns the
+// load in bytes, -1 when unknown, and the index of the following field
+func parseLoad(fields []string, i int) (int64, int, error) {
+	if i >= len(fields) {
+		return 0, i, fmt.Errorf("missing load")
+	}
+	if fields[i] == "?" {
+		return -1, i + 1, nil
+	}
+	if i+1 >= len(fields) {
+		return 0, i, fmt.Errorf("missing unit of the load %q", fields[i])
+	}
+	size, err := parseSize(fields[i], fields[i+1])
+	return size, i + 2, err
+}
+
+// parseSize returns the number of bytes of a size like 108.45 KiB
+func parseSize(value string, unit string) (int64, error) {
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(f * float64(multiplier)), nil
+}
+
+// parsePercent parses a percentage like 65.4%, "?" and "NaN%" are returned as -1
+func parsePercent(s string) (float64, error) {
+	if s == "?" {
+		return -1, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	if math.IsNaN(f) {
+		return -1, nil
+	}
+	return f, nil
+}
+
+// stripPort removes the storage port printed after the addresses by Cassandra 4: 10.0.0.1:7000 is 10.0.0.1
+func stripPort(address string) string {
+	address = strings.TrimPrefix(address, "/")
+	i := strings.LastIndex(address, ":")
+	if i < 0 || strings.Count(address, ":") > 1 && !strings.HasPrefix(address, "[") {
+		// an IPv6 address without port
+		return address
+	}
+	if _, err := strconv.Atoi(address[i+1:]); err != nil {
+		return address
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(address[:i], "["), "]")
+}
@@ -0,0 +1,200 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/pavel-v-chernykh/keystore-go"
+)
+
+const (
+	// keySize is the size of the RSA keys of the CA and of the nodes
+	keySize = 2048
+	// caValidity is the validity of the generated certificate authorities
+	caValidity = 10 * 365 * 24 * time.Hour
+)
+
+// CA is a certificate authority signing the certificates of the nodes
+type CA struct {
+	Cert *x509.Certificate
+	Key  *rsa.PrivateKey
+}
+
+// NewCA generates a self-signed certificate authority
+func NewCA(commonName string) (*CA, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Cert: cert, Key: key}, nil
+}
+
+// ParseCA reads a certificate authority from its PEM encoded certificate and private key
+func ParseCA(certPEM []byte, keyPEM []byte) (*CA, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key found")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("the private key of the CA isn't a RSA key")
+		}
+		key = rsaKey
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return nil, err
+	}
+	return &CA{Cert: cert, Key: key}, nil
+}
+
+// ParseCertificates reads the PEM encoded certificates of a bundle
+func ParseCertificates(certsPEM []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, certsPEM = pem.Decode(certsPEM)
+		if block == nil {
+			return certs, nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// CertPEM returns the PEM encoded certificate of the CA
+func (ca *CA) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw})
+}
+
+// KeyPEM returns the PEM encoded private key of the CA
+func (ca *CA) KeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(ca.Key)})
+}
+
+// Certificate is a certificate of a node signed by the CA
+type Certificate struct {
+	Cert *x509.Certificate
+	Key  *rsa.PrivateKey
+}
+
+// Issue signs a certificate for the DNS names, valid until notAfter. It's used by the nodes both as server and as
+// client of the other nodes
+func (ca *CA) Issue(dnsNames []string, notAfter time.Time) (*Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.Key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{Cert: cert, Key: key}, nil
+}
+
+// KeyStore returns a JKS keystore holding the private key and the certificate chain of the node
+func (ca *CA) KeyStore(cert *Certificate, alias string, password string) ([]byte, error) {
+	key, err := x509.MarshalPKCS8PrivateKey(cert.Key)
+	if err != nil {
+		return nil, err
+	}
+	ks := keystore.KeyStore{
+		alias: &keystore.PrivateKeyEntry{
+			Entry:   keystore.Entry{CreationDate: time.Now()},
+			PrivKey: key,
+			CertChain: []keystore.Certificate{
+				{Type: "X509", Content: cert.Cert.Raw},
+				{Type: "X509", Content: ca.Cert.Raw},
+			},
+		},
+	}
+	return encode(ks, password)
+}
+
+// TrustStore returns a JKS truststore holding the certificate of the CA and the other trusted certificates, like
+// the previous CA during a rotation
+func (ca *CA) TrustStore(password string, trusted ...*x509.Certificate) ([]byte, error) {
+	ks := keystore.KeyStore{
+		"ca": &keystore.TrustedCertificateEntry{
+			Entry:       keystore.Entry{CreationDate: time.Now()},
+			Certificate: keystore.Certificate{Type: "X509", Content: ca.Cert.Raw},
+		},
+	}
+	for i, cert := range trusted {
+		ks[fmt.Sprintf("ca-%d", i+1)] = &keystore.TrustedCertificateEntry{
+			Entry:       keystore.Entry{CreationDate: time.Now()},
+			Certificate: keystore.Certificate{Type: "X509", Content: cert.Raw},
+		}
+	}
+	return encode(ks, password)
+}
+
+func encode(ks keystore.KeyStore, password string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := keystore.Encode(&buf, ks, []byte(password)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newSerial returns a random serial number for a certificate
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
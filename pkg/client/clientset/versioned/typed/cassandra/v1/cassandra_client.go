@@ -25,7 +25,11 @@ import (
 
 type CassandraV1Interface interface {
 	RESTClient() rest.Interface
+	CassandraBackupsGetter
 	CassandraClustersGetter
+	CassandraKeyspacesGetter
+	CassandraRestoresGetter
+	CassandraRolesGetter
 }
 
 // CassandraV1Client is used to interact with features provided by the cassandra group.
@@ -33,10 +37,26 @@ type CassandraV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *CassandraV1Client) CassandraBackups(namespace string) CassandraBackupInterface {
+	return newCassandraBackups(c, namespace)
+}
+
 func (c *CassandraV1Client) CassandraClusters(namespace string) CassandraClusterInterface {
 	return newCassandraClusters(c, namespace)
 }
 
+func (c *CassandraV1Client) CassandraKeyspaces(namespace string) CassandraKeyspaceInterface {
+	return newCassandraKeyspaces(c, namespace)
+}
+
+func (c *CassandraV1Client) CassandraRestores(namespace string) CassandraRestoreInterface {
+	return newCassandraRestores(c, namespace)
+}
+
+func (c *CassandraV1Client) CassandraRoles(namespace string) CassandraRoleInterface {
+	return newCassandraRoles(c, namespace)
+}
+
 // NewForConfig creates a new CassandraV1Client for the given config.
 func NewForConfig(c *rest.Config) (*CassandraV1Client, error) {
 	config := *c
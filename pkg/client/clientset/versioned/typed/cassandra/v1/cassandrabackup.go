@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	scheme "github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// CassandraBackupsGetter has a method to return a CassandraBackupInterface.
+// A group's client should implement this interface.
+type CassandraBackupsGetter interface {
+	CassandraBackups(namespace string) CassandraBackupInterface
+}
+
+// CassandraBackupInterface has methods to work with CassandraBackup resources.
+type CassandraBackupInterface interface {
+	Create(*v1.CassandraBackup) (*v1.CassandraBackup, error)
+	Update(*v1.CassandraBackup) (*v1.CassandraBackup, error)
+	UpdateStatus(*v1.CassandraBackup) (*v1.CassandraBackup, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.CassandraBackup, error)
+	List(opts meta_v1.ListOptions) (*v1.CassandraBackupList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraBackup, err error)
+	CassandraBackupExpansion
+}
+
+// cassandraBackups implements CassandraBackupInterface
+type cassandraBackups struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCassandraBackups returns a CassandraBackups
+func newCassandraBackups(c *CassandraV1Client, namespace string) *cassandraBackups {
+	return &cassandraBackups{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the cassandraBackup, and returns the corresponding cassandraBackup object, and an error if there is any.
+func (c *cassandraBackups) Get(name string, options meta_v1.GetOptions) (result *v1.CassandraBackup, err error) {
+	result = &v1.CassandraBackup{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CassandraBackups that match those selectors.
+func (c *cassandraBackups) List(opts meta_v1.ListOptions) (result *v1.CassandraBackupList, err error) {
+	result = &v1.CassandraBackupList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraBackups.
+func (c *cassandraBackups) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Create takes the representation of a cassandraBackup and creates it.  Returns the server's representation of the cassandraBackup, and an error, if there is any.
+func (c *cassandraBackups) Create(cassandraBackup *v1.CassandraBackup) (result *v1.CassandraBackup, err error) {
+	result = &v1.CassandraBackup{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		Body(cassandraBackup).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a cassandraBackup and updates it. Returns the server's representation of the cassandraBackup, and an error, if there is any.
+func (c *cassandraBackups) Update(cassandraBackup *v1.CassandraBackup) (result *v1.CassandraBackup, err error) {
+	result = &v1.CassandraBackup{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		Name(cassandraBackup.Name).
+		Body(cassandraBackup).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *cassandraBackups) UpdateStatus(cassandraBackup *v1.CassandraBackup) (result *v1.CassandraBackup, err error) {
+	result = &v1.CassandraBackup{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		Name(cassandraBackup.Name).
+		SubResource("status").
+		Body(cassandraBackup).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the cassandraBackup and deletes it. Returns an error if one occurs.
+func (c *cassandraBackups) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *cassandraBackups) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched cassandraBackup.
+func (c *cassandraBackups) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraBackup, err error) {
+	result = &v1.CassandraBackup{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("cassandrabackups").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
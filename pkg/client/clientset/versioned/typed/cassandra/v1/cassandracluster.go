@@ -35,6 +35,7 @@ type CassandraClustersGetter interface {
 type CassandraClusterInterface interface {
 	Create(*v1.CassandraCluster) (*v1.CassandraCluster, error)
 	Update(*v1.CassandraCluster) (*v1.CassandraCluster, error)
+	UpdateStatus(*v1.CassandraCluster) (*v1.CassandraCluster, error)
 	Delete(name string, options *meta_v1.DeleteOptions) error
 	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
 	Get(name string, options meta_v1.GetOptions) (*v1.CassandraCluster, error)
@@ -118,6 +119,22 @@ func (c *cassandraClusters) Update(cassandraCluster *v1.CassandraCluster) (resul
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *cassandraClusters) UpdateStatus(cassandraCluster *v1.CassandraCluster) (result *v1.CassandraCluster, err error) {
+	result = &v1.CassandraCluster{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandraclusters").
+		Name(cassandraCluster.Name).
+		SubResource("status").
+		Body(cassandraCluster).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the cassandraCluster and deletes it. Returns an error if one occurs.
 func (c *cassandraClusters) Delete(name string, options *meta_v1.DeleteOptions) error {
 	return c.client.Delete().
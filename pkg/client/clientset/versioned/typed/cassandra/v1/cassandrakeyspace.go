@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	scheme "github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// CassandraKeyspacesGetter has a method to return a CassandraKeyspaceInterface.
+// A group's client should implement this interface.
+type CassandraKeyspacesGetter interface {
+	CassandraKeyspaces(namespace string) CassandraKeyspaceInterface
+}
+
+// CassandraKeyspaceInterface has methods to work with CassandraKeyspace resources.
+type CassandraKeyspaceInterface interface {
+	Create(*v1.CassandraKeyspace) (*v1.CassandraKeyspace, error)
+	Update(*v1.CassandraKeyspace) (*v1.CassandraKeyspace, error)
+	UpdateStatus(*v1.CassandraKeyspace) (*v1.CassandraKeyspace, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.CassandraKeyspace, error)
+	List(opts meta_v1.ListOptions) (*v1.CassandraKeyspaceList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraKeyspace, err error)
+	CassandraKeyspaceExpansion
+}
+
+// cassandraKeyspaces implements CassandraKeyspaceInterface
+type cassandraKeyspaces struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCassandraKeyspaces returns a CassandraKeyspaces
+func newCassandraKeyspaces(c *CassandraV1Client, namespace string) *cassandraKeyspaces {
+	return &cassandraKeyspaces{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the cassandraKeyspace, and returns the corresponding cassandraKeyspace object, and an error if there is any.
+func (c *cassandraKeyspaces) Get(name string, options meta_v1.GetOptions) (result *v1.CassandraKeyspace, err error) {
+	result = &v1.CassandraKeyspace{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CassandraKeyspaces that match those selectors.
+func (c *cassandraKeyspaces) List(opts meta_v1.ListOptions) (result *v1.CassandraKeyspaceList, err error) {
+	result = &v1.CassandraKeyspaceList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraKeyspaces.
+func (c *cassandraKeyspaces) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Create takes the representation of a cassandraKeyspace and creates it.  Returns the server's representation of the cassandraKeyspace, and an error, if there is any.
+func (c *cassandraKeyspaces) Create(cassandraKeyspace *v1.CassandraKeyspace) (result *v1.CassandraKeyspace, err error) {
+	result = &v1.CassandraKeyspace{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		Body(cassandraKeyspace).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a cassandraKeyspace and updates it. Returns the server's representation of the cassandraKeyspace, and an error, if there is any.
+func (c *cassandraKeyspaces) Update(cassandraKeyspace *v1.CassandraKeyspace) (result *v1.CassandraKeyspace, err error) {
+	result = &v1.CassandraKeyspace{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		Name(cassandraKeyspace.Name).
+		Body(cassandraKeyspace).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *cassandraKeyspaces) UpdateStatus(cassandraKeyspace *v1.CassandraKeyspace) (result *v1.CassandraKeyspace, err error) {
+	result = &v1.CassandraKeyspace{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		Name(cassandraKeyspace.Name).
+		SubResource("status").
+		Body(cassandraKeyspace).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the cassandraKeyspace and deletes it. Returns an error if one occurs.
+func (c *cassandraKeyspaces) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *cassandraKeyspaces) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched cassandraKeyspace.
+func (c *cassandraKeyspaces) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraKeyspace, err error) {
+	result = &v1.CassandraKeyspace{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("cassandrakeyspaces").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
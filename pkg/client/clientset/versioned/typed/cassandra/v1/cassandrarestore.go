@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	scheme "github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// CassandraRestoresGetter has a method to return a CassandraRestoreInterface.
+// A group's client should implement this interface.
+type CassandraRestoresGetter interface {
+	CassandraRestores(namespace string) CassandraRestoreInterface
+}
+
+// CassandraRestoreInterface has methods to work with CassandraRestore resources.
+type CassandraRestoreInterface interface {
+	Create(*v1.CassandraRestore) (*v1.CassandraRestore, error)
+	Update(*v1.CassandraRestore) (*v1.CassandraRestore, error)
+	UpdateStatus(*v1.CassandraRestore) (*v1.CassandraRestore, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.CassandraRestore, error)
+	List(opts meta_v1.ListOptions) (*v1.CassandraRestoreList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraRestore, err error)
+	CassandraRestoreExpansion
+}
+
+// cassandraRestores implements CassandraRestoreInterface
+type cassandraRestores struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCassandraRestores returns a CassandraRestores
+func newCassandraRestores(c *CassandraV1Client, namespace string) *cassandraRestores {
+	return &cassandraRestores{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the cassandraRestore, and returns the corresponding cassandraRestore object, and an error if there is any.
+func (c *cassandraRestores) Get(name string, options meta_v1.GetOptions) (result *v1.CassandraRestore, err error) {
+	result = &v1.CassandraRestore{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CassandraRestores that match those selectors.
+func (c *cassandraRestores) List(opts meta_v1.ListOptions) (result *v1.CassandraRestoreList, err error) {
+	result = &v1.CassandraRestoreList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraRestores.
+func (c *cassandraRestores) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Create takes the representation of a cassandraRestore and creates it.  Returns the server's representation of the cassandraRestore, and an error, if there is any.
+func (c *cassandraRestores) Create(cassandraRestore *v1.CassandraRestore) (result *v1.CassandraRestore, err error) {
+	result = &v1.CassandraRestore{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		Body(cassandraRestore).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a cassandraRestore and updates it. Returns the server's representation of the cassandraRestore, and an error, if there is any.
+func (c *cassandraRestores) Update(cassandraRestore *v1.CassandraRestore) (result *v1.CassandraRestore, err error) {
+	result = &v1.CassandraRestore{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		Name(cassandraRestore.Name).
+		Body(cassandraRestore).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *cassandraRestores) UpdateStatus(cassandraRestore *v1.CassandraRestore) (result *v1.CassandraRestore, err error) {
+	result = &v1.CassandraRestore{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		Name(cassandraRestore.Name).
+		SubResource("status").
+		Body(cassandraRestore).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the cassandraRestore and deletes it. Returns an error if one occurs.
+func (c *cassandraRestores) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *cassandraRestores) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched cassandraRestore.
+func (c *cassandraRestores) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraRestore, err error) {
+	result = &v1.CassandraRestore{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("cassandrarestores").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	scheme "github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// CassandraRolesGetter has a method to return a CassandraRoleInterface.
+// A group's client should implement this interface.
+type CassandraRolesGetter interface {
+	CassandraRoles(namespace string) CassandraRoleInterface
+}
+
+// CassandraRoleInterface has methods to work with CassandraRole resources.
+type CassandraRoleInterface interface {
+	Create(*v1.CassandraRole) (*v1.CassandraRole, error)
+	Update(*v1.CassandraRole) (*v1.CassandraRole, error)
+	UpdateStatus(*v1.CassandraRole) (*v1.CassandraRole, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.CassandraRole, error)
+	List(opts meta_v1.ListOptions) (*v1.CassandraRoleList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraRole, err error)
+	CassandraRoleExpansion
+}
+
+// cassandraRoles implements CassandraRoleInterface
+type cassandraRoles struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCassandraRoles returns a CassandraRoles
+func newCassandraRoles(c *CassandraV1Client, namespace string) *cassandraRoles {
+	return &cassandraRoles{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the cassandraRole, and returns the corresponding cassandraRole object, and an error if there is any.
+func (c *cassandraRoles) Get(name string, options meta_v1.GetOptions) (result *v1.CassandraRole, err error) {
+	result = &v1.CassandraRole{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CassandraRoles that match those selectors.
+func (c *cassandraRoles) List(opts meta_v1.ListOptions) (result *v1.CassandraRoleList, err error) {
+	result = &v1.CassandraRoleList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraRoles.
+func (c *cassandraRoles) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Create takes the representation of a cassandraRole and creates it.  Returns the server's representation of the cassandraRole, and an error, if there is any.
+func (c *cassandraRoles) Create(cassandraRole *v1.CassandraRole) (result *v1.CassandraRole, err error) {
+	result = &v1.CassandraRole{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		Body(cassandraRole).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a cassandraRole and updates it. Returns the server's representation of the cassandraRole, and an error, if there is any.
+func (c *cassandraRoles) Update(cassandraRole *v1.CassandraRole) (result *v1.CassandraRole, err error) {
+	result = &v1.CassandraRole{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		Name(cassandraRole.Name).
+		Body(cassandraRole).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *cassandraRoles) UpdateStatus(cassandraRole *v1.CassandraRole) (result *v1.CassandraRole, err error) {
+	result = &v1.CassandraRole{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		Name(cassandraRole.Name).
+		SubResource("status").
+		Body(cassandraRole).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the cassandraRole and deletes it. Returns an error if one occurs.
+func (c *cassandraRoles) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *cassandraRoles) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched cassandraRole.
+func (c *cassandraRoles) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.CassandraRole, err error) {
+	result = &v1.CassandraRole{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("cassandraroles").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -26,10 +26,26 @@ type FakeCassandraV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeCassandraV1) CassandraBackups(namespace string) v1.CassandraBackupInterface {
+	return &FakeCassandraBackups{c, namespace}
+}
+
 func (c *FakeCassandraV1) CassandraClusters(namespace string) v1.CassandraClusterInterface {
 	return &FakeCassandraClusters{c, namespace}
 }
 
+func (c *FakeCassandraV1) CassandraKeyspaces(namespace string) v1.CassandraKeyspaceInterface {
+	return &FakeCassandraKeyspaces{c, namespace}
+}
+
+func (c *FakeCassandraV1) CassandraRestores(namespace string) v1.CassandraRestoreInterface {
+	return &FakeCassandraRestores{c, namespace}
+}
+
+func (c *FakeCassandraV1) CassandraRoles(namespace string) v1.CassandraRoleInterface {
+	return &FakeCassandraRoles{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeCassandraV1) RESTClient() rest.Interface {
@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	cassandra_v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeCassandraBackups implements CassandraBackupInterface
+type FakeCassandraBackups struct {
+	Fake *FakeCassandraV1
+	ns   string
+}
+
+var cassandrabackupsResource = schema.GroupVersionResource{Group: "cassandra", Version: "v1", Resource: "cassandrabackups"}
+
+var cassandrabackupsKind = schema.GroupVersionKind{Group: "cassandra", Version: "v1", Kind: "CassandraBackup"}
+
+// Get takes name of the cassandraBackup, and returns the corresponding cassandraBackup object, and an error if there is any.
+func (c *FakeCassandraBackups) Get(name string, options v1.GetOptions) (result *cassandra_v1.CassandraBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(cassandrabackupsResource, c.ns, name), &cassandra_v1.CassandraBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraBackup), err
+}
+
+// List takes label and field selectors, and returns the list of CassandraBackups that match those selectors.
+func (c *FakeCassandraBackups) List(opts v1.ListOptions) (result *cassandra_v1.CassandraBackupList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(cassandrabackupsResource, cassandrabackupsKind, c.ns, opts), &cassandra_v1.CassandraBackupList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &cassandra_v1.CassandraBackupList{}
+	for _, item := range obj.(*cassandra_v1.CassandraBackupList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraBackups.
+func (c *FakeCassandraBackups) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(cassandrabackupsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a cassandraBackup and creates it.  Returns the server's representation of the cassandraBackup, and an error, if there is any.
+func (c *FakeCassandraBackups) Create(cassandraBackup *cassandra_v1.CassandraBackup) (result *cassandra_v1.CassandraBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(cassandrabackupsResource, c.ns, cassandraBackup), &cassandra_v1.CassandraBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraBackup), err
+}
+
+// Update takes the representation of a cassandraBackup and updates it. Returns the server's representation of the cassandraBackup, and an error, if there is any.
+func (c *FakeCassandraBackups) Update(cassandraBackup *cassandra_v1.CassandraBackup) (result *cassandra_v1.CassandraBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(cassandrabackupsResource, c.ns, cassandraBackup), &cassandra_v1.CassandraBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraBackup), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCassandraBackups) UpdateStatus(cassandraBackup *cassandra_v1.CassandraBackup) (*cassandra_v1.CassandraBackup, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(cassandrabackupsResource, "status", c.ns, cassandraBackup), &cassandra_v1.CassandraBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraBackup), err
+}
+
+// Delete takes name of the cassandraBackup and deletes it. Returns an error if one occurs.
+func (c *FakeCassandraBackups) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(cassandrabackupsResource, c.ns, name), &cassandra_v1.CassandraBackup{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCassandraBackups) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(cassandrabackupsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &cassandra_v1.CassandraBackupList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched cassandraBackup.
+func (c *FakeCassandraBackups) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *cassandra_v1.CassandraBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(cassandrabackupsResource, c.ns, name, data, subresources...), &cassandra_v1.CassandraBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraBackup), err
+}
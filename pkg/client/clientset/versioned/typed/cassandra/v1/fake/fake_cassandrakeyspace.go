@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	cassandra_v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeCassandraKeyspaces implements CassandraKeyspaceInterface
+type FakeCassandraKeyspaces struct {
+	Fake *FakeCassandraV1
+	ns   string
+}
+
+var cassandrakeyspacesResource = schema.GroupVersionResource{Group: "cassandra", Version: "v1", Resource: "cassandrakeyspaces"}
+
+var cassandrakeyspacesKind = schema.GroupVersionKind{Group: "cassandra", Version: "v1", Kind: "CassandraKeyspace"}
+
+// Get takes name of the cassandraKeyspace, and returns the corresponding cassandraKeyspace object, and an error if there is any.
+func (c *FakeCassandraKeyspaces) Get(name string, options v1.GetOptions) (result *cassandra_v1.CassandraKeyspace, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(cassandrakeyspacesResource, c.ns, name), &cassandra_v1.CassandraKeyspace{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraKeyspace), err
+}
+
+// List takes label and field selectors, and returns the list of CassandraKeyspaces that match those selectors.
+func (c *FakeCassandraKeyspaces) List(opts v1.ListOptions) (result *cassandra_v1.CassandraKeyspaceList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(cassandrakeyspacesResource, cassandrakeyspacesKind, c.ns, opts), &cassandra_v1.CassandraKeyspaceList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &cassandra_v1.CassandraKeyspaceList{}
+	for _, item := range obj.(*cassandra_v1.CassandraKeyspaceList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraKeyspaces.
+func (c *FakeCassandraKeyspaces) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(cassandrakeyspacesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a cassandraKeyspace and creates it.  Returns the server's representation of the cassandraKeyspace, and an error, if there is any.
+func (c *FakeCassandraKeyspaces) Create(cassandraKeyspace *cassandra_v1.CassandraKeyspace) (result *cassandra_v1.CassandraKeyspace, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(cassandrakeyspacesResource, c.ns, cassandraKeyspace), &cassandra_v1.CassandraKeyspace{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraKeyspace), err
+}
+
+// Update takes the representation of a cassandraKeyspace and updates it. Returns the server's representation of the cassandraKeyspace, and an error, if there is any.
+func (c *FakeCassandraKeyspaces) Update(cassandraKeyspace *cassandra_v1.CassandraKeyspace) (result *cassandra_v1.CassandraKeyspace, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(cassandrakeyspacesResource, c.ns, cassandraKeyspace), &cassandra_v1.CassandraKeyspace{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraKeyspace), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCassandraKeyspaces) UpdateStatus(cassandraKeyspace *cassandra_v1.CassandraKeyspace) (*cassandra_v1.CassandraKeyspace, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(cassandrakeyspacesResource, "status", c.ns, cassandraKeyspace), &cassandra_v1.CassandraKeyspace{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraKeyspace), err
+}
+
+// Delete takes name of the cassandraKeyspace and deletes it. Returns an error if one occurs.
+func (c *FakeCassandraKeyspaces) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(cassandrakeyspacesResource, c.ns, name), &cassandra_v1.CassandraKeyspace{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCassandraKeyspaces) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(cassandrakeyspacesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &cassandra_v1.CassandraKeyspaceList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched cassandraKeyspace.
+func (c *FakeCassandraKeyspaces) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *cassandra_v1.CassandraKeyspace, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(cassandrakeyspacesResource, c.ns, name, data, subresources...), &cassandra_v1.CassandraKeyspace{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraKeyspace), err
+}
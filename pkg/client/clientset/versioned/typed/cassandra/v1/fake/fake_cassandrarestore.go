@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	cassandra_v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeCassandraRestores implements CassandraRestoreInterface
+type FakeCassandraRestores struct {
+	Fake *FakeCassandraV1
+	ns   string
+}
+
+var cassandrarestoresResource = schema.GroupVersionResource{Group: "cassandra", Version: "v1", Resource: "cassandrarestores"}
+
+var cassandrarestoresKind = schema.GroupVersionKind{Group: "cassandra", Version: "v1", Kind: "CassandraRestore"}
+
+// Get takes name of the cassandraRestore, and returns the corresponding cassandraRestore object, and an error if there is any.
+func (c *FakeCassandraRestores) Get(name string, options v1.GetOptions) (result *cassandra_v1.CassandraRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(cassandrarestoresResource, c.ns, name), &cassandra_v1.CassandraRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRestore), err
+}
+
+// List takes label and field selectors, and returns the list of CassandraRestores that match those selectors.
+func (c *FakeCassandraRestores) List(opts v1.ListOptions) (result *cassandra_v1.CassandraRestoreList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(cassandrarestoresResource, cassandrarestoresKind, c.ns, opts), &cassandra_v1.CassandraRestoreList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &cassandra_v1.CassandraRestoreList{}
+	for _, item := range obj.(*cassandra_v1.CassandraRestoreList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraRestores.
+func (c *FakeCassandraRestores) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(cassandrarestoresResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a cassandraRestore and creates it.  Returns the server's representation of the cassandraRestore, and an error, if there is any.
+func (c *FakeCassandraRestores) Create(cassandraRestore *cassandra_v1.CassandraRestore) (result *cassandra_v1.CassandraRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(cassandrarestoresResource, c.ns, cassandraRestore), &cassandra_v1.CassandraRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRestore), err
+}
+
+// Update takes the representation of a cassandraRestore and updates it. Returns the server's representation of the cassandraRestore, and an error, if there is any.
+func (c *FakeCassandraRestores) Update(cassandraRestore *cassandra_v1.CassandraRestore) (result *cassandra_v1.CassandraRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(cassandrarestoresResource, c.ns, cassandraRestore), &cassandra_v1.CassandraRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRestore), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCassandraRestores) UpdateStatus(cassandraRestore *cassandra_v1.CassandraRestore) (*cassandra_v1.CassandraRestore, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(cassandrarestoresResource, "status", c.ns, cassandraRestore), &cassandra_v1.CassandraRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRestore), err
+}
+
+// Delete takes name of the cassandraRestore and deletes it. Returns an error if one occurs.
+func (c *FakeCassandraRestores) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(cassandrarestoresResource, c.ns, name), &cassandra_v1.CassandraRestore{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCassandraRestores) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(cassandrarestoresResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &cassandra_v1.CassandraRestoreList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched cassandraRestore.
+func (c *FakeCassandraRestores) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *cassandra_v1.CassandraRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(cassandrarestoresResource, c.ns, name, data, subresources...), &cassandra_v1.CassandraRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRestore), err
+}
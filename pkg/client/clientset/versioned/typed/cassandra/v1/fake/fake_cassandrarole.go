@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	cassandra_v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeCassandraRoles implements CassandraRoleInterface
+type FakeCassandraRoles struct {
+	Fake *FakeCassandraV1
+	ns   string
+}
+
+var cassandrarolesResource = schema.GroupVersionResource{Group: "cassandra", Version: "v1", Resource: "cassandraroles"}
+
+var cassandrarolesKind = schema.GroupVersionKind{Group: "cassandra", Version: "v1", Kind: "CassandraRole"}
+
+// Get takes name of the cassandraRole, and returns the corresponding cassandraRole object, and an error if there is any.
+func (c *FakeCassandraRoles) Get(name string, options v1.GetOptions) (result *cassandra_v1.CassandraRole, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(cassandrarolesResource, c.ns, name), &cassandra_v1.CassandraRole{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRole), err
+}
+
+// List takes label and field selectors, and returns the list of CassandraRoles that match those selectors.
+func (c *FakeCassandraRoles) List(opts v1.ListOptions) (result *cassandra_v1.CassandraRoleList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(cassandrarolesResource, cassandrarolesKind, c.ns, opts), &cassandra_v1.CassandraRoleList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &cassandra_v1.CassandraRoleList{}
+	for _, item := range obj.(*cassandra_v1.CassandraRoleList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested cassandraRoles.
+func (c *FakeCassandraRoles) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(cassandrarolesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a cassandraRole and creates it.  Returns the server's representation of the cassandraRole, and an error, if there is any.
+func (c *FakeCassandraRoles) Create(cassandraRole *cassandra_v1.CassandraRole) (result *cassandra_v1.CassandraRole, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(cassandrarolesResource, c.ns, cassandraRole), &cassandra_v1.CassandraRole{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRole), err
+}
+
+// Update takes the representation of a cassandraRole and updates it. Returns the server's representation of the cassandraRole, and an error, if there is any.
+func (c *FakeCassandraRoles) Update(cassandraRole *cassandra_v1.CassandraRole) (result *cassandra_v1.CassandraRole, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(cassandrarolesResource, c.ns, cassandraRole), &cassandra_v1.CassandraRole{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRole), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCassandraRoles) UpdateStatus(cassandraRole *cassandra_v1.CassandraRole) (*cassandra_v1.CassandraRole, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(cassandrarolesResource, "status", c.ns, cassandraRole), &cassandra_v1.CassandraRole{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRole), err
+}
+
+// Delete takes name of the cassandraRole and deletes it. Returns an error if one occurs.
+func (c *FakeCassandraRoles) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(cassandrarolesResource, c.ns, name), &cassandra_v1.CassandraRole{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCassandraRoles) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(cassandrarolesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &cassandra_v1.CassandraRoleList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched cassandraRole.
+func (c *FakeCassandraRoles) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *cassandra_v1.CassandraRole, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(cassandrarolesResource, c.ns, name, data, subresources...), &cassandra_v1.CassandraRole{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cassandra_v1.CassandraRole), err
+}
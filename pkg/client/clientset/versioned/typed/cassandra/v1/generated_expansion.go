@@ -16,4 +16,12 @@ limitations under the License.
 
 package v1
 
+type CassandraBackupExpansion interface{}
+
 type CassandraClusterExpansion interface{}
+
+type CassandraKeyspaceExpansion interface{}
+
+type CassandraRestoreExpansion interface{}
+
+type CassandraRoleExpansion interface{}
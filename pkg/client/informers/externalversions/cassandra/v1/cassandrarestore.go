@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file was automatically generated by informer-gen
+
+package v1
+
+import (
+	cassandra_v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	versioned "github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned"
+	internalinterfaces "github.com/vgkowski/cassandra-operator/pkg/client/informers/externalversions/internalinterfaces"
+	v1 "github.com/vgkowski/cassandra-operator/pkg/client/listers/cassandra/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	time "time"
+)
+
+// CassandraRestoreInformer provides access to a shared informer and lister for
+// CassandraRestores.
+type CassandraRestoreInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.CassandraRestoreLister
+}
+
+type cassandraRestoreInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewCassandraRestoreInformer constructs a new informer for CassandraRestore type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewCassandraRestoreInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredCassandraRestoreInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredCassandraRestoreInformer constructs a new informer for CassandraRestore type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredCassandraRestoreInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CassandraV1().CassandraRestores(namespace).List(options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CassandraV1().CassandraRestores(namespace).Watch(options)
+			},
+		},
+		&cassandra_v1.CassandraRestore{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *cassandraRestoreInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredCassandraRestoreInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *cassandraRestoreInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&cassandra_v1.CassandraRestore{}, f.defaultInformer)
+}
+
+func (f *cassandraRestoreInformer) Lister() v1.CassandraRestoreLister {
+	return v1.NewCassandraRestoreLister(f.Informer().GetIndexer())
+}
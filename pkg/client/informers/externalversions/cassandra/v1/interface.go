@@ -24,8 +24,16 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// CassandraBackups returns a CassandraBackupInformer.
+	CassandraBackups() CassandraBackupInformer
 	// CassandraClusters returns a CassandraClusterInformer.
 	CassandraClusters() CassandraClusterInformer
+	// CassandraKeyspaces returns a CassandraKeyspaceInformer.
+	CassandraKeyspaces() CassandraKeyspaceInformer
+	// CassandraRestores returns a CassandraRestoreInformer.
+	CassandraRestores() CassandraRestoreInformer
+	// CassandraRoles returns a CassandraRoleInformer.
+	CassandraRoles() CassandraRoleInformer
 }
 
 type version struct {
@@ -39,7 +47,27 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// CassandraBackups returns a CassandraBackupInformer.
+func (v *version) CassandraBackups() CassandraBackupInformer {
+	return &cassandraBackupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // CassandraClusters returns a CassandraClusterInformer.
 func (v *version) CassandraClusters() CassandraClusterInformer {
 	return &cassandraClusterInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// CassandraKeyspaces returns a CassandraKeyspaceInformer.
+func (v *version) CassandraKeyspaces() CassandraKeyspaceInformer {
+	return &cassandraKeyspaceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// CassandraRestores returns a CassandraRestoreInformer.
+func (v *version) CassandraRestores() CassandraRestoreInformer {
+	return &cassandraRestoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// CassandraRoles returns a CassandraRoleInformer.
+func (v *version) CassandraRoles() CassandraRoleInformer {
+	return &cassandraRoleInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -52,8 +52,16 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=cassandra, Version=v1
+	case v1.SchemeGroupVersion.WithResource("cassandrabackups"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cassandra().V1().CassandraBackups().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("cassandraclusters"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Cassandra().V1().CassandraClusters().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("cassandrakeyspaces"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cassandra().V1().CassandraKeyspaces().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("cassandrarestores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cassandra().V1().CassandraRestores().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("cassandraroles"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cassandra().V1().CassandraRoles().Informer()}, nil
 
 	}
 
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file was automatically generated by lister-gen
+
+package v1
+
+import (
+	v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CassandraBackupLister helps list CassandraBackups.
+type CassandraBackupLister interface {
+	// List lists all CassandraBackups in the indexer.
+	List(selector labels.Selector) (ret []*v1.CassandraBackup, err error)
+	// CassandraBackups returns an object that can list and get CassandraBackups.
+	CassandraBackups(namespace string) CassandraBackupNamespaceLister
+	CassandraBackupListerExpansion
+}
+
+// cassandraBackupLister implements the CassandraBackupLister interface.
+type cassandraBackupLister struct {
+	indexer cache.Indexer
+}
+
+// NewCassandraBackupLister returns a new CassandraBackupLister.
+func NewCassandraBackupLister(indexer cache.Indexer) CassandraBackupLister {
+	return &cassandraBackupLister{indexer: indexer}
+}
+
+// List lists all CassandraBackups in the indexer.
+func (s *cassandraBackupLister) List(selector labels.Selector) (ret []*v1.CassandraBackup, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CassandraBackup))
+	})
+	return ret, err
+}
+
+// CassandraBackups returns an object that can list and get CassandraBackups.
+func (s *cassandraBackupLister) CassandraBackups(namespace string) CassandraBackupNamespaceLister {
+	return cassandraBackupNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CassandraBackupNamespaceLister helps list and get CassandraBackups.
+type CassandraBackupNamespaceLister interface {
+	// List lists all CassandraBackups in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.CassandraBackup, err error)
+	// Get retrieves the CassandraBackup from the indexer for a given namespace and name.
+	Get(name string) (*v1.CassandraBackup, error)
+	CassandraBackupNamespaceListerExpansion
+}
+
+// cassandraBackupNamespaceLister implements the CassandraBackupNamespaceLister
+// interface.
+type cassandraBackupNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CassandraBackups in the indexer for a given namespace.
+func (s cassandraBackupNamespaceLister) List(selector labels.Selector) (ret []*v1.CassandraBackup, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CassandraBackup))
+	})
+	return ret, err
+}
+
+// Get retrieves the CassandraBackup from the indexer for a given namespace and name.
+func (s cassandraBackupNamespaceLister) Get(name string) (*v1.CassandraBackup, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("cassandrabackup"), name)
+	}
+	return obj.(*v1.CassandraBackup), nil
+}
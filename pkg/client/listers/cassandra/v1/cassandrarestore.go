@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file was automatically generated by lister-gen
+
+package v1
+
+import (
+	v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CassandraRestoreLister helps list CassandraRestores.
+type CassandraRestoreLister interface {
+	// List lists all CassandraRestores in the indexer.
+	List(selector labels.Selector) (ret []*v1.CassandraRestore, err error)
+	// CassandraRestores returns an object that can list and get CassandraRestores.
+	CassandraRestores(namespace string) CassandraRestoreNamespaceLister
+	CassandraRestoreListerExpansion
+}
+
+// cassandraRestoreLister implements the CassandraRestoreLister interface.
+type cassandraRestoreLister struct {
+	indexer cache.Indexer
+}
+
+// NewCassandraRestoreLister returns a new CassandraRestoreLister.
+func NewCassandraRestoreLister(indexer cache.Indexer) CassandraRestoreLister {
+	return &cassandraRestoreLister{indexer: indexer}
+}
+
+// List lists all CassandraRestores in the indexer.
+func (s *cassandraRestoreLister) List(selector labels.Selector) (ret []*v1.CassandraRestore, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CassandraRestore))
+	})
+	return ret, err
+}
+
+// CassandraRestores returns an object that can list and get CassandraRestores.
+func (s *cassandraRestoreLister) CassandraRestores(namespace string) CassandraRestoreNamespaceLister {
+	return cassandraRestoreNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CassandraRestoreNamespaceLister helps list and get CassandraRestores.
+type CassandraRestoreNamespaceLister interface {
+	// List lists all CassandraRestores in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.CassandraRestore, err error)
+	// Get retrieves the CassandraRestore from the indexer for a given namespace and name.
+	Get(name string) (*v1.CassandraRestore, error)
+	CassandraRestoreNamespaceListerExpansion
+}
+
+// cassandraRestoreNamespaceLister implements the CassandraRestoreNamespaceLister
+// interface.
+type cassandraRestoreNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CassandraRestores in the indexer for a given namespace.
+func (s cassandraRestoreNamespaceLister) List(selector labels.Selector) (ret []*v1.CassandraRestore, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CassandraRestore))
+	})
+	return ret, err
+}
+
+// Get retrieves the CassandraRestore from the indexer for a given namespace and name.
+func (s cassandraRestoreNamespaceLister) Get(name string) (*v1.CassandraRestore, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("cassandrarestore"), name)
+	}
+	return obj.(*v1.CassandraRestore), nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file was automatically generated by lister-gen
+
+package v1
+
+import (
+	v1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CassandraRoleLister helps list CassandraRoles.
+type CassandraRoleLister interface {
+	// List lists all CassandraRoles in the indexer.
+	List(selector labels.Selector) (ret []*v1.CassandraRole, err error)
+	// CassandraRoles returns an object that can list and get CassandraRoles.
+	CassandraRoles(namespace string) CassandraRoleNamespaceLister
+	CassandraRoleListerExpansion
+}
+
+// cassandraRoleLister implements the CassandraRoleLister interface.
+type cassandraRoleLister struct {
+	indexer cache.Indexer
+}
+
+// NewCassandraRoleLister returns a new CassandraRoleLister.
+func NewCassandraRoleLister(indexer cache.Indexer) CassandraRoleLister {
+	return &cassandraRoleLister{indexer: indexer}
+}
+
+// List lists all CassandraRoles in the indexer.
+func (s *cassandraRoleLister) List(selector labels.Selector) (ret []*v1.CassandraRole, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CassandraRole))
+	})
+	return ret, err
+}
+
+// CassandraRoles returns an object that can list and get CassandraRoles.
+func (s *cassandraRoleLister) CassandraRoles(namespace string) CassandraRoleNamespaceLister {
+	return cassandraRoleNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CassandraRoleNamespaceLister helps list and get CassandraRoles.
+type CassandraRoleNamespaceLister interface {
+	// List lists all CassandraRoles in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.CassandraRole, err error)
+	// Get retrieves the CassandraRole from the indexer for a given namespace and name.
+	Get(name string) (*v1.CassandraRole, error)
+	CassandraRoleNamespaceListerExpansion
+}
+
+// cassandraRoleNamespaceLister implements the CassandraRoleNamespaceLister
+// interface.
+type cassandraRoleNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CassandraRoles in the indexer for a given namespace.
+func (s cassandraRoleNamespaceLister) List(selector labels.Selector) (ret []*v1.CassandraRole, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CassandraRole))
+	})
+	return ret, err
+}
+
+// Get retrieves the CassandraRole from the indexer for a given namespace and name.
+func (s cassandraRoleNamespaceLister) Get(name string) (*v1.CassandraRole, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("cassandrarole"), name)
+	}
+	return obj.(*v1.CassandraRole), nil
+}
@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1
 
+// CassandraBackupListerExpansion allows custom methods to be added to
+// CassandraBackupLister.
+type CassandraBackupListerExpansion interface{}
+
+// CassandraBackupNamespaceListerExpansion allows custom methods to be added to
+// CassandraBackupNamespaceLister.
+type CassandraBackupNamespaceListerExpansion interface{}
+
 // CassandraClusterListerExpansion allows custom methods to be added to
 // CassandraClusterLister.
 type CassandraClusterListerExpansion interface{}
@@ -25,3 +33,27 @@ type CassandraClusterListerExpansion interface{}
 // CassandraClusterNamespaceListerExpansion allows custom methods to be added to
 // CassandraClusterNamespaceLister.
 type CassandraClusterNamespaceListerExpansion interface{}
+
+// CassandraKeyspaceListerExpansion allows custom methods to be added to
+// CassandraKeyspaceLister.
+type CassandraKeyspaceListerExpansion interface{}
+
+// CassandraKeyspaceNamespaceListerExpansion allows custom methods to be added to
+// CassandraKeyspaceNamespaceLister.
+type CassandraKeyspaceNamespaceListerExpansion interface{}
+
+// CassandraRestoreListerExpansion allows custom methods to be added to
+// CassandraRestoreLister.
+type CassandraRestoreListerExpansion interface{}
+
+// CassandraRestoreNamespaceListerExpansion allows custom methods to be added to
+// CassandraRestoreNamespaceLister.
+type CassandraRestoreNamespaceListerExpansion interface{}
+
+// CassandraRoleListerExpansion allows custom methods to be added to
+// CassandraRoleLister.
+type CassandraRoleListerExpansion interface{}
+
+// CassandraRoleNamespaceListerExpansion allows custom methods to be added to
+// CassandraRoleNamespaceLister.
+type CassandraRoleNamespaceListerExpansion interface{}
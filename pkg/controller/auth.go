@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	// keys of the secret of the superuser
+	superuserUsernameKey = "username"
+	superuserPasswordKey = "password"
+	// defaultSuperuser is the role created by the operator when the secret of the superuser doesn't exist
+	defaultSuperuser = "admin"
+	// cassandraRole is the superuser created by Cassandra with the cassandra password
+	cassandraRole = "cassandra"
+	// repairTriggerReplication is recorded when the replication factor of a keyspace is increased
+	repairTriggerReplication = "ReplicationChange"
+)
+
+// superuserSecretName returns the name of the secret holding the credentials of the superuser of the cluster
+func superuserSecretName(ccName string) string {
+	return ccName + "-superuser"
+}
+
+// passwordAuthentication returns true if the clients must log in
+func passwordAuthentication(cc *cassandrav1.CassandraCluster) bool {
+	return strings.HasSuffix(cc.Spec.CassandraSpec.Config.Authenticator, cassandrav1.PasswordAuthenticator)
+}
+
+// reconcileAuth replaces the default cassandra superuser by the superuser of the secret and keeps system_auth
+// replicated on all the nodes
+func (c *Controller) reconcileAuth(cc *cassandrav1.CassandraCluster) error {
+	if !passwordAuthentication(cc) {
+		return nil
+	}
+	// the secret is created before the nodes so the operator commands run in them can log in
+	username, password, err := c.getSuperuser(cc)
+	if err != nil {
+		return err
+	}
+	if cc.Status.Phase != cassandrav1.ClusterPhaseRunning || !clusterStable(cc) {
+		return nil
+	}
+	pod, err := c.readyPod(cc)
+	if err != nil {
+		return err
+	}
+
+	if !cc.Status.AuthBootstrapped {
+		// the role may already exist if the operator restarted after it was created
+		create := fmt.Sprintf("CREATE ROLE IF NOT EXISTS '%s' WITH PASSWORD = '%s' AND SUPERUSER = true AND LOGIN = true", username, password)
+		_, stderr, err := c.ExecCmd(pod.Name, cqlshCmd("-u", cassandraRole, "-p", cassandraRole, pod.Status.PodIP, "-e", create))
+		if err != nil && !strings.Contains(stderr, "AuthenticationFailed") {
+			// the default superuser is created by Cassandra a few seconds after the start of the cluster
+			return fmt.Errorf("could not create the superuser %s: %v %s", username, err, stderr)
+		}
+		if username != cassandraRole {
+			newPassword := GenerateToken(cc.Name, bcrypt.DefaultCost)
+			if newPassword == "" {
+				return fmt.Errorf("could not generate a password")
+			}
+			disable := fmt.Sprintf("ALTER ROLE %s WITH PASSWORD = '%s' AND SUPERUSER = false AND LOGIN = false", cassandraRole, newPassword)
+			if _, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", disable)); err != nil {
+				return fmt.Errorf("could not disable the default superuser: %v %s", err, stderr)
+			}
+		}
+		cc.Status.AuthBootstrapped = true
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "AuthBootstrapped", "Superuser %s of secret %s created, the default superuser is disabled", username, superuserSecretName(cc.Name))
+		if err := c.persistStatus(cc); err != nil {
+			return err
+		}
+	}
+	return c.reconcileSystemAuth(cc, pod)
+}
+
+// reconcileSystemAuth sets the replication factor of system_auth to the number of nodes of each datacenter
+func (c *Controller) reconcileSystemAuth(cc *cassandrav1.CassandraCluster, pod *corev1.Pod) error {
+	replication, err := c.getReplication(cc)
+	if err != nil {
+		return err
+	}
+	current := replication["system_auth"]
+	nodes := datacenterNodes(cc)
+	delete(nodes, "")
+	changed := len(current) != len(nodes)
+	for dc, n := range nodes {
+		if current[dc] != n {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	alter := "ALTER KEYSPACE system_auth WITH replication = " + replicationCQL(cassandrav1.NetworkTopologyStrategy, nodes)
+	glog.Infof("%s in CassandraCluster %s", alter, cc.Name)
+	if _, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", alter)); err != nil {
+		return fmt.Errorf("could not set the replication of system_auth: %v %s", err, stderr)
+	}
+	// the new replicas get the roles from the repair
+	if replicationIncreased(current, nodes) {
+		requestKeyspaceRepair(cc, repairTriggerReplication, "system_auth")
+	}
+	return nil
+}
+
+// getSuperuser returns the credentials of the superuser, the secret is generated unless it was provided
+func (c *Controller) getSuperuser(cc *cassandrav1.CassandraCluster) (string, string, error) {
+	client := c.kubeClientset.CoreV1().Secrets(c.namespace)
+	secret, err := client.Get(superuserSecretName(cc.Name), metav1.GetOptions{})
+	if err == nil {
+		username, password := string(secret.Data[superuserUsernameKey]), string(secret.Data[superuserPasswordKey])
+		if username == "" || password == "" || strings.ContainsAny(username+password, "'\"") {
+			return "", "", fmt.Errorf("secret %s must have a %s and a %s without quotes", secret.Name, superuserUsernameKey, superuserPasswordKey)
+		}
+		return username, password, nil
+	}
+	if !errors.IsNotFound(err) {
+		return "", "", err
+	}
+	password := GenerateToken(cc.Name, bcrypt.DefaultCost)
+	if password == "" {
+		return "", "", fmt.Errorf("could not generate the password of the superuser")
+	}
+	_, err = client.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: superuserSecretName(cc.Name),
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role":             "superuser",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Data: map[string][]byte{
+			superuserUsernameKey: []byte(defaultSuperuser),
+			superuserPasswordKey: []byte(password),
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return defaultSuperuser, password, nil
+}
+
+// readyPod returns a ready node of the cluster
+func (c *Controller) readyPod(cc *cassandrav1.CassandraCluster) (*corev1.Pod, error) {
+	pods, err := c.podLister.Pods(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if podState(pod) == cassandrav1.NodeStateReady {
+			return pod, nil
+		}
+	}
+	return nil, fmt.Errorf("no ready node in CassandraCluster %s", cc.Name)
+}
+
+// authEnv returns the credentials used by cqlsh in the nodes when the clients must log in
+func authEnv(cc *cassandrav1.CassandraCluster) []corev1.EnvVar {
+	if !passwordAuthentication(cc) {
+		return nil
+	}
+	return []corev1.EnvVar{
+		superuserEnv(cc, "CQLSH_USERNAME", superuserUsernameKey),
+		superuserEnv(cc, "CQLSH_PASSWORD", superuserPasswordKey),
+	}
+}
+
+func superuserEnv(cc *cassandrav1.CassandraCluster, name string, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: superuserSecretName(cc.Name)},
+				Key:                  key,
+				Optional:             func(b bool) *bool { return &b }(true),
+			},
+		},
+	}
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+// newRunningCluster returns a running cluster of 3 ready nodes with password authentication
+func newRunningCluster(name string) *cassandrav1.CassandraCluster {
+	cc := newCassandraCluster(name)
+	cc.Spec.CassandraSpec.Config.Authenticator = cassandrav1.PasswordAuthenticator
+	cc.Status.Phase = cassandrav1.ClusterPhaseRunning
+	cc.Status.DesiredNodes, cc.Status.ReadyNodes = 3, 3
+	for _, node := range []string{"-dc1-rack1-0", "-dc1-rack1-1", "-dc1-rack1-2"} {
+		cc.Status.Nodes = append(cc.Status.Nodes, cassandrav1.CassandraNodeStatus{Name: name + node})
+	}
+	return cc
+}
+
+// replicationOutput returns the cqlsh output of the replication of the keyspaces
+func replicationOutput(replication map[string]string) string {
+	out := " keyspace_name | replication\n---------------+-------------\n"
+	for keyspace, options := range replication {
+		out += " " + keyspace + " | " + options + "\n"
+	}
+	return out
+}
+
+func TestReconcileAuth(t *testing.T) {
+	cc := newRunningCluster("test")
+	pod := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	pod.Status.PodIP = "10.0.0.1"
+	f := newFixture(t, cc, pod)
+	systemAuth := "{'class': 'org.apache.cassandra.locator.SimpleStrategy', 'replication_factor': '1'}"
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		if strings.Contains(strings.Join(cmd, " "), "system_schema.keyspaces") {
+			return replicationOutput(map[string]string{"system_auth": systemAuth}), "", nil
+		}
+		return "", "", nil
+	}
+
+	if err := f.controller.reconcileAuth(cc); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := f.kubeClient.CoreV1().Secrets(testNamespace).Get(superuserSecretName("test"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := string(secret.Data[superuserPasswordKey])
+	if string(secret.Data[superuserUsernameKey]) != defaultSuperuser || password == "" || !metav1.IsControlledBy(secret, cc) {
+		t.Errorf("got %+v, want the generated superuser", secret)
+	}
+	want := []string{
+		"-u cassandra -p cassandra 10.0.0.1 -e CREATE ROLE IF NOT EXISTS 'admin' WITH PASSWORD = '" + password + "'",
+		"ALTER ROLE cassandra WITH PASSWORD",
+		"SELECT keyspace_name, replication FROM system_schema.keyspaces",
+		"ALTER KEYSPACE system_auth WITH replication = {'class': 'NetworkTopologyStrategy', 'dc1': 3}",
+	}
+	if len(f.commands) != len(want) {
+		t.Fatalf("got commands %v, want %d commands", f.commands, len(want))
+	}
+	for i, command := range f.commands {
+		if !strings.HasPrefix(command, pod.Name+": ") || !strings.Contains(command, want[i]) {
+			t.Errorf("got command %q, want %q", command, want[i])
+		}
+	}
+	if !cc.Status.AuthBootstrapped || cc.Status.PendingRepair != repairTriggerReplication || !containsString(cc.Status.PendingRepairKeyspaces, "system_auth") {
+		t.Errorf("got %+v, want the superuser bootstrapped and system_auth repaired", cc.Status)
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Normal AuthBootstrapped") {
+		t.Errorf("got events %v, want an AuthBootstrapped event", events)
+	}
+
+	// system_auth is replicated on all the nodes
+	f.commands = nil
+	systemAuth = "{'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'dc1': '3'}"
+	if err := f.controller.reconcileAuth(cc); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.commands) != 1 {
+		t.Errorf("got commands %v, want the replication checked only", f.commands)
+	}
+}
+
+func TestReconcileAuthLegacy(t *testing.T) {
+	cc := newRunningCluster("test")
+	cc.Status.AuthBootstrapped = true
+	pod := newNodePod(cc, "test-dc1-rack1-0", "cassandra:2.2", "", true)
+	pod.Status.PodIP = "10.0.0.1"
+	f := newFixture(t, cc, pod)
+	// Cassandra 2.x has no system_schema keyspace
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		if strings.Contains(strings.Join(cmd, " "), "system.schema_keyspaces") {
+			return " keyspace_name | strategy_class | strategy_options\n" +
+				"---------------+----------------+------------------\n" +
+				" system_auth | org.apache.cassandra.locator.SimpleStrategy | {\"replication_factor\":\"1\"}\n" +
+				" system | org.apache.cassandra.locator.LocalStrategy | {}\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	if err := f.controller.reconcileAuth(cc); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT keyspace_name, strategy_class, strategy_options FROM system.schema_keyspaces",
+		"ALTER KEYSPACE system_auth WITH replication = {'class': 'NetworkTopologyStrategy', 'dc1': 3}",
+	}
+	if len(f.commands) != len(want) {
+		t.Fatalf("got commands %v, want %d commands", f.commands, len(want))
+	}
+	for i, command := range f.commands {
+		if !strings.Contains(command, want[i]) {
+			t.Errorf("got command %q, want %q", command, want[i])
+		}
+	}
+}
+
+func TestReconcileAuthWaitsForRunningCluster(t *testing.T) {
+	cc := newRunningCluster("test")
+	cc.Status.ReadyNodes = 2
+	f := newFixture(t, cc)
+	if err := f.controller.reconcileAuth(cc); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.commands) != 0 || cc.Status.AuthBootstrapped {
+		t.Errorf("got commands %v, want the bootstrap waiting for the nodes", f.commands)
+	}
+	// the secret is created before the nodes so the commands run in them can log in
+	if _, err := f.kubeClient.CoreV1().Secrets(testNamespace).Get(superuserSecretName("test"), metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+}
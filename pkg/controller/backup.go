@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/backup"
+)
+
+const (
+	// cassandraDataDir is the data directory of the nodes, holding one directory per keyspace
+	cassandraDataDir = "/cassandra_data/data"
+	// keys of the secret holding the credentials of the object storage
+	backupAccessKeyID     = "accessKeyId"
+	backupSecretAccessKey = "secretAccessKey"
+)
+
+// syncBackup drives a CassandraBackup through its phases: a snapshot is taken on every node of the cluster,
+// then the snapshot files are uploaded one node at a time and a manifest describing the backup is written.
+// Every step is recorded in the status so the backup is resumed after a restart of the operator
+func (c *Controller) syncBackup(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+	b, err := c.cassandraBackupsLister.CassandraBackups(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if b.Status.Phase == cassandrav1.BackupCompleted || b.Status.Phase == cassandrav1.BackupFailed {
+		return nil
+	}
+	b = b.DeepCopy()
+
+	switch b.Status.Phase {
+	case "":
+		return c.startBackup(b)
+	case cassandrav1.BackupSnapshotting:
+		return c.snapshotBackup(b)
+	case cassandrav1.BackupUploading:
+		return c.uploadBackup(b)
+	}
+	return nil
+}
+
+// startBackup lists the nodes to backup. The backup waits until the cluster is stable
+func (c *Controller) startBackup(b *cassandrav1.CassandraBackup) error {
+	cc, err := c.CassandraClustersLister.CassandraClusters(b.Namespace).Get(b.Spec.Cluster)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.failBackup(b, fmt.Errorf("CassandraCluster %s not found", b.Spec.Cluster))
+		}
+		return err
+	}
+	if !clusterStable(cc) {
+		return fmt.Errorf("CassandraCluster %s is not stable, waiting to start the backup %s", cc.Name, b.Name)
+	}
+	if _, err := c.objectStore(b.Namespace, b.Spec.Storage); err != nil {
+		return c.failBackup(b, err)
+	}
+
+	b.Status.Phase = cassandrav1.BackupSnapshotting
+	b.Status.SnapshotName = "backup-" + b.Name
+	b.Status.StartTime = now()
+	b.Status.Manifest = backup.ManifestKey(cc.Name, b.Name)
+	b.Status.Nodes = nil
+	for _, node := range cc.Status.Nodes {
+		b.Status.Nodes = append(b.Status.Nodes, cassandrav1.BackupNodeStatus{
+			Pod:   node.Name,
+			Phase: cassandrav1.BackupNodePending,
+		})
+	}
+	c.recorder.Eventf(b, corev1.EventTypeNormal, "BackupStarted", "Backing up %d nodes of CassandraCluster %s", len(b.Status.Nodes), cc.Name)
+	return c.persistBackupStatus(b)
+}
+
+// snapshotBackup takes the snapshot on all the nodes. Snapshots are cheap hard links so they are taken
+// one after the other in the same sync to get a consistent point in time across the cluster
+func (c *Controller) snapshotBackup(b *cassandrav1.CassandraBackup) error {
+	for i := range b.Status.Nodes {
+		node := &b.Status.Nodes[i]
+		if node.Phase != cassandrav1.BackupNodePending {
+			continue
+		}
+		client, err := c.adminClient(node.Pod)
+		if err != nil {
+			return err
+		}
+		if err := client.Snapshot(b.Status.SnapshotName, b.Spec.Keyspaces...); err != nil {
+			node.Phase = cassandrav1.BackupNodeFailed
+			node.Message = err.Error()
+			return c.failBackup(b, fmt.Errorf("could not snapshot node %s: %v", node.Pod, err))
+		}
+		node.Phase = cassandrav1.BackupNodeSnapshotted
+	}
+	b.Status.Phase = cassandrav1.BackupUploading
+	c.recorder.Eventf(b, corev1.EventTypeNormal, "Snapshotted", "Snapshot %s taken on all the nodes", b.Status.SnapshotName)
+	return c.persistBackupStatus(b)
+}
+
+// uploadBackup uploads the snapshot of the next node, the status update requeues the backup for the following one.
+// When all the nodes are uploaded, the backup manifest is written and the snapshots are cleared
+func (c *Controller) uploadBackup(b *cassandrav1.CassandraBackup) error {
+	store, err := c.objectStore(b.Namespace, b.Spec.Storage)
+	if err != nil {
+		return err
+	}
+	for i := range b.Status.Nodes {
+		node := &b.Status.Nodes[i]
+		if node.Phase != cassandrav1.BackupNodeSnapshotted {
+			continue
+		}
+		nodeManifest, err := c.uploadNode(b, store, node)
+		if err != nil {
+			// the upload is retried by the rate limited queue, the objects already uploaded are overwritten
+			return fmt.Errorf("could not upload the snapshot of node %s: %v", node.Pod, err)
+		}
+		if err := backup.WriteJSON(store, backup.NodeManifestKey(b.Spec.Cluster, b.Name, node.Pod), nodeManifest); err != nil {
+			return err
+		}
+		node.Phase = cassandrav1.BackupNodeUploaded
+		node.Message = ""
+		glog.Infof("uploaded %d files (%d bytes) of node %s for backup %s", node.Files, node.Bytes, node.Pod, b.Name)
+		return c.persistBackupStatus(b)
+	}
+
+	manifest := backup.Manifest{
+		Cluster:  b.Spec.Cluster,
+		Backup:   b.Name,
+		Snapshot: b.Status.SnapshotName,
+		Time:     b.Status.StartTime.Time,
+	}
+	for _, node := range b.Status.Nodes {
+		var nodeManifest backup.NodeManifest
+		if err := backup.ReadJSON(store, backup.NodeManifestKey(b.Spec.Cluster, b.Name, node.Pod), &nodeManifest); err != nil {
+			return fmt.Errorf("could not read the manifest of node %s: %v", node.Pod, err)
+		}
+		manifest.Nodes = append(manifest.Nodes, nodeManifest)
+	}
+	// the schema is needed to recreate the tables before restoring their files
+	schema, err := c.describeSchema(b.Namespace, b.Status.Nodes[0].Pod)
+	if err != nil {
+		return err
+	}
+	manifest.Schema = backup.SchemaKey(b.Spec.Cluster, b.Name)
+	if err := store.Put(manifest.Schema, strings.NewReader(schema), int64(len(schema))); err != nil {
+		return err
+	}
+	if err := backup.WriteJSON(store, b.Status.Manifest, manifest); err != nil {
+		return err
+	}
+	c.clearSnapshots(b)
+	b.Status.Phase = cassandrav1.BackupCompleted
+	b.Status.CompletionTime = now()
+	c.recorder.Eventf(b, corev1.EventTypeNormal, "BackupCompleted", "Backup stored in %s/%s", b.Spec.Storage.Bucket, b.Status.Manifest)
+	return c.persistBackupStatus(b)
+}
+
+// uploadNode streams the snapshot files of the node to the object store and returns the manifest of the node
+func (c *Controller) uploadNode(b *cassandrav1.CassandraBackup, store backup.ObjectStore, node *cassandrav1.BackupNodeStatus) (*backup.NodeManifest, error) {
+	pod, err := c.podLister.Pods(b.Namespace).Get(node.Pod)
+	if err != nil {
+		return nil, err
+	}
+	nodeManifest := &backup.NodeManifest{
+		Pod:  node.Pod,
+		DC:   pod.Labels["cassandraDC"],
+		Rack: pod.Labels["cassandraRack"],
+	}
+	client, err := c.adminClient(node.Pod)
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.Info()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the tokens: %v", err)
+	}
+	nodeManifest.HostID, nodeManifest.Tokens = info.HostID, info.Tokens
+
+	stdout, stderr, err := c.ExecCmd(node.Pod, []string{"find", cassandraDataDir, "-path", "*/snapshots/" + b.Status.SnapshotName + "/*", "-type", "f", "-printf", "%s %p\n"})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the snapshot files: %v %s", err, stderr)
+	}
+	tables := map[string]*backup.TableManifest{}
+	node.Files, node.Bytes = 0, 0
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		// the files are in <data dir>/<keyspace>/<table>-<id>/snapshots/<snapshot>/<file>
+		parts := strings.Split(strings.TrimPrefix(fields[1], cassandraDataDir+"/"), "/")
+		if len(parts) != 5 {
+			continue
+		}
+		keyspace, directory, file := parts[0], parts[1], parts[4]
+		table, ok := tables[keyspace+"/"+directory]
+		if !ok {
+			table = &backup.TableManifest{Keyspace: keyspace, Table: tableName(directory), Directory: directory}
+			tables[keyspace+"/"+directory] = table
+		}
+		key := backup.FileKey(b.Spec.Cluster, b.Name, node.Pod, keyspace, directory, file)
+		if err := c.uploadFile(store, node.Pod, fields[1], key, size); err != nil {
+			return nil, err
+		}
+		table.Files = append(table.Files, backup.FileManifest{Name: file, Key: key, Size: size})
+		node.Files++
+		node.Bytes += size
+	}
+	for _, table := range tables {
+		nodeManifest.Tables = append(nodeManifest.Tables, *table)
+	}
+	sort.Slice(nodeManifest.Tables, func(i, j int) bool {
+		return path.Join(nodeManifest.Tables[i].Keyspace, nodeManifest.Tables[i].Directory) <
+			path.Join(nodeManifest.Tables[j].Keyspace, nodeManifest.Tables[j].Directory)
+	})
+	return nodeManifest, nil
+}
+
+// uploadFile streams a file of the pod to the object store without buffering it in the operator
+func (c *Controller) uploadFile(store backup.ObjectStore, pod string, file string, key string, size int64) error {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		stderr, err := c.ExecCmdStream(pod, []string{"cat", file}, writer)
+		if err != nil {
+			err = fmt.Errorf("could not read %s: %v %s", file, err, stderr)
+		}
+		writer.CloseWithError(err)
+		done <- err
+	}()
+	err := store.Put(key, reader, size)
+	// unblock the exec if the upload stopped before the end of the file
+	reader.Close()
+	// a failed upload makes the exec fail on the closed pipe, the first error is returned
+	execErr := <-done
+	if err != nil {
+		return err
+	}
+	return execErr
+}
+
+// describeSchema returns the CQL statements creating the keyspaces and tables of the cluster
+func (c *Controller) describeSchema(namespace string, podName string) (string, error) {
+	pod, err := c.podLister.Pods(namespace).Get(podName)
+	if err != nil {
+		return "", err
+	}
+	stdout, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", "DESC SCHEMA"))
+	if err != nil {
+		return "", fmt.Errorf("could not describe the schema on %s: %v %s", pod.Name, err, stderr)
+	}
+	return stdout, nil
+}
+
+// failBackup marks the backup as failed and removes the snapshots taken so far
+func (c *Controller) failBackup(b *cassandrav1.CassandraBackup, err error) error {
+	glog.Errorf("backup %s failed: %v", b.Name, err)
+	c.clearSnapshots(b)
+	b.Status.Phase = cassandrav1.BackupFailed
+	b.Status.Message = err.Error()
+	b.Status.CompletionTime = now()
+	c.recorder.Event(b, corev1.EventTypeWarning, "BackupFailed", err.Error())
+	return c.persistBackupStatus(b)
+}
+
+// clearSnapshots removes the snapshot of the backup from the nodes, errors are only logged
+// as a leftover snapshot doesn't prevent the cluster from working
+func (c *Controller) clearSnapshots(b *cassandrav1.CassandraBackup) {
+	if b.Status.SnapshotName == "" {
+		return
+	}
+	for _, node := range b.Status.Nodes {
+		if node.Phase == cassandrav1.BackupNodePending {
+			continue
+		}
+		client, err := c.adminClient(node.Pod)
+		if err == nil {
+			err = client.ClearSnapshot(b.Status.SnapshotName)
+		}
+		if err != nil {
+			glog.Warningf("could not clear the snapshot %s of node %s: %v", b.Status.SnapshotName, node.Pod, err)
+		}
+	}
+}
+
+// newObjectStore returns the object store holding the backups with the credentials of its secret
+func (c *Controller) newObjectStore(namespace string, storage cassandrav1.BackupStorage) (backup.ObjectStore, error) {
+	secret, err := c.kubeClientset.CoreV1().Secrets(namespace).Get(storage.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the secret %s of the object storage: %v", storage.SecretName, err)
+	}
+	return backup.NewS3Store(storage.Endpoint, string(secret.Data[backupAccessKeyID]), string(secret.Data[backupSecretAccessKey]),
+		storage.Region, !storage.Insecure, storage.Bucket, storage.Prefix)
+}
+
+// persistBackupStatus writes the status of the backup
+func (c *Controller) persistBackupStatus(b *cassandrav1.CassandraBackup) error {
+	_, err := c.cassandraClusterClientset.CassandraV1().CassandraBackups(b.Namespace).UpdateStatus(b)
+	return err
+}
+
+// tableName returns the name of a table from its directory name <table>-<id>
+func tableName(directory string) string {
+	if i := strings.LastIndex(directory, "-"); i > 0 {
+		return directory[:i]
+	}
+	return directory
+}
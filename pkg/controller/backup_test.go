@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/backup"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+const testSnapshotDir = cassandraDataDir + "/orders/items-1234/snapshots/backup-daily/"
+
+func newCassandraBackup(name string, cluster string) *cassandrav1.CassandraBackup {
+	return &cassandrav1.CassandraBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec: cassandrav1.CassandraBackupSpec{
+			Cluster:   cluster,
+			Keyspaces: []string{"orders"},
+			Storage:   cassandrav1.BackupStorage{Bucket: "backups", SecretName: "s3"},
+		},
+	}
+}
+
+// newBackupFixture returns the fixture of a running cluster whose nodes have a snapshot file of 12 bytes and one of 4
+func newBackupFixture(t *testing.T, b *cassandrav1.CassandraBackup) *fixture {
+	cc := newRunningCluster("test")
+	objects := []runtime.Object{b}
+	for _, node := range cc.Status.Nodes {
+		pod := newNodePod(cc, node.Name, "cassandra:3.11", "", true)
+		pod.Labels["cassandraDC"], pod.Labels["cassandraRack"] = "dc1", "rack1"
+		objects = append(objects, pod)
+	}
+	f := newFixture(t, cc, objects...)
+	for i, node := range cc.Status.Nodes {
+		f.node(node.Name).HostID = "host" + strconv.Itoa(i)
+		f.node(node.Name).Tokens["-10"+strconv.Itoa(i)] = node.Name
+	}
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		switch cmd[0] {
+		case "find":
+			return "12 " + testSnapshotDir + "mc-1-big-Data.db\n4 " + testSnapshotDir + "mc-1-big-Index.db\n", "", nil
+		case "cat":
+			if strings.HasSuffix(cmd[1], "Data.db") {
+				return "0123456789ab", "", nil
+			}
+			return "abcd", "", nil
+		}
+		return "CREATE KEYSPACE orders WITH replication = {'class': 'SimpleStrategy', 'replication_factor': '3'};", "", nil
+	}
+	return f
+}
+
+func TestBackup(t *testing.T) {
+	b := newCassandraBackup("daily", "test")
+	f := newBackupFixture(t, b)
+
+	if err := f.controller.startBackup(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Status.Phase != cassandrav1.BackupSnapshotting || b.Status.SnapshotName != "backup-daily" || len(b.Status.Nodes) != 3 {
+		t.Fatalf("got %+v, want the snapshot of the 3 nodes started", b.Status)
+	}
+
+	if err := f.controller.snapshotBackup(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range b.Status.Nodes {
+		if node.Phase != cassandrav1.BackupNodeSnapshotted || len(f.node(node.Pod).Snapshots["backup-daily"]) != 1 {
+			t.Errorf("got %+v, want the keyspace snapshotted", node)
+		}
+	}
+	if b.Status.Phase != cassandrav1.BackupUploading {
+		t.Fatalf("got phase %s, want %s", b.Status.Phase, cassandrav1.BackupUploading)
+	}
+
+	// one node is uploaded per sync
+	for i := range b.Status.Nodes {
+		if err := f.controller.uploadBackup(b); err != nil {
+			t.Fatal(err)
+		}
+		node := b.Status.Nodes[i]
+		if node.Phase != cassandrav1.BackupNodeUploaded || node.Files != 2 || node.Bytes != 16 {
+			t.Errorf("got %+v, want the 2 files of the node uploaded", node)
+		}
+		key := backup.FileKey("test", "daily", node.Pod, "orders", "items-1234", "mc-1-big-Data.db")
+		if data := string(f.store.Objects[key]); data != "0123456789ab" {
+			t.Errorf("got %q in %s, want the snapshot file", data, key)
+		}
+	}
+	if b.Status.Phase != cassandrav1.BackupUploading {
+		t.Fatalf("got phase %s before the manifest is written", b.Status.Phase)
+	}
+
+	if err := f.controller.uploadBackup(b); err != nil {
+		t.Fatal(err)
+	}
+	var manifest backup.Manifest
+	if err := backup.ReadJSON(f.store, b.Status.Manifest, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Nodes) != 3 || manifest.Snapshot != "backup-daily" || !strings.HasPrefix(string(f.store.Objects[manifest.Schema]), "CREATE KEYSPACE orders") {
+		t.Fatalf("got %+v, want the manifest of the 3 nodes with the schema", manifest)
+	}
+	node := manifest.Nodes[1]
+	if node.HostID != "host1" || len(node.Tokens) != 1 || node.Tokens[0] != "-101" || node.DC != "dc1" || node.Rack != "rack1" {
+		t.Errorf("got %+v, want the identity of the node", node)
+	}
+	if len(node.Tables) != 1 || node.Tables[0].Table != "items" || len(node.Tables[0].Files) != 2 {
+		t.Errorf("got tables %+v, want the files of orders.items", node.Tables)
+	}
+	if b.Status.Phase != cassandrav1.BackupCompleted || b.Status.CompletionTime == nil {
+		t.Errorf("got %+v, want the backup completed", b.Status)
+	}
+	for _, node := range b.Status.Nodes {
+		if count := countCalls(f.node(node.Pod), "clearsnapshot backup-daily"); count != 1 || len(f.node(node.Pod).Snapshots) != 0 {
+			t.Errorf("got %d clearsnapshot on %s, want the snapshot cleared", count, node.Pod)
+		}
+	}
+	stored, err := f.client.CassandraV1().CassandraBackups(testNamespace).Get("daily", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.Phase != cassandrav1.BackupCompleted {
+		t.Errorf("got phase %s, want the status persisted", stored.Status.Phase)
+	}
+}
+
+func TestBackupSnapshotFailure(t *testing.T) {
+	b := newCassandraBackup("daily", "test")
+	f := newBackupFixture(t, b)
+	if err := f.controller.startBackup(b); err != nil {
+		t.Fatal(err)
+	}
+	f.node(b.Status.Nodes[1].Pod).Err = &admin.Error{Operation: "snapshot", Message: "disk full"}
+
+	if err := f.controller.snapshotBackup(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Status.Phase != cassandrav1.BackupFailed || !strings.Contains(b.Status.Message, "disk full") {
+		t.Fatalf("got %+v, want the backup failed", b.Status)
+	}
+	if node := b.Status.Nodes[1]; node.Phase != cassandrav1.BackupNodeFailed || node.Message == "" {
+		t.Errorf("got %+v, want the node failed", node)
+	}
+	// the snapshots taken are cleared, the nodes not snapshotted yet are left alone
+	if len(f.node(b.Status.Nodes[0].Pod).Snapshots) != 0 || countCalls(f.node(b.Status.Nodes[0].Pod), "clearsnapshot backup-daily") != 1 {
+		t.Error("the snapshot of the first node isn't cleared")
+	}
+	if calls := f.node(b.Status.Nodes[2].Pod).Calls; len(calls) != 0 {
+		t.Errorf("got calls %v on the node not snapshotted", calls)
+	}
+	if events := f.events(); len(events) != 2 || !strings.HasPrefix(events[1], "Warning BackupFailed") {
+		t.Errorf("got events %v, want a BackupFailed event", events)
+	}
+}
+
+func TestBackupUploadFailure(t *testing.T) {
+	b := newCassandraBackup("daily", "test")
+	f := newBackupFixture(t, b)
+	if err := f.controller.startBackup(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.controller.snapshotBackup(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// the upload error isn't hidden by the copy of the file failing on the closed pipe
+	f.store.Err = errors.New("access denied")
+	if err := f.controller.uploadBackup(b); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("got %v, want the error of the object store", err)
+	}
+	f.store.Err = nil
+	cqlsh := f.cqlsh
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		if cmd[0] == "cat" {
+			return "", "No such file or directory", errors.New("command terminated with exit code 1")
+		}
+		return cqlsh(podName, cmd)
+	}
+	if err := f.controller.uploadBackup(b); err == nil || !strings.Contains(err.Error(), "No such file or directory") {
+		t.Errorf("got %v, want the error of the copy", err)
+	}
+	// the upload is retried
+	if b.Status.Phase != cassandrav1.BackupUploading || b.Status.Nodes[0].Phase != cassandrav1.BackupNodeSnapshotted {
+		t.Errorf("got %+v, want the upload retried", b.Status)
+	}
+	if exists, _ := f.store.Exists(backup.NodeManifestKey("test", "daily", b.Status.Nodes[0].Pod)); exists {
+		t.Error("the manifest of the node is written before its files are uploaded")
+	}
+
+	// a missing node manifest fails the manifest of the backup
+	f.cqlsh = cqlsh
+	for range b.Status.Nodes {
+		if err := f.controller.uploadBackup(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	delete(f.store.Objects, backup.NodeManifestKey("test", "daily", b.Status.Nodes[2].Pod))
+	if err := f.controller.uploadBackup(b); err == nil || b.Status.Phase == cassandrav1.BackupCompleted {
+		t.Errorf("got %v, want the manifest of the backup waiting for the manifests of the nodes", err)
+	}
+}
@@ -1,56 +1,206 @@
 package controller
 
-import "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+import (
+	"fmt"
 
-func (c *Controller) deleteCassandraCluster(name string) error {
-	// deleted the statefulset
-	err := c.DeleteStatefulSet(name)
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	// clusterFinalizer applies the deletion policy of the CassandraCluster before it's deleted
+	clusterFinalizer = "cassandra/cluster"
+)
+
+// deleteCassandraCluster applies the deletion policy to the PVCs and releases the finalizer, the other objects are
+// garbage collected
+func (c *Controller) deleteCassandraCluster(cc *v1.CassandraCluster) error {
+	if !containsString(cc.Finalizers, clusterFinalizer) {
+		return nil
+	}
+	switch cc.Spec.DeletionPolicy {
+	case v1.DeletionSnapshotThenDelete:
+		done, err := c.finalBackup(cc)
+		if err != nil || !done {
+			return err
+		}
+		fallthrough
+	case v1.DeletionDelete:
+		deleted, err := c.DeletePVC(cc.Name)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			// the statefulsets are only garbage collected once the finalizer is released
+			nodes, err := c.deployedNodes(cc.Name)
+			if err != nil {
+				return err
+			}
+			if nodes > 0 {
+				c.recorder.Eventf(cc, corev1.EventTypeWarning, "PVCNotFound", "No PVC found for the %d nodes of the cluster, the finalizer is kept", nodes)
+				return fmt.Errorf("no PVC of CassandraCluster %s found to delete while it has %d nodes", cc.Name, nodes)
+			}
+		}
+		glog.Infof("deleted %d PVCs of CassandraCluster %s", deleted, cc.Name)
+	default:
+		glog.Infof("keeping the PVCs of the deleted CassandraCluster %s", cc.Name)
+	}
+	cc.Finalizers = removeString(cc.Finalizers, clusterFinalizer)
+	_, err := c.cassandraClusterClientset.CassandraV1().CassandraClusters(cc.Namespace).Update(cc)
+	return err
+}
+
+// deployedNodes returns the number of replicas of the statefulsets of a cluster
+func (c *Controller) deployedNodes(ccName string) (int32, error) {
+	stss, err := c.statefulsetsLister.StatefulSets(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": ccName}))
 	if err != nil {
-		return err
+		return 0, err
+	}
+	nodes := int32(0)
+	for _, sts := range stss {
+		if sts.Spec.Replicas != nil {
+			nodes += *sts.Spec.Replicas
+		}
+	}
+	return nodes, nil
+}
+
+// deployed returns true once a statefulset of the cluster was created
+func (c *Controller) deployed(ccName string) (bool, error) {
+	stss, err := c.statefulsetsLister.StatefulSets(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": ccName}))
+	return len(stss) > 0, err
+}
+
+// legacyDeployment returns true when the cluster runs the single statefulset named after it, deployed before
+// the racks. Its name, selector and service can't change so it's adopted as the statefulset of the first rack
+func (c *Controller) legacyDeployment(cc *v1.CassandraCluster) (bool, error) {
+	sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(cc.Name)
+	if errors.IsNotFound(err) {
+		return false, nil
 	}
-	// delete the pvc
-	err = c.DeletePVC(name)
 	if err != nil {
+		return false, err
+	}
+	owned := metav1.GetControllerOf(sts) == nil || metav1.IsControlledBy(sts, cc)
+	return owned && sts.Labels["cassandraCluster"] == cc.Name, nil
+}
+
+// finalBackupName returns the name of the CassandraBackup taken before the deletion of a cluster. The backups
+// outlive their cluster, the time of the deletion tells apart the clusters created again with the same name
+func finalBackupName(cc *v1.CassandraCluster) string {
+	return fmt.Sprintf("%s-final-%d", cc.Name, cc.DeletionTimestamp.Unix())
+}
+
+// finalBackup backs up the cluster being deleted and returns true once the backup is completed. The backup isn't
+// owned by the cluster so it can be restored after the deletion. A failed backup keeps the PVCs until it's deleted,
+// to be taken again, or until the deletion policy changes
+func (c *Controller) finalBackup(cc *v1.CassandraCluster) (bool, error) {
+	name := finalBackupName(cc)
+	b, err := c.cassandraBackupsLister.CassandraBackups(cc.Namespace).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if errors.IsNotFound(err) {
+		if cc.Spec.FinalBackup == nil {
+			return false, fmt.Errorf("CassandraCluster %s has no finalBackup storage, waiting for its deletion policy to change", cc.Name)
+		}
+		_, err = c.cassandraClusterClientset.CassandraV1().CassandraBackups(cc.Namespace).Create(&v1.CassandraBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"cassandraCluster": cc.Name,
+				},
+			},
+			Spec: v1.CassandraBackupSpec{
+				Cluster: cc.Name,
+				Storage: *cc.Spec.FinalBackup,
+			},
+		})
+		if errors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "FinalBackupStarted", "Backing up the cluster before deleting its data in backup %s", name)
+		return false, nil
+	}
+
+	switch b.Status.Phase {
+	case v1.BackupCompleted:
+		return true, nil
+	case v1.BackupFailed:
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "FinalBackupFailed", "The final backup %s failed, the PVCs are kept: %s", name, b.Status.Message)
+		return false, fmt.Errorf("final backup %s of CassandraCluster %s failed: %s", name, cc.Name, b.Status.Message)
+	}
+	// the resync requeues the cluster until the backup ends
+	return false, nil
+}
+
+func (c *Controller) createOrUpdateCassandraCluster(cc *v1.CassandraCluster) error {
+	// a cluster rebuilt from a backup is only deployed once the tokens of its nodes are known
+	waiting, err := c.waitingForRestore(cc)
+	if err != nil || waiting {
 		return err
 	}
-	// delete the service
-	err = c.DeleteService(name)
+
+	// renders the configuration and moves the upgrade of the Cassandra image or the rolling restart one step further,
+	// it sets the image, configuration and partitions of the statefulsets
+	err = c.reconcileUpgrade(cc)
 	if err != nil {
 		return err
 	}
-	return err
-}
 
-func (c *Controller) createOrUpdateCassandraCluster(cc *v1.CassandraCluster) error {
-	// reconciliates the statefulset
-	repair,err := c.CreateOrUpdateStatefulSet(cc)
+	// records the identity of the nodes in the ring and replaces the dead nodes whose pod lost its data
+	err = c.reconcileReplacement(cc)
 	if err != nil {
 		return err
 	}
 
-	// if required, launch a repair
-	if repair == true {
+	// labels the pods of the seeds published by the seed service
+	err = c.reconcileSeeds(cc)
+	if err != nil {
+		return err
+	}
 
+	// replaces the default superuser and keeps system_auth replicated on all the nodes. A failure doesn't hold the
+	// statefulsets back, the error is returned once the rest of the cluster is reconciliated
+	authErr := c.reconcileAuth(cc)
+	if authErr != nil {
+		glog.Warningf("could not reconcile the authentication of CassandraCluster %s: %v", cc.Name, authErr)
 	}
-	// reconciliates the service
-	err = c.CreateOrUpdateService(cc)
+
+	// reconciliates the statefulset
+	repair, err := c.CreateOrUpdateStatefulSets(cc)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	// if required, launch a repair once the topology is stable. Nodes added during the creation don't need it
+	if repair == true && cc.Status.Phase != v1.ClusterPhaseCreating {
+		requestRepair(cc, repairTriggerTopology)
+	}
+	// reconciliates the services
+	err = c.CreateOrUpdateServices(cc)
+	if err != nil {
+		return err
+	}
 
-func (c *Controller) fullRepair(cc *v1.CassandraCluster) error {
-	// get the statefulset
-	sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(cc.Name)
+	// moves the repair in progress one step further
+	err = c.reconcileRepair(cc)
 	if err != nil {
 		return err
 	}
-	// get the pods owned by the statefulset
-	pods := c.podLister.Pods(c.namespace).List()
 
-	// iterate over the range and execucte "nodetool repair -pr"
-	c.ExecCmd(pod,"nodetool repair -pr")
+	// blocks the voluntary disruptions of the nodes while the cluster changes
+	err = c.reconcilePodDisruptionBudgets(cc)
+	if err != nil {
+		return err
+	}
+	return authErr
 }
-
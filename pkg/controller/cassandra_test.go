@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func TestDeleteCassandraCluster(t *testing.T) {
+	labels := map[string]string{"cassandraCluster": "test"}
+	sts := newRackStatefulSet("test-dc1-rack1", 3)
+	sts.Labels = labels
+	pvcs := []runtime.Object{
+		newClusterPVC("test-dc1-rack1-0", labels),
+		newClusterPVC("test-dc1-rack1-1", labels),
+		newClusterPVC("test-dc1-rack1-2", labels),
+	}
+
+	tests := []struct {
+		name      string
+		policy    cassandrav1.DeletionPolicy
+		finalizer bool
+		objects   []runtime.Object
+		err       string
+		// finalized tells if the finalizer is removed
+		finalized bool
+		pvcs      int
+		events    int
+	}{
+		{"retain", cassandrav1.DeletionRetain, true, append(pvcs, sts), "", true, 3, 0},
+		{"default policy", "", true, append(pvcs, sts), "", true, 3, 0},
+		{"delete", cassandrav1.DeletionDelete, true, append(pvcs, sts), "", true, 0, 0},
+		// some claims were already deleted with their nodes
+		{"delete remaining", cassandrav1.DeletionDelete, true, append(pvcs[:1:1], sts), "", true, 0, 0},
+		// the PVCs are missing while the nodes are deployed, they may have other labels
+		{"no PVC found", cassandrav1.DeletionDelete, true, []runtime.Object{sts}, "no PVC of CassandraCluster test found", false, 0, 1},
+		{"nothing deployed", cassandrav1.DeletionDelete, true, nil, "", true, 0, 0},
+		// the deletion policy was applied already
+		{"no finalizer", cassandrav1.DeletionDelete, false, pvcs, "", true, 3, 0},
+	}
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		cc.Spec.DeletionPolicy = test.policy
+		cc.Finalizers = []string{"other"}
+		if test.finalizer {
+			cc.Finalizers = append(cc.Finalizers, clusterFinalizer)
+		}
+		f := newFixture(t, cc, test.objects...)
+
+		err := f.controller.deleteCassandraCluster(cc)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: got %v, want %q", test.name, err, test.err)
+		}
+		stored, err := f.client.CassandraV1().CassandraClusters(testNamespace).Get("test", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if containsString(stored.Finalizers, clusterFinalizer) == test.finalized || !containsString(stored.Finalizers, "other") {
+			t.Errorf("%s: got finalizers %v, want the finalizer of the cluster removed: %t", test.name, stored.Finalizers, test.finalized)
+		}
+		if names := f.pvcNames(); len(names) != test.pvcs {
+			t.Errorf("%s: got PVCs %v, want %d", test.name, names, test.pvcs)
+		}
+		if events := f.events(); len(events) != test.events || len(events) == 1 && !strings.HasPrefix(events[0], "Warning PVCNotFound") {
+			t.Errorf("%s: got events %v, want %d", test.name, events, test.events)
+		}
+	}
+}
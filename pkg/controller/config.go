@@ -0,0 +1,401 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	// ConfigHashAnnotation is the hash of the configuration of the pods
+	ConfigHashAnnotation = "cassandra/config-hash"
+	// configDir is where the rendered configuration is mounted in the init container
+	configDir = "/cassandra-config"
+	// cassandraConfDir is the configuration directory of the Cassandra image
+	cassandraConfDir = "/etc/cassandra"
+)
+
+// jvmOptions are the JVM options of the nodes, the heap sizes are set by cassandra-env.sh from the environment
+var jvmOptions = []string{
+	"-ea",
+	"-XX:+UseThreadPriorities",
+	"-XX:ThreadPriorityPolicy=42",
+	"-XX:+HeapDumpOnOutOfMemoryError",
+	"-Xss256k",
+	"-XX:StringTableSize=1000003",
+	"-XX:+AlwaysPreTouch",
+	"-XX:-UseBiasedLocking",
+	"-XX:+UseTLAB",
+	"-XX:+ResizeTLAB",
+	"-XX:+UseNUMA",
+	"-XX:+PerfDisableSharedMem",
+	"-Djava.net.preferIPv4Stack=true",
+	"-XX:+UseParNewGC",
+	"-XX:+UseConcMarkSweepGC",
+	"-XX:+CMSParallelRemarkEnabled",
+	"-XX:SurvivorRatio=8",
+	"-XX:MaxTenuringThreshold=1",
+	"-XX:CMSInitiatingOccupancyFraction=75",
+	"-XX:+UseCMSInitiatingOccupancyOnly",
+	"-XX:CMSWaitDuration=10000",
+	"-XX:+CMSParallelInitialMarkEnabled",
+	"-XX:+CMSEdenChunksRecordAlways",
+	"-XX:+CMSClassUnloadingEnabled",
+}
+
+// java8Options are the options only set for Cassandra 2.2 and 3.x running on Java 8. From Cassandra 4 they are in
+// the jvm8-server.options and jvm11-server.options of the image, which match its JVM
+var java8Options = map[string]bool{
+	"-XX:ThreadPriorityPolicy=42":           true,
+	"-XX:+UseParNewGC":                      true,
+	"-XX:+UseConcMarkSweepGC":               true,
+	"-XX:+CMSParallelRemarkEnabled":         true,
+	"-XX:SurvivorRatio=8":                   true,
+	"-XX:MaxTenuringThreshold=1":            true,
+	"-XX:CMSInitiatingOccupancyFraction=75": true,
+	"-XX:+UseCMSInitiatingOccupancyOnly":    true,
+	"-XX:CMSWaitDuration=10000":             true,
+	"-XX:+CMSParallelInitialMarkEnabled":    true,
+	"-XX:+CMSEdenChunksRecordAlways":        true,
+	"-XX:+CMSClassUnloadingEnabled":         true,
+}
+
+// defaultMajorVersion is the Cassandra version of the images without a version in their tag
+const defaultMajorVersion = 3
+
+// reconcileConfig renders the configuration of the nodes in a configmap named after its hash and returns the hash.
+// A new configmap is created for each configuration so the pods not restarted yet keep their configuration during
+// the rolling restart, and the previous one is still available for a rollback
+func (c *Controller) reconcileConfig(cc *cassandrav1.CassandraCluster) (string, error) {
+	revision, err := c.reconcileTLS(cc)
+	if err != nil {
+		return "", fmt.Errorf("could not issue the certificates: %v", err)
+	}
+	data, err := c.renderConfig(cc)
+	if err != nil {
+		return "", err
+	}
+	if revision != "" {
+		// the revision of the certificates is part of the hash so their renewal restarts the nodes
+		data["certificates.revision"] = revision
+	}
+	hash := configHash(data)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: configMapName(cc.Name, hash),
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role":             "config",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Data: data,
+	}
+	_, err = c.kubeClientset.CoreV1().ConfigMaps(c.namespace).Create(cm)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return hash, nil
+}
+
+// deleteConfigs removes the configmaps of the cluster except the configurations to keep
+func (c *Controller) deleteConfigs(ccName string, keep ...string) error {
+	cms, err := c.kubeClientset.CoreV1().ConfigMaps(c.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"cassandraCluster": ccName, "role": "config"}).String(),
+	})
+	if err != nil {
+		return err
+	}
+	for _, cm := range cms.Items {
+		if containsString(keep, strings.TrimPrefix(cm.Name, ccName+"-config-")) {
+			continue
+		}
+		glog.V(2).Infof("deleting the configuration %s of CassandraCluster %s", cm.Name, ccName)
+		err := c.kubeClientset.CoreV1().ConfigMaps(c.namespace).Delete(cm.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderConfig returns the cassandra.yaml and jvm options of the cluster and the cassandra-rackdc.properties of each
+// rack, for the Cassandra version of the base image
+func (c *Controller) renderConfig(cc *cassandrav1.CassandraCluster) (map[string]string, error) {
+	major := majorVersion(cc.Spec.BaseImage)
+	// the seeds are replaced by the ones of the seeds configmap when the node starts, so the configuration
+	// doesn't change with the selection of the seeds
+	cassandraYaml, err := renderCassandraYaml(cc, []string{seedServiceName(cc.Name) + "." + c.namespace + ".svc.cluster.local"}, major)
+	if err != nil {
+		return nil, err
+	}
+	var options []string
+	for _, option := range jvmOptions {
+		if major < 4 || !java8Options[option] {
+			options = append(options, option)
+		}
+	}
+	options = append(options, cc.Spec.CassandraSpec.Config.JVMOptions...)
+	data := map[string]string{
+		"cassandra.yaml":      cassandraYaml,
+		jvmOptionsFile(major): strings.Join(options, "\n") + "\n",
+	}
+	for _, rack := range getRacks(cc) {
+		data[rackdcKey(rack)] = fmt.Sprintf("dc=%s\nrack=%s\nprefer_local=true\n", rack.DC.Name, rack.Rack.Name)
+	}
+	return data, nil
+}
+
+// jvmOptionsFile returns the file of the JVM options read by the Cassandra version
+func jvmOptionsFile(major int) string {
+	if major < 4 {
+		return "jvm.options"
+	}
+	return "jvm-server.options"
+}
+
+// majorVersion returns the Cassandra major version of the image
+func majorVersion(image string) int {
+	major, _, err := imageVersion(image)
+	if err != nil {
+		return defaultMajorVersion
+	}
+	return major
+}
+
+// renderCassandraYaml builds the cassandra.yaml of the nodes. The addresses aren't set, Cassandra uses the address
+// the hostname of the pod resolves to. Cassandra refuses the properties unknown to its version
+func renderCassandraYaml(cc *cassandrav1.CassandraCluster, seeds []string, major int) (string, error) {
+	config := cc.Spec.CassandraSpec.Config
+	settings := map[string]interface{}{
+		"cluster_name":                     cc.Name,
+		"num_tokens":                       cc.Spec.CassandraSpec.NbToken,
+		"partitioner":                      "org.apache.cassandra.dht.Murmur3Partitioner",
+		"endpoint_snitch":                  "GossipingPropertyFileSnitch",
+		"authenticator":                    "AllowAllAuthenticator",
+		"authorizer":                       "AllowAllAuthorizer",
+		"role_manager":                     "CassandraRoleManager",
+		"data_file_directories":            []string{cassandraDataDir},
+		"commitlog_directory":              "/cassandra_data/commitlog",
+		"saved_caches_directory":           "/cassandra_data/saved_caches",
+		"commitlog_sync":                   "periodic",
+		"commitlog_sync_period_in_ms":      10000,
+		"commitlog_segment_size_in_mb":     32,
+		"hinted_handoff_enabled":           true,
+		"max_hint_window_in_ms":            10800000,
+		"disk_failure_policy":              "stop",
+		"commit_failure_policy":            "stop",
+		"concurrent_reads":                 32,
+		"concurrent_writes":                32,
+		"concurrent_counter_writes":        32,
+		"memtable_allocation_type":         "heap_buffers",
+		"storage_port":                     7000,
+		"ssl_storage_port":                 7001,
+		"start_native_transport":           true,
+		"native_transport_port":            9042,
+		"incremental_backups":              false,
+		"snapshot_before_compaction":       false,
+		"auto_snapshot":                    true,
+		"compaction_throughput_mb_per_sec": 16,
+		"read_request_timeout_in_ms":       5000,
+		"range_request_timeout_in_ms":      10000,
+		"write_request_timeout_in_ms":      2000,
+		"request_timeout_in_ms":            10000,
+		"tombstone_warn_threshold":         1000,
+		"tombstone_failure_threshold":      100000,
+		"batch_size_warn_threshold_in_kb":  5,
+		"gc_warn_threshold_in_ms":          1000,
+		"seed_provider": []map[string]interface{}{{
+			"class_name": "org.apache.cassandra.locator.SimpleSeedProvider",
+			"parameters": []map[string]string{{"seeds": strings.Join(seeds, ",")}},
+		}},
+	}
+	if major >= 3 {
+		settings["hints_directory"] = "/cassandra_data/hints"
+	}
+	// thrift was removed in Cassandra 4
+	if major < 4 {
+		settings["start_rpc"] = false
+		settings["rpc_port"] = 9160
+	}
+	setInt32(settings, "concurrent_reads", config.ConcurrentReads)
+	setInt32(settings, "concurrent_writes", config.ConcurrentWrites)
+	setInt32(settings, "concurrent_counter_writes", config.ConcurrentCounterWrites)
+	setInt32(settings, "compaction_throughput_mb_per_sec", config.CompactionThroughputMBPerSec)
+	setInt64(settings, "read_request_timeout_in_ms", config.ReadRequestTimeoutMs)
+	setInt64(settings, "write_request_timeout_in_ms", config.WriteRequestTimeoutMs)
+	setInt64(settings, "range_request_timeout_in_ms", config.RangeRequestTimeoutMs)
+	setInt64(settings, "request_timeout_in_ms", config.RequestTimeoutMs)
+	settings["server_encryption_options"], settings["client_encryption_options"] = encryptionOptions(cc)
+	if config.Authenticator != "" {
+		settings["authenticator"] = config.Authenticator
+	}
+	if config.Authorizer != "" {
+		settings["authorizer"] = config.Authorizer
+	}
+
+	// the overrides are applied in a stable order
+	keys := make([]string, 0, len(config.Overrides))
+	for key := range config.Overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(config.Overrides[key]), &value); err != nil {
+			return "", fmt.Errorf("invalid value for the %s override: %v", key, err)
+		}
+		settings[key] = value
+	}
+
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func setInt32(settings map[string]interface{}, key string, value *int32) {
+	if value != nil {
+		settings[key] = *value
+	}
+}
+
+func setInt64(settings map[string]interface{}, key string, value *int64) {
+	if value != nil {
+		settings[key] = *value
+	}
+}
+
+// configHash returns a short hash of the configuration files
+func configHash(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s\n%s\n", key, data[key])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:10]
+}
+
+// configMapName returns the name of the configmap holding a configuration of the cluster
+func configMapName(ccName string, hash string) string {
+	return ccName + "-config-" + hash
+}
+
+// rackdcKey returns the key of the cassandra-rackdc.properties of the rack in the configmap
+func rackdcKey(rack cassandraRack) string {
+	return "cassandra-rackdc." + rack.DC.Name + "." + rack.Rack.Name + ".properties"
+}
+
+// clusterConfigHash returns the configuration of the statefulsets: the new one during a rolling restart,
+// the previous one when it's rejected or rolled back
+func clusterConfigHash(cc *cassandrav1.CassandraCluster) string {
+	if u := cc.Status.Upgrade; u != nil {
+		switch u.Phase {
+		case cassandrav1.UpgradeRunning, cassandrav1.UpgradePaused, cassandrav1.UpgradeSSTables:
+			return u.ToConfigHash
+		default:
+			return u.FromConfigHash
+		}
+	}
+	return cc.Status.ConfigHash
+}
+
+// configVolumes returns the volumes of the configuration of the rack: the rendered files are copied over the
+// configuration of the image by an init container as the image entrypoint may edit them
+func configVolumes(cc *cassandrav1.CassandraCluster, rack cassandraRack, hash string) ([]corev1.Volume, []corev1.Container) {
+	if hash == "" {
+		// the cluster was deployed before the configuration was managed by the operator
+		return nil, nil
+	}
+	// the configuration was rendered for the image of the statefulsets
+	major := majorVersion(clusterImage(cc))
+	volumes := []corev1.Volume{
+		{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMapName(cc.Name, hash)},
+					Items: []corev1.KeyToPath{
+						{Key: "cassandra.yaml", Path: "cassandra.yaml"},
+						{Key: jvmOptionsFile(major), Path: jvmOptionsFile(major)},
+						{Key: rackdcKey(rack), Path: "cassandra-rackdc.properties"},
+					},
+				},
+			},
+		},
+		{
+			Name: "etc-cassandra",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			// address of the dead node replaced by each pod, only present during a replacement
+			Name: "replace-address",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: replaceAddressesConfigMapName(cc.Name)},
+					Optional:             func(b bool) *bool { return &b }(true),
+				},
+			},
+		},
+	}
+	initContainer := corev1.Container{
+		Name:  "config",
+		Image: clusterImage(cc),
+		Command: []string{
+			"/bin/sh",
+			"-c",
+			fmt.Sprintf("cp -a %[1]s/. /etc-cassandra/ && cp %[2]s/* /etc-cassandra/ && %[3]s && %[4]s", cassandraConfDir, configDir, seedsCommand(), replaceAddressCommand(major)),
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.name",
+					},
+				},
+			},
+			seedsEnv(cc),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "config", MountPath: configDir},
+			{Name: "etc-cassandra", MountPath: "/etc-cassandra"},
+			{Name: "replace-address", MountPath: replaceAddressDir, ReadOnly: true},
+		},
+	}
+	if tlsEnabled(cc) {
+		// each node picks its own keystore in the secret
+		volumes = append(volumes, corev1.Volume{
+			Name: "tls",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tlsSecretName(cc.Name),
+				},
+			},
+		})
+		initContainer.Command[2] += " && " + tlsCopyCommand()
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      "tls",
+			MountPath: tlsSecretDir,
+			ReadOnly:  true,
+		})
+	}
+	return volumes, []corev1.Container{initContainer}
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestRenderCassandraYaml(t *testing.T) {
+	tests := []struct {
+		image   string
+		present []string
+		absent  []string
+	}{
+		{"cassandra:2.2.13", []string{"start_rpc", "rpc_port"}, []string{"hints_directory"}},
+		{"cassandra:3.11.3", []string{"hints_directory", "start_rpc", "rpc_port"}, nil},
+		{"cassandra:4.0.1", []string{"hints_directory"}, []string{"start_rpc", "rpc_port"}},
+		// the version of the images without a version tag is unknown
+		{"mycompany/cassandra:latest", []string{"hints_directory", "start_rpc", "rpc_port"}, nil},
+	}
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		cc.Spec.BaseImage = test.image
+		out, err := renderCassandraYaml(cc, []string{"test-dc1-rack1-0"}, majorVersion(test.image))
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(out), &settings); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range test.present {
+			if _, ok := settings[key]; !ok {
+				t.Errorf("%s: %s missing", test.image, key)
+			}
+		}
+		for _, key := range test.absent {
+			if _, ok := settings[key]; ok {
+				t.Errorf("%s: %s unknown to the version", test.image, key)
+			}
+		}
+	}
+}
+
+func TestRenderConfigJVMOptions(t *testing.T) {
+	tests := []struct {
+		image string
+		file  string
+		cms   bool
+	}{
+		{"cassandra:3.11.3", "jvm.options", true},
+		{"cassandra:4.0.1", "jvm-server.options", false},
+	}
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		cc.Spec.BaseImage = test.image
+		cc.Spec.CassandraSpec.Config.JVMOptions = []string{"-Dcassandra.test=true"}
+		f := newFixture(t, cc)
+		data, err := f.controller.renderConfig(cc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		options, ok := data[test.file]
+		if !ok {
+			t.Fatalf("%s: no %s in %v", test.image, test.file, data)
+		}
+		if cms := strings.Contains(options, "-XX:+UseConcMarkSweepGC"); cms != test.cms {
+			t.Errorf("%s: got CMS options %v, want %v", test.image, cms, test.cms)
+		}
+		if !strings.HasSuffix(options, "-Dcassandra.test=true\n") {
+			t.Errorf("%s: the options of the spec aren't last: %s", test.image, options)
+		}
+	}
+}
+
+func TestMajorVersion(t *testing.T) {
+	tests := map[string]int{
+		"cassandra:2.2.13":              2,
+		"cassandra:3.0.15":              3,
+		"registry:5000/cassandra:4.0.1": 4,
+		"cassandra":                     defaultMajorVersion,
+	}
+	for image, major := range tests {
+		if got := majorVersion(image); got != major {
+			t.Errorf("%s: got %d, want %d", image, got, major)
+		}
+	}
+}
@@ -2,13 +2,16 @@ package controller
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/golang/glog"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
@@ -17,6 +20,7 @@ import (
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -28,8 +32,8 @@ import (
 	informers "github.com/vgkowski/cassandra-operator/pkg/client/informers/externalversions"
 	cassandraScheme "github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned/scheme"
 	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
-	"k8s.io/client-go/scale/scheme/appsv1beta2"
-	"k8s.io/client-go/informers/core/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/backup"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
 )
 
 const controllerAgentName = "cassandraCluster-controller"
@@ -57,6 +61,16 @@ type Controller struct {
 	kubeClientset kubernetes.Interface
 	// namespace where the controller operates
 	namespace string
+	// baseImage is the Cassandra image of the clusters which don't set it
+	baseImage string
+	// jolokiaPort is the port of the Jolokia agent of the nodes, nodetool is run in the pods when it's 0
+	jolokiaPort int
+	// adminClient returns the client managing the Cassandra node of a pod, it's replaced by a fake in the tests
+	adminClient func(podName string) (admin.Client, error)
+	// exec runs a command in the Cassandra container of a pod, it's replaced by a fake in the tests
+	exec func(podName string, cmd []string, stdin io.Reader, stdout io.Writer) (string, error)
+	// objectStore returns the object store holding the backups, it's replaced by a fake in the tests
+	objectStore func(namespace string, storage cassandrav1.BackupStorage) (backup.ObjectStore, error)
 	// cassandraClusterClientset is a clientset for our own API group
 	cassandraClusterClientset clientset.Interface
 
@@ -64,8 +78,20 @@ type Controller struct {
 	statefulsetsSynced cache.InformerSynced
 	servicesLister corelisters.ServiceLister
 	servicesSynced cache.InformerSynced
+	configMapsSynced cache.InformerSynced
+	secretsSynced cache.InformerSynced
+	pdbsLister policylisters.PodDisruptionBudgetLister
+	pdbsSynced cache.InformerSynced
 	CassandraClustersLister        listers.CassandraClusterLister
 	CassandraClustersSynced        cache.InformerSynced
+	cassandraBackupsLister         listers.CassandraBackupLister
+	cassandraBackupsSynced         cache.InformerSynced
+	cassandraRestoresLister        listers.CassandraRestoreLister
+	cassandraRestoresSynced        cache.InformerSynced
+	cassandraRolesLister           listers.CassandraRoleLister
+	cassandraRolesSynced           cache.InformerSynced
+	cassandraKeyspacesLister       listers.CassandraKeyspaceLister
+	cassandraKeyspacesSynced       cache.InformerSynced
 	podLister					   corelisters.PodLister
 	podSynced					   cache.InformerSynced
 
@@ -75,6 +101,15 @@ type Controller struct {
 	// time, and makes it easy to ensure we are never processing the same item
 	// simultaneously in two different workers.
 	workqueue workqueue.RateLimitingInterface
+	// backupQueue is the work queue of the CassandraBackup resources. Backups are processed by
+	// their own worker as uploading the files of a node takes time
+	backupQueue workqueue.RateLimitingInterface
+	// restoreQueue is the work queue of the CassandraRestore resources
+	restoreQueue workqueue.RateLimitingInterface
+	// roleQueue is the work queue of the CassandraRole resources
+	roleQueue workqueue.RateLimitingInterface
+	// keyspaceQueue is the work queue of the CassandraKeyspace resources
+	keyspaceQueue workqueue.RateLimitingInterface
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
 	recorder record.EventRecorder
@@ -85,6 +120,8 @@ func NewController(
 	config *rest.Config,
 	kubeClientset kubernetes.Interface,
 	namespace string,
+	baseImage string,
+	jolokiaPort int,
 	cassandraClusterClientset clientset.Interface,
 	kubeInformerFactory kubeinformers.SharedInformerFactory,
 	cassandraClusterInformerFactory informers.SharedInformerFactory) *Controller {
@@ -93,8 +130,15 @@ func NewController(
 	// types.
 	statefulsetInformer := kubeInformerFactory.Apps().V1().StatefulSets()
 	CassandraClusterInformer := cassandraClusterInformerFactory.Cassandra().V1().CassandraClusters()
+	cassandraBackupInformer := cassandraClusterInformerFactory.Cassandra().V1().CassandraBackups()
+	cassandraRestoreInformer := cassandraClusterInformerFactory.Cassandra().V1().CassandraRestores()
+	cassandraRoleInformer := cassandraClusterInformerFactory.Cassandra().V1().CassandraRoles()
+	cassandraKeyspaceInformer := cassandraClusterInformerFactory.Cassandra().V1().CassandraKeyspaces()
 	serviceInformer := kubeInformerFactory.Core().V1().Services()
 	podInformer := kubeInformerFactory.Core().V1().Pods()
+	configMapInformer := kubeInformerFactory.Core().V1().ConfigMaps()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
+	pdbInformer := kubeInformerFactory.Policy().V1beta1().PodDisruptionBudgets()
 
 	// Create event broadcaster
 	// Add cassandraCluster-controller types to the default Kubernetes Scheme so Events can be
@@ -110,18 +154,39 @@ func NewController(
 		config:			   config,
 		kubeClientset:     kubeClientset,
 		namespace: namespace,
+		baseImage: baseImage,
+		jolokiaPort: jolokiaPort,
 		cassandraClusterClientset:   cassandraClusterClientset,
 		podLister: podInformer.Lister(),
-		podSynced: podInformer.Informer().HasSynced(),
+		podSynced: podInformer.Informer().HasSynced,
 		servicesLister: serviceInformer.Lister(),
-		servicesSynced: serviceInformer.Informer().HasSynced(),
+		servicesSynced: serviceInformer.Informer().HasSynced,
+		configMapsSynced: configMapInformer.Informer().HasSynced,
+		secretsSynced: secretInformer.Informer().HasSynced,
+		pdbsLister: pdbInformer.Lister(),
+		pdbsSynced: pdbInformer.Informer().HasSynced,
 		statefulsetsLister: statefulsetInformer.Lister(),
 		statefulsetsSynced: statefulsetInformer.Informer().HasSynced,
 		CassandraClustersLister:        CassandraClusterInformer.Lister(),
 		CassandraClustersSynced:        CassandraClusterInformer.Informer().HasSynced,
+		cassandraBackupsLister:         cassandraBackupInformer.Lister(),
+		cassandraBackupsSynced:         cassandraBackupInformer.Informer().HasSynced,
+		cassandraRestoresLister:        cassandraRestoreInformer.Lister(),
+		cassandraRestoresSynced:        cassandraRestoreInformer.Informer().HasSynced,
+		cassandraRolesLister:           cassandraRoleInformer.Lister(),
+		cassandraRolesSynced:           cassandraRoleInformer.Informer().HasSynced,
+		cassandraKeyspacesLister:       cassandraKeyspaceInformer.Lister(),
+		cassandraKeyspacesSynced:       cassandraKeyspaceInformer.Informer().HasSynced,
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "CassandraClusters"),
+		backupQueue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "CassandraBackups"),
+		restoreQueue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "CassandraRestores"),
+		roleQueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "CassandraRoles"),
+		keyspaceQueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "CassandraKeyspaces"),
 		recorder:          recorder,
 	}
+	controller.adminClient = controller.newAdminClient
+	controller.exec = controller.execCmd
+	controller.objectStore = controller.newObjectStore
 
 	glog.Info("Setting up event handlers")
 	// Set up an event handler for when CassandraCluster resources change
@@ -132,6 +197,35 @@ func NewController(
 		},
 		DeleteFunc: controller.enqueueCassandraCluster,
 	})
+	// Set up an event handler for when CassandraBackup resources change
+	cassandraBackupInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueCassandraBackup,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueCassandraBackup(new)
+		},
+	})
+	// Set up an event handler for when CassandraRestore resources change
+	cassandraRestoreInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueCassandraRestore,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueCassandraRestore(new)
+		},
+	})
+	// Set up an event handler for when CassandraRole resources change. The periodic resync also checks that the
+	// permissions of the roles didn't drift
+	cassandraRoleInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueCassandraRole,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueCassandraRole(new)
+		},
+	})
+	// Set up an event handler for when CassandraKeyspace resources change
+	cassandraKeyspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueCassandraKeyspace,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueCassandraKeyspace(new)
+		},
+	})
 	// Set up an event handler for when Statefulset resources change. This
 	// handler will lookup the owner of the given Deployment, and if it is
 	// owned by a CassandraCluster resource will enqueue that CassandraCluster resource for
@@ -152,6 +246,19 @@ func NewController(
 		},
 		DeleteFunc: controller.handleObject,
 	})
+	// the other objects generated for a CassandraCluster requeue it the same way
+	for _, informer := range []cache.SharedIndexInformer{serviceInformer.Informer(), configMapInformer.Informer(), secretInformer.Informer(), pdbInformer.Informer()} {
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: controller.handleObject,
+			UpdateFunc: func(old, new interface{}) {
+				if new.(metav1.Object).GetResourceVersion() == old.(metav1.Object).GetResourceVersion() {
+					return
+				}
+				controller.handleObject(new)
+			},
+			DeleteFunc: controller.handleObject,
+		})
+	}
 
 	return controller
 }
@@ -163,13 +270,17 @@ func NewController(
 func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	defer runtime.HandleCrash()
 	defer c.workqueue.ShutDown()
+	defer c.backupQueue.ShutDown()
+	defer c.restoreQueue.ShutDown()
+	defer c.roleQueue.ShutDown()
+	defer c.keyspaceQueue.ShutDown()
 
 	// Start the informer factories to begin populating the informer caches
 	glog.Info("Starting CassandraCluster controller")
 
 	// Wait for the caches to be synced before starting workers
 	glog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.statefulsetsSynced, c.CassandraClustersSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, c.statefulsetsSynced, c.servicesSynced, c.configMapsSynced, c.secretsSynced, c.pdbsSynced, c.podSynced, c.CassandraClustersSynced, c.cassandraBackupsSynced, c.cassandraRestoresSynced, c.cassandraRolesSynced, c.cassandraKeyspacesSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
@@ -178,6 +289,10 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
+	go wait.Until(c.runBackupWorker, time.Second, stopCh)
+	go wait.Until(c.runRestoreWorker, time.Second, stopCh)
+	go wait.Until(c.runRoleWorker, time.Second, stopCh)
+	go wait.Until(c.runKeyspaceWorker, time.Second, stopCh)
 
 	glog.Info("Started workers")
 	<-stopCh
@@ -190,14 +305,38 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 // processNextWorkItem function in order to read and process a message on the
 // workqueue.
 func (c *Controller) runWorker() {
-	for c.processNextWorkItem() {
+	for c.processNextWorkItem(c.workqueue, c.syncHandler) {
+	}
+}
+
+// runBackupWorker processes the CassandraBackup work queue
+func (c *Controller) runBackupWorker() {
+	for c.processNextWorkItem(c.backupQueue, c.syncBackup) {
+	}
+}
+
+// runRestoreWorker processes the CassandraRestore work queue
+func (c *Controller) runRestoreWorker() {
+	for c.processNextWorkItem(c.restoreQueue, c.syncRestore) {
+	}
+}
+
+// runRoleWorker processes the CassandraRole work queue
+func (c *Controller) runRoleWorker() {
+	for c.processNextWorkItem(c.roleQueue, c.syncRole) {
+	}
+}
+
+// runKeyspaceWorker processes the CassandraKeyspace work queue
+func (c *Controller) runKeyspaceWorker() {
+	for c.processNextWorkItem(c.keyspaceQueue, c.syncKeyspace) {
 	}
 }
 
 // processNextWorkItem will read a single work item off the workqueue and
 // attempt to process it, by calling the syncHandler.
-func (c *Controller) processNextWorkItem() bool {
-	obj, shutdown := c.workqueue.Get()
+func (c *Controller) processNextWorkItem(queue workqueue.RateLimitingInterface, syncHandler func(string) error) bool {
+	obj, shutdown := queue.Get()
 
 	if shutdown {
 		return false
@@ -211,7 +350,7 @@ func (c *Controller) processNextWorkItem() bool {
 		// not call Forget if a transient error occurs, instead the item is
 		// put back on the workqueue and attempted again after a back-off
 		// period.
-		defer c.workqueue.Done(obj)
+		defer queue.Done(obj)
 		var key string
 		var ok bool
 		// We expect strings to come off the workqueue. These are of the
@@ -223,18 +362,18 @@ func (c *Controller) processNextWorkItem() bool {
 			// As the item in the workqueue is actually invalid, we call
 			// Forget here else we'd go into a loop of attempting to
 			// process a work item that is invalid.
-			c.workqueue.Forget(obj)
+			queue.Forget(obj)
 			runtime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
 			return nil
 		}
 		// Run the syncHandler, passing it the namespace/name string of the
-		// CassandraCluster resource to be synced.
-		if err := c.syncHandler(key); err != nil {
+		// resource to be synced.
+		if err := syncHandler(key); err != nil {
 			return fmt.Errorf("error syncing '%s': %s", key, err.Error())
 		}
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
-		c.workqueue.Forget(obj)
+		queue.Forget(obj)
 		glog.Infof("Successfully synced '%s'", key)
 		return nil
 	}(obj)
@@ -261,29 +400,63 @@ func (c *Controller) syncHandler(key string) error {
 	// Get the CassandraCluster resource with this namespace/name
 	cassandraCluster, err := c.CassandraClustersLister.CassandraClusters(namespace).Get(name)
 	if err != nil {
-		// The CassandraCluster resource may no longer exist, in which case we consider as a deletion of a CassandraCluster
-		// processing.
+		// The CassandraCluster resource may no longer exist, its finalizer already cleaned up after it
 		if errors.IsNotFound(err) {
-			glog.Infof("CassandraCluster '%s' in work queue no longer exists, deleting the CassandraCluster...", key)
-			return c.deleteCassandraCluster(name)
+			glog.V(4).Infof("CassandraCluster '%s' in work queue no longer exists", key)
+			return nil
 		}
 
 		return err
-	} else {
-		return c.createOrUpdateCassandraCluster(cassandraCluster)
 	}
 
-	// If the Deployment is not controlled by this CassandraCluster resource, we should log
-	// a warning to the event recorder and ret
-	if !metav1.IsControlledBy(deployment, CassandraCluster) {
-		msg := fmt.Sprintf(MessageResourceExists, deployment.Name)
-		c.recorder.Event(CassandraCluster, corev1.EventTypeWarning, ErrResourceExists, msg)
-		return fmt.Errorf(msg)
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	// The reconciliation works on a deep copy as long running operations keep track of their progress in the status
+	ccCopy := cassandraCluster.DeepCopy()
+
+	if ccCopy.DeletionTimestamp != nil {
+		return c.deleteCassandraCluster(ccCopy)
+	}
+	if !containsString(ccCopy.Finalizers, clusterFinalizer) {
+		// the update requeues the cluster
+		ccCopy.Finalizers = append(ccCopy.Finalizers, clusterFinalizer)
+		_, err = c.cassandraClusterClientset.CassandraV1().CassandraClusters(namespace).Update(ccCopy)
+		return err
 	}
 
+	// clusters created without the mutating webhook get their defaults recorded before being deployed
+	cassandrav1.SetDefaults(ccCopy, c.baseImage)
+	deployed, err := c.deployed(ccCopy.Name)
+	if err != nil {
+		return err
+	}
+	if !deployed {
+		cassandrav1.SetAuthDefaults(ccCopy)
+	}
+	if !equality.Semantic.DeepEqual(cassandraCluster.Spec, ccCopy.Spec) {
+		glog.Infof("recording the defaults of CassandraCluster %s", key)
+		_, err = c.cassandraClusterClientset.CassandraV1().CassandraClusters(namespace).Update(ccCopy)
+		return err
+	}
+
+	// the admission webhook is optional, the spec is checked again before anything is deployed
+	if errs := validateSpec(ccCopy); len(errs) > 0 {
+		return c.rejectCassandraCluster(cassandraCluster, ccCopy, errs)
+	}
+	if !ccCopy.Status.Legacy {
+		ccCopy.Status.Legacy, err = c.legacyDeployment(ccCopy)
+		if err != nil {
+			return err
+		}
+	}
+
+	syncErr := c.createOrUpdateCassandraCluster(ccCopy)
+
 	// Finally, we update the status block of the CassandraCluster resource to reflect the
 	// current state of the world
-	err = c.updateCassandraClusterStatus(CassandraCluster, deployment)
+	err = c.updateCassandraClusterStatus(cassandraCluster, ccCopy)
+	if syncErr != nil {
+		return syncErr
+	}
 	if err != nil {
 		return err
 	}
@@ -292,20 +465,33 @@ func (c *Controller) syncHandler(key string) error {
 	return nil
 }
 
-func (c *Controller) updateCassandraClusterStatus(CassandraCluster *cassandrav1.CassandraCluster, deployment *appsv1beta2.Deployment) error {
-	// NEVER modify objects from the store. It's a read-only, local cache.
-	// You can use DeepCopy() to make a deep copy of original object and modify this copy
-	// Or create a copy manually for better performance
-	CassandraClusterCopy := CassandraCluster.DeepCopy()
-	CassandraClusterCopy.Status.AvailableReplicas = deployment.Status.AvailableReplicas
-	// Until #38113 is merged, we must use Update instead of UpdateStatus to
-	// update the Status block of the CassandraCluster resource. UpdateStatus will not
-	// allow changes to the Spec of the resource, which is ideal for ensuring
-	// nothing other than resource status has been updated.
-	_, err := c.sampleclientset.SamplecontrollerV1alpha1().CassandraClusters(CassandraCluster.Namespace).Update(CassandraClusterCopy)
+// updateCassandraClusterStatus computes the status of the reconciled copy and writes it if it differs
+// from the status of the original object
+func (c *Controller) updateCassandraClusterStatus(cc *cassandrav1.CassandraCluster, ccCopy *cassandrav1.CassandraCluster) error {
+	err := c.computeStatus(ccCopy)
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(cc.Status, ccCopy.Status) {
+		return nil
+	}
+	// UpdateStatus will not allow changes to the Spec of the resource, which is ideal
+	// for ensuring nothing other than resource status has been updated.
+	_, err = c.cassandraClusterClientset.CassandraV1().CassandraClusters(ccCopy.Namespace).UpdateStatus(ccCopy)
 	return err
 }
 
+// persistStatus writes the status of the CassandraCluster right away. It's used by long running operations
+// to record their progress before acting so they can be resumed after a restart of the operator
+func (c *Controller) persistStatus(cc *cassandrav1.CassandraCluster) error {
+	updated, err := c.cassandraClusterClientset.CassandraV1().CassandraClusters(cc.Namespace).UpdateStatus(cc)
+	if err != nil {
+		return err
+	}
+	cc.ResourceVersion = updated.ResourceVersion
+	return nil
+}
+
 // enqueueCassandraCluster takes a CassandraCluster resource and converts it into a namespace/name
 // string which is then put onto the work queue. This method should *not* be
 // passed resources of any type other than CassandraCluster.
@@ -319,6 +505,50 @@ func (c *Controller) enqueueCassandraCluster(obj interface{}) {
 	c.workqueue.AddRateLimited(key)
 }
 
+// enqueueCassandraBackup puts the namespace/name key of a CassandraBackup resource onto the backup work queue
+func (c *Controller) enqueueCassandraBackup(obj interface{}) {
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.backupQueue.AddRateLimited(key)
+}
+
+// enqueueCassandraRestore puts the namespace/name key of a CassandraRestore resource onto the restore work queue
+func (c *Controller) enqueueCassandraRestore(obj interface{}) {
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.restoreQueue.AddRateLimited(key)
+}
+
+// enqueueCassandraRole puts the namespace/name key of a CassandraRole resource onto the role work queue
+func (c *Controller) enqueueCassandraRole(obj interface{}) {
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.roleQueue.AddRateLimited(key)
+}
+
+// enqueueCassandraKeyspace puts the namespace/name key of a CassandraKeyspace resource onto the keyspace work queue
+func (c *Controller) enqueueCassandraKeyspace(obj interface{}) {
+	var key string
+	var err error
+	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.keyspaceQueue.AddRateLimited(key)
+}
+
 // handleObject will take any resource implementing metav1.Object and attempt
 // to find the CassandraCluster resource that 'owns' it. It does this by looking at the
 // objects metadata.ownerReferences field for an appropriate OwnerReference.
@@ -357,4 +587,107 @@ func (c *Controller) handleObject(obj interface{}) {
 		c.enqueueCassandraCluster(CassandraCluster)
 		return
 	}
-}
\ No newline at end of file
+}
+
+// newControllerRef returns the owner reference making the CassandraCluster the controller of an object generated for it
+func newControllerRef(cc *cassandrav1.CassandraCluster) metav1.OwnerReference {
+	return *metav1.NewControllerRef(cc, cassandrav1.SchemeGroupVersion.WithKind("CassandraCluster"))
+}
+
+// checkOwner returns an error and records an ErrResourceExists event when an object of the CassandraCluster isn't
+// controlled by it, except the objects labelled with the cluster created before the owner references
+func (c *Controller) checkOwner(cc *cassandrav1.CassandraCluster, object metav1.Object) error {
+	if ownedBy(cc, object) {
+		return nil
+	}
+	msg := fmt.Sprintf(MessageResourceExists, object.GetName())
+	c.recorder.Event(cc, corev1.EventTypeWarning, ErrResourceExists, msg)
+	return fmt.Errorf(msg)
+}
+
+// ownedBy returns true if the object is controlled by the CassandraCluster or was created for it before the owner references
+func ownedBy(cc *cassandrav1.CassandraCluster, object metav1.Object) bool {
+	if metav1.IsControlledBy(object, cc) {
+		return true
+	}
+	return metav1.GetControllerOf(object) == nil && object.GetLabels()["cassandraCluster"] == cc.Name
+}
+
+// adopt makes the CassandraCluster the controller of an object checked by checkOwner
+func adopt(cc *cassandrav1.CassandraCluster, object metav1.Object) {
+	if metav1.GetControllerOf(object) == nil {
+		object.SetOwnerReferences(append(object.GetOwnerReferences(), newControllerRef(cc)))
+	}
+}
+
+// object is a Kubernetes object generated for a CassandraCluster
+type object interface {
+	metav1.Object
+	apiruntime.Object
+}
+
+// objectClient gets, creates and updates the objects of a kind
+type objectClient struct {
+	get    func(name string) (object, error)
+	create func(obj object) error
+	update func(obj object) error
+}
+
+// createOrUpdate creates the desired object, or adopts the current one and updates it when merge changes its copy
+func (c *Controller) createOrUpdate(cc *cassandrav1.CassandraCluster, client objectClient, desired object, merge func(updated object)) error {
+	current, err := client.get(desired.GetName())
+	if errors.IsNotFound(err) {
+		return client.create(desired)
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.checkOwner(cc, current); err != nil {
+		return err
+	}
+	updated := current.DeepCopyObject().(object)
+	adopt(cc, updated)
+	merge(updated)
+	if equality.Semantic.DeepEqual(current, updated) {
+		return nil
+	}
+	return client.update(updated)
+}
+
+// configMapClient returns the client of the configmaps, read from the API as they aren't cached
+func (c *Controller) configMapClient() objectClient {
+	client := c.kubeClientset.CoreV1().ConfigMaps(c.namespace)
+	return objectClient{
+		get: func(name string) (object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		create: func(obj object) error {
+			_, err := client.Create(obj.(*corev1.ConfigMap))
+			return err
+		},
+		update: func(obj object) error {
+			_, err := client.Update(obj.(*corev1.ConfigMap))
+			return err
+		},
+	}
+}
+
+// mergeConfigMap returns the merge replacing the data of the configmap and adding its labels
+func mergeConfigMap(desired *corev1.ConfigMap) func(updated object) {
+	return func(updated object) {
+		cm := updated.(*corev1.ConfigMap)
+		cm.Labels = mergeMap(cm.Labels, desired.Labels)
+		cm.Data = desired.Data
+	}
+}
+
+// mergeMap adds the entries to the current labels or annotations, keeping the ones added by others
+func mergeMap(current map[string]string, entries map[string]string) map[string]string {
+	if current == nil {
+		current = map[string]string{}
+	}
+	for k, v := range entries {
+		current[k] = v
+	}
+	return current
+}
@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/backup"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+	"github.com/vgkowski/cassandra-operator/pkg/client/clientset/versioned/fake"
+	informers "github.com/vgkowski/cassandra-operator/pkg/client/informers/externalversions"
+)
+
+const testNamespace = "default"
+
+// fixture is a controller working on fake clientsets, with the listers filled with the objects of the test and
+// fake Cassandra nodes
+type fixture struct {
+	t          *testing.T
+	controller *Controller
+	kubeClient *kubefake.Clientset
+	client     *fake.Clientset
+	recorder   *record.FakeRecorder
+	nodes      map[string]*admin.Fake
+	store      *backup.Fake
+	// commands are the commands run in the pods, prefixed by the pod
+	commands []string
+	// cqlsh returns the output of the commands run in the pods
+	cqlsh func(podName string, cmd []string) (string, string, error)
+}
+
+func newFixture(t *testing.T, cc *cassandrav1.CassandraCluster, objects ...runtime.Object) *fixture {
+	var kubeObjects, cassandraObjects []runtime.Object
+	for _, object := range objects {
+		switch object.(type) {
+		case *cassandrav1.CassandraRole, *cassandrav1.CassandraKeyspace, *cassandrav1.CassandraBackup:
+			cassandraObjects = append(cassandraObjects, object)
+		default:
+			kubeObjects = append(kubeObjects, object)
+		}
+	}
+	f := &fixture{
+		t:          t,
+		kubeClient: kubefake.NewSimpleClientset(kubeObjects...),
+		client:     fake.NewSimpleClientset(append(cassandraObjects, cc)...),
+		recorder:   record.NewFakeRecorder(100),
+		nodes:      map[string]*admin.Fake{},
+		store:      backup.NewFake(),
+	}
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(f.kubeClient, 0)
+	informerFactory := informers.NewSharedInformerFactory(f.client, 0)
+	f.controller = NewController(&rest.Config{}, f.kubeClient, testNamespace, "cassandra:3.11", 0, f.client, kubeInformerFactory, informerFactory)
+	f.controller.recorder = f.recorder
+	f.controller.adminClient = func(podName string) (admin.Client, error) {
+		return f.node(podName), nil
+	}
+	f.controller.exec = func(podName string, cmd []string, stdin io.Reader, stdout io.Writer) (string, error) {
+		f.commands = append(f.commands, podName+": "+strings.Join(cmd, " "))
+		if f.cqlsh == nil {
+			return "", nil
+		}
+		out, stderr, err := f.cqlsh(podName, cmd)
+		// the output streamed to a closed pipe fails the command
+		if _, writeErr := io.WriteString(stdout, out); writeErr != nil && err == nil {
+			return stderr, writeErr
+		}
+		return stderr, err
+	}
+	f.controller.objectStore = func(namespace string, storage cassandrav1.BackupStorage) (backup.ObjectStore, error) {
+		return f.store, nil
+	}
+
+	informerFactory.Cassandra().V1().CassandraClusters().Informer().GetIndexer().Add(cc)
+	for _, object := range objects {
+		var err error
+		switch o := object.(type) {
+		case *corev1.Pod:
+			err = kubeInformerFactory.Core().V1().Pods().Informer().GetIndexer().Add(o)
+		case *appsv1.StatefulSet:
+			err = kubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer().Add(o)
+		case *corev1.Service:
+			err = kubeInformerFactory.Core().V1().Services().Informer().GetIndexer().Add(o)
+		case *policyv1beta1.PodDisruptionBudget:
+			err = kubeInformerFactory.Policy().V1beta1().PodDisruptionBudgets().Informer().GetIndexer().Add(o)
+		case *cassandrav1.CassandraRole:
+			err = informerFactory.Cassandra().V1().CassandraRoles().Informer().GetIndexer().Add(o)
+		case *cassandrav1.CassandraKeyspace:
+			err = informerFactory.Cassandra().V1().CassandraKeyspaces().Informer().GetIndexer().Add(o)
+		case *cassandrav1.CassandraBackup:
+			err = informerFactory.Cassandra().V1().CassandraBackups().Informer().GetIndexer().Add(o)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+// node returns the fake Cassandra node of the pod
+func (f *fixture) node(podName string) *admin.Fake {
+	node, ok := f.nodes[podName]
+	if !ok {
+		node = admin.NewFake(podName)
+		f.nodes[podName] = node
+	}
+	return node
+}
+
+// events returns the events recorded so far
+func (f *fixture) events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-f.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func newCassandraCluster(name string) *cassandrav1.CassandraCluster {
+	nbNodes := int32(3)
+	return &cassandrav1.CassandraCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			UID:       "uid-" + name,
+		},
+		Spec: cassandrav1.CassandraClusterSpec{
+			BaseImage: "cassandra:3.11",
+			NbNodes:   &nbNodes,
+		},
+	}
+}
+
+func TestCreateOrUpdate(t *testing.T) {
+	cc := newCassandraCluster("test")
+	legacy := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: testNamespace, Labels: map[string]string{"cassandraCluster": "test", "team": "db"}},
+		Data:       map[string]string{"key": "old"},
+	}
+	foreign := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foreign", Namespace: testNamespace}}
+	f := newFixture(t, cc, legacy, foreign)
+	desired := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"cassandraCluster": "test"},
+				OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+			},
+			Data: map[string]string{"key": "new"},
+		}
+	}
+	client := f.controller.configMapClient()
+	for _, name := range []string{"created", "legacy"} {
+		cm := desired(name)
+		if err := f.controller.createOrUpdate(cc, client, cm, mergeConfigMap(cm)); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := f.kubeClient.CoreV1().ConfigMaps(testNamespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !metav1.IsControlledBy(updated, cc) || updated.Data["key"] != "new" {
+			t.Errorf("%s: got %+v, want the configmap of the cluster", name, updated)
+		}
+	}
+	if cm, _ := f.kubeClient.CoreV1().ConfigMaps(testNamespace).Get("legacy", metav1.GetOptions{}); cm.Labels["team"] != "db" {
+		t.Error("the labels added by others are removed")
+	}
+
+	// the configmap is up to date
+	actions := len(f.kubeClient.Actions())
+	cm := desired("legacy")
+	if err := f.controller.createOrUpdate(cc, client, cm, mergeConfigMap(cm)); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range f.kubeClient.Actions()[actions:] {
+		if action.GetVerb() != "get" {
+			t.Errorf("unexpected %s of the configmap up to date", action.GetVerb())
+		}
+	}
+
+	cm = desired("foreign")
+	if err := f.controller.createOrUpdate(cc, client, cm, mergeConfigMap(cm)); err == nil {
+		t.Error("the configmap not generated for the cluster is updated")
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+ErrResourceExists) {
+		t.Errorf("got events %v, want an %s warning", events, ErrResourceExists)
+	}
+}
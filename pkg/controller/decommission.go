@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+// decommissionNode removes the last node of the statefulset from the ring before the statefulset is scaled down.
+// The decommission runs in the background on the node and its progress is recorded in the status of the
+// CassandraCluster so it's resumed after a restart of the operator. It returns true when the node left the ring
+func (c *Controller) decommissionNode(cc *cassandrav1.CassandraCluster, sts *v1.StatefulSet) (bool, error) {
+	podName := fmt.Sprintf("%s-%d", sts.Name, *sts.Spec.Replicas-1)
+	d := cc.Status.Decommission
+	if d == nil || d.Pod != podName {
+		d = &cassandrav1.DecommissionStatus{
+			Pod:         podName,
+			StatefulSet: sts.Name,
+			Phase:       cassandrav1.DecommissionRunning,
+			StartTime:   metav1.Now(),
+		}
+		cc.Status.Decommission = d
+		if err := c.persistStatus(cc); err != nil {
+			return false, err
+		}
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "Decommissioning", "Decommissioning node %s before scaling down", podName)
+	}
+	if d.Phase == cassandrav1.DecommissionDone {
+		return true, nil
+	}
+	if d.HostID == "" {
+		hostID, err := c.nodeHostID(cc, d.Pod)
+		if err != nil {
+			return false, err
+		}
+		d.HostID = hostID
+		if err := c.persistStatus(cc); err != nil {
+			return false, err
+		}
+	}
+
+	nodes, err := c.ringStatus(cc, d.Pod)
+	if err != nil {
+		return false, err
+	}
+	node := findHostID(nodes, d.HostID)
+	if node == nil {
+		glog.Infof("node %s of CassandraCluster %s left the ring", d.Pod, cc.Name)
+		d.Phase = cassandrav1.DecommissionDone
+		if err := c.persistStatus(cc); err != nil {
+			return false, err
+		}
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "Decommissioned", "Node %s left the ring", d.Pod)
+		return true, nil
+	}
+	if node.Code() != "UN" {
+		glog.V(2).Infof("node %s of CassandraCluster %s is leaving the ring (%s)", d.Pod, cc.Name, node.Code())
+		return false, nil
+	}
+	// the decommission is issued once to each instance of the pod, a restart of the node stops it
+	pod, err := c.podLister.Pods(c.namespace).Get(d.Pod)
+	if err != nil {
+		return false, err
+	}
+	instance := podInstance(pod)
+	if d.Issued == instance {
+		glog.V(2).Infof("waiting for node %s of CassandraCluster %s to start leaving the ring", d.Pod, cc.Name)
+		return false, nil
+	}
+	glog.Infof("starting the decommission of node %s of CassandraCluster %s", d.Pod, cc.Name)
+	client, err := c.adminClient(d.Pod)
+	if err != nil {
+		return false, err
+	}
+	if err := client.Decommission(); err != nil {
+		return false, fmt.Errorf("could not start the decommission of %s: %v", d.Pod, err)
+	}
+	d.Issued = instance
+	return false, c.persistStatus(cc)
+}
+
+// nodeHostID returns the host ID of the node of the pod, from the ring members or from the node itself
+func (c *Controller) nodeHostID(cc *cassandrav1.CassandraCluster, podName string) (string, error) {
+	if member := findRingMember(cc.Status.RingMembers, podName); member != nil && member.HostID != "" {
+		return member.HostID, nil
+	}
+	client, err := c.adminClient(podName)
+	if err != nil {
+		return "", err
+	}
+	info, err := client.Info()
+	if err != nil {
+		return "", fmt.Errorf("could not get the host ID of node %s: %v", podName, err)
+	}
+	if info.HostID == "" {
+		return "", fmt.Errorf("unknown host ID for node %s, cannot check if it left the ring", podName)
+	}
+	return info.HostID, nil
+}
+
+// podInstance identifies a run of the containers of the pod
+func podInstance(pod *corev1.Pod) string {
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return fmt.Sprintf("%s/%d", pod.UID, restarts)
+}
+
+// getNodeRingState returns the state (UN, UL, DN...) of the node with the given IP in the ring, as seen by
+// another ready node of the cluster. It returns an empty state if the node isn't part of the ring
+func (c *Controller) getNodeRingState(cc *cassandrav1.CassandraCluster, ip string, exclude string) (string, error) {
+	nodes, err := c.ringStatus(cc, exclude)
+	if err != nil {
+		return "", err
+	}
+	if node := admin.FindNode(nodes, ip); node != nil {
+		return node.Code(), nil
+	}
+	return "", nil
+}
+
+// ringStatus returns the state of the nodes of the ring as seen by the first ready node of the cluster answering,
+// other than the excluded pod
+func (c *Controller) ringStatus(cc *cassandrav1.CassandraCluster, exclude string) ([]admin.NodeStatus, error) {
+	pods, err := c.podLister.Pods(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if pod.Name == exclude || podState(pod) != cassandrav1.NodeStateReady {
+			continue
+		}
+		client, err := c.adminClient(pod.Name)
+		if err != nil {
+			glog.Warningf("could not get the ring state from %s: %v", pod.Name, err)
+			continue
+		}
+		nodes, err := client.Status()
+		if err != nil {
+			glog.Warningf("could not get the ring state from %s: %v", pod.Name, err)
+			continue
+		}
+		return nodes, nil
+	}
+	return nil, fmt.Errorf("no ready node to get the ring state of CassandraCluster %s", cc.Name)
+}
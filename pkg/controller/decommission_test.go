@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+func countCalls(node *admin.Fake, call string) int {
+	count := 0
+	for _, c := range node.Calls {
+		if c == call {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDecommissionNode(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Status.RingMembers = []cassandrav1.RingMember{{Pod: "test-dc1-rack1-1", Address: "10.0.0.2", HostID: "host1"}}
+	sts := newRackStatefulSet("test-dc1-rack1", 2)
+	seed := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	leaving := newNodePod(cc, "test-dc1-rack1-1", "cassandra:3.11", "", true)
+	leaving.UID = "uid-1"
+	f := newFixture(t, cc, sts, seed, leaving)
+	ring := []admin.NodeStatus{
+		{Address: "10.0.0.1", HostID: "host0", Up: true, State: admin.NodeNormal},
+		{Address: "10.0.0.2", HostID: "host1", Up: true, State: admin.NodeNormal},
+	}
+	f.node(seed.Name).Nodes = ring
+
+	done, err := f.controller.decommissionNode(cc, sts)
+	if err != nil || done {
+		t.Fatalf("got %t, %v, want the decommission started", done, err)
+	}
+	d := cc.Status.Decommission
+	if d == nil || d.Pod != leaving.Name || d.HostID != "host1" || d.Issued != "uid-1/0" {
+		t.Fatalf("got %+v, want the decommission of %s issued", d, leaving.Name)
+	}
+	if count := countCalls(f.node(leaving.Name), "decommission"); count != 1 {
+		t.Errorf("got %d decommissions, want 1", count)
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Normal Decommissioning") {
+		t.Errorf("got events %v, want a Decommissioning event", events)
+	}
+
+	// the node didn't start leaving yet
+	if done, err := f.controller.decommissionNode(cc, sts); err != nil || done {
+		t.Fatalf("got %t, %v, want the decommission waited for", done, err)
+	}
+	if count := countCalls(f.node(leaving.Name), "decommission"); count != 1 {
+		t.Errorf("got %d decommissions, want the decommission issued once", count)
+	}
+
+	// the node restarted with a new IP, the decommission is issued again
+	leaving.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "cassandra", RestartCount: 1}}
+	ring[1].Address = "10.0.0.3"
+	if done, err := f.controller.decommissionNode(cc, sts); err != nil || done {
+		t.Fatalf("got %t, %v, want the decommission restarted", done, err)
+	}
+	if count := countCalls(f.node(leaving.Name), "decommission"); count != 2 || d.Issued != "uid-1/1" {
+		t.Errorf("got %d decommissions issued to %s, want the restarted node decommissioned", count, d.Issued)
+	}
+
+	// the node streams its data
+	ring[1].State = admin.NodeLeaving
+	if done, err := f.controller.decommissionNode(cc, sts); err != nil || done {
+		t.Fatalf("got %t, %v, want the node leaving", done, err)
+	}
+
+	// the node left the ring
+	f.node(seed.Name).Nodes = ring[:1]
+	if done, err := f.controller.decommissionNode(cc, sts); err != nil || !done {
+		t.Fatalf("got %t, %v, want the node decommissioned", done, err)
+	}
+	if d.Phase != cassandrav1.DecommissionDone {
+		t.Errorf("got phase %s, want %s", d.Phase, cassandrav1.DecommissionDone)
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Normal Decommissioned") {
+		t.Errorf("got events %v, want a Decommissioned event", events)
+	}
+}
+
+func TestDecommissionNodeHostID(t *testing.T) {
+	cc := newCassandraCluster("test")
+	sts := newRackStatefulSet("test-dc1-rack1", 2)
+	seed := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	leaving := newNodePod(cc, "test-dc1-rack1-1", "cassandra:3.11", "", true)
+	f := newFixture(t, cc, sts, seed, leaving)
+	f.node(seed.Name).Nodes = []admin.NodeStatus{
+		{Address: "10.0.0.1", HostID: "host0", Up: true, State: admin.NodeNormal},
+		{Address: "10.0.0.2", HostID: "host1", Up: true, State: admin.NodeNormal},
+	}
+
+	// the node isn't a ring member yet, its host ID comes from the node
+	f.node(leaving.Name).Err = &admin.Error{Operation: "info", Message: "connection refused"}
+	if _, err := f.controller.decommissionNode(cc, sts); err == nil {
+		t.Fatal("got no error without the host ID of the node")
+	}
+	if countCalls(f.node(leaving.Name), "decommission") != 0 {
+		t.Error("the node is decommissioned without its host ID")
+	}
+	f.node(leaving.Name).Err = nil
+	f.node(leaving.Name).HostID = "host1"
+	if _, err := f.controller.decommissionNode(cc, sts); err != nil {
+		t.Fatal(err)
+	}
+	if cc.Status.Decommission.HostID != "host1" || countCalls(f.node(leaving.Name), "decommission") != 1 {
+		t.Errorf("got %+v, want the decommission of host1 started", cc.Status.Decommission)
+	}
+}
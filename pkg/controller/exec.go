@@ -6,17 +6,43 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"fmt"
 	"bytes"
+	"io"
 	"k8s.io/api/core/v1"
+	"time"
+
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
 )
 
+// adminTimeout is the maximum duration of a management operation of a node, like the drain
+const adminTimeout = 5 * time.Minute
+
 func (c *Controller) ExecCmd(podName string,cmd [] string) (string,string,error) {
+	var stdout bytes.Buffer
+	stderr, err := c.ExecCmdStream(podName, cmd, &stdout)
+	return stdout.String(), stderr, err
+}
+
+// ExecCmdStream runs the command in the pod and streams its standard output to the writer.
+// It's used for large outputs like files which don't fit in memory
+func (c *Controller) ExecCmdStream(podName string,cmd [] string, stdout io.Writer) (string,error) {
+	return c.exec(podName, cmd, nil, stdout)
+}
+
+// ExecCmdInput runs the command in the pod with the content of the reader as standard input
+func (c *Controller) ExecCmdInput(podName string,cmd [] string, stdin io.Reader) (string,string,error) {
+	var stdout bytes.Buffer
+	stderr, err := c.exec(podName, cmd, stdin, &stdout)
+	return stdout.String(), stderr, err
+}
+
+func (c *Controller) execCmd(podName string,cmd [] string, stdin io.Reader, stdout io.Writer) (string,error) {
 	// get the pod from the name
 	pod, err := c.kubeClientset.CoreV1().Pods(c.namespace).Get(podName, metav1.GetOptions{})
 	if err != nil {
-		return "", "",fmt.Errorf("could not get pod info: %v", err)
+		return "",fmt.Errorf("could not get pod info: %v", err)
 	}
 	if len(pod.Spec.Containers) != 1 {
-		return "", "", fmt.Errorf("could not determine which container to use")
+		return "", fmt.Errorf("could not determine which container to use")
 	}
 
 	// build the remoteexec
@@ -28,7 +54,7 @@ func (c *Controller) ExecCmd(podName string,cmd [] string) (string,string,error)
 	req.VersionedParams(&v1.PodExecOptions{
 		Container: pod.Spec.Containers[0].Name,
 		Command:   cmd,
-		Stdin:	   false,
+		Stdin:	   stdin != nil,
 		Stdout:    true,
 		Stderr:    true,
 		TTY:       false,
@@ -36,15 +62,34 @@ func (c *Controller) ExecCmd(podName string,cmd [] string) (string,string,error)
 
 	exec, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
 	if err != nil {
-		return "", "",fmt.Errorf("could not init remote executor: %v", err)
+		return "",fmt.Errorf("could not init remote executor: %v", err)
 	}
 
-	var stdout, stderr bytes.Buffer
+	var stderr bytes.Buffer
 	err = exec.Stream(remotecommand.StreamOptions{
-		Stdout:             &stdout,
+		Stdin:              stdin,
+		Stdout:             stdout,
 		Stderr:             &stderr,
 		Tty:                false,
 	})
 
-	return stdout.String(), stderr.String(),err
+	return stderr.String(),err
+}
+
+// newAdminClient returns the client managing the Cassandra node of the pod, through its Jolokia agent when the
+// operator is configured with its port, or with nodetool run in the pod
+func (c *Controller) newAdminClient(podName string) (admin.Client, error) {
+	if c.jolokiaPort == 0 {
+		return admin.NewExecClient(func(cmd []string) (string, string, error) {
+			return c.ExecCmd(podName, cmd)
+		}), nil
+	}
+	pod, err := c.podLister.Pods(c.namespace).Get(podName)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.PodIP == "" {
+		return nil, fmt.Errorf("pod %s has no IP", podName)
+	}
+	return admin.NewJolokiaClient(fmt.Sprintf("http://%s:%d/jolokia/", pod.Status.PodIP, c.jolokiaPort), adminTimeout), nil
 }
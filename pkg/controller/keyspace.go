@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+// identifierRegexp matches the unquoted CQL identifiers of the keyspaces and tables
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,47}$`)
+
+// syncKeyspace applies a CassandraKeyspace to its cluster and repairs it when its replication increases
+func (c *Controller) syncKeyspace(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+	k, err := c.cassandraKeyspacesLister.CassandraKeyspaces(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if k.Status.Phase == cassandrav1.KeyspaceReady && k.Status.ObservedGeneration == k.Generation {
+		return nil
+	}
+	k = k.DeepCopy()
+
+	cc, err := c.CassandraClustersLister.CassandraClusters(k.Namespace).Get(k.Spec.Cluster)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.setKeyspacePhase(k, cassandrav1.KeyspacePending, fmt.Sprintf("CassandraCluster %s not found", k.Spec.Cluster))
+		}
+		return err
+	}
+	// requeued by the informer resync
+	if cc.Status.Phase != cassandrav1.ClusterPhaseRunning || !clusterStable(cc) ||
+		(passwordAuthentication(cc) && !cc.Status.AuthBootstrapped) {
+		return c.setKeyspacePhase(k, cassandrav1.KeyspacePending, fmt.Sprintf("waiting for CassandraCluster %s to be running", cc.Name))
+	}
+	factors, err := keyspaceReplication(cc, k)
+	if err != nil {
+		if k.Status.Phase != cassandrav1.KeyspaceFailed || k.Status.Message != err.Error() {
+			c.recorder.Event(k, corev1.EventTypeWarning, "KeyspaceFailed", err.Error())
+		}
+		return c.setKeyspacePhase(k, cassandrav1.KeyspaceFailed, err.Error())
+	}
+
+	pod, err := c.readyPod(cc)
+	if err != nil {
+		return err
+	}
+	replication, err := c.getReplication(cc)
+	if err != nil {
+		return err
+	}
+	name = keyspaceName(k)
+	current, exists := replication[name]
+	durableWrites := k.Spec.DurableWrites == nil || *k.Spec.DurableWrites
+	options := fmt.Sprintf("replication = %s AND durable_writes = %t", replicationCQL(k.Spec.Replication.Strategy, factors), durableWrites)
+	statements := []string{
+		fmt.Sprintf("CREATE KEYSPACE IF NOT EXISTS %s WITH %s", name, options),
+		fmt.Sprintf("ALTER KEYSPACE %s WITH %s", name, options),
+	}
+	for _, table := range k.Spec.Tables {
+		statements = append(statements, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s %s", name, table.Name, table.Schema))
+	}
+	glog.Infof("applying CassandraKeyspace %s to CassandraCluster %s", k.Name, cc.Name)
+	if _, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", strings.Join(statements, "; "))); err != nil {
+		err = fmt.Errorf("could not apply the keyspace %s: %v %s", name, err, stderr)
+		c.recorder.Event(k, corev1.EventTypeWarning, "KeyspaceFailed", err.Error())
+		k.Status.Phase = cassandrav1.KeyspaceFailed
+		k.Status.Message = err.Error()
+		if err := c.persistKeyspaceStatus(k); err != nil {
+			return err
+		}
+		// retried with the backoff of the queue
+		return err
+	}
+
+	// the status of the keyspace is updated once the repair is recorded
+	if (exists && replicationIncreased(current, factors)) ||
+		(k.Status.Replication != nil && replicationIncreased(k.Status.Replication, factors)) {
+		cc = cc.DeepCopy()
+		requestKeyspaceRepair(cc, repairTriggerReplication, name)
+		if err := c.persistStatus(cc); err != nil {
+			return err
+		}
+		c.recorder.Eventf(k, corev1.EventTypeNormal, "RepairRequested", "Replication factor of keyspace %s increased, repair requested on CassandraCluster %s", name, cc.Name)
+	}
+	c.recorder.Eventf(k, corev1.EventTypeNormal, "KeyspaceSynced", "Keyspace %s applied to CassandraCluster %s", name, cc.Name)
+	k.Status.Phase = cassandrav1.KeyspaceReady
+	k.Status.ObservedGeneration = k.Generation
+	k.Status.Replication = factors
+	k.Status.Message = ""
+	return c.persistKeyspaceStatus(k)
+}
+
+// keyspaceReplication returns the replication factors of the keyspace by datacenter
+func keyspaceReplication(cc *cassandrav1.CassandraCluster, k *cassandrav1.CassandraKeyspace) (map[string]int32, error) {
+	name := keyspaceName(k)
+	if !identifierRegexp.MatchString(name) {
+		return nil, fmt.Errorf("%q isn't a valid keyspace name", name)
+	}
+	for _, table := range k.Spec.Tables {
+		if !identifierRegexp.MatchString(table.Name) {
+			return nil, fmt.Errorf("%q isn't a valid table name", table.Name)
+		}
+		if strings.TrimSpace(table.Schema) == "" {
+			return nil, fmt.Errorf("table %s has no schema", table.Name)
+		}
+	}
+	nodes := datacenterNodes(cc)
+	factors := map[string]int32{}
+	switch k.Spec.Replication.Strategy {
+	case cassandrav1.SimpleStrategy:
+		factors[""] = k.Spec.Replication.ReplicationFactor
+	case cassandrav1.NetworkTopologyStrategy, "":
+		for dc, rf := range k.Spec.Replication.Datacenters {
+			if _, ok := nodes[dc]; !ok || dc == "" {
+				return nil, fmt.Errorf("CassandraCluster %s has no datacenter %q", cc.Name, dc)
+			}
+			factors[dc] = rf
+		}
+	default:
+		return nil, fmt.Errorf("unknown replication strategy %s", k.Spec.Replication.Strategy)
+	}
+	if len(factors) == 0 {
+		return nil, fmt.Errorf("keyspace %s has no replication factor", name)
+	}
+	for dc, rf := range factors {
+		if rf < 1 {
+			return nil, fmt.Errorf("replication factor %d must be positive", rf)
+		}
+		if rf > nodes[dc] {
+			if dc == "" {
+				return nil, fmt.Errorf("replication factor %d is larger than the %d nodes of CassandraCluster %s", rf, nodes[dc], cc.Name)
+			}
+			return nil, fmt.Errorf("replication factor %d is larger than the %d nodes of datacenter %s", rf, nodes[dc], dc)
+		}
+	}
+	return factors, nil
+}
+
+// replicationIncreased returns true if a datacenter has more replicas than before
+func replicationIncreased(before map[string]int32, after map[string]int32) bool {
+	for dc, rf := range after {
+		if rf > before[dc] {
+			return true
+		}
+	}
+	return false
+}
+
+// replicationCQL returns the replication map of a keyspace
+func replicationCQL(strategy cassandrav1.ReplicationStrategy, factors map[string]int32) string {
+	if strategy == cassandrav1.SimpleStrategy {
+		return fmt.Sprintf("{'class': 'SimpleStrategy', 'replication_factor': %d}", factors[""])
+	}
+	var options []string
+	for dc, rf := range factors {
+		options = append(options, fmt.Sprintf("'%s': %d", dc, rf))
+	}
+	sort.Strings(options)
+	return fmt.Sprintf("{'class': 'NetworkTopologyStrategy', %s}", strings.Join(options, ", "))
+}
+
+// setKeyspacePhase updates the status of the keyspace when it changes
+func (c *Controller) setKeyspacePhase(k *cassandrav1.CassandraKeyspace, phase cassandrav1.KeyspacePhase, message string) error {
+	if k.Status.Phase == phase && k.Status.Message == message {
+		return nil
+	}
+	glog.Infof("CassandraKeyspace %s: %s", k.Name, message)
+	k.Status.Phase = phase
+	k.Status.Message = message
+	return c.persistKeyspaceStatus(k)
+}
+
+func (c *Controller) persistKeyspaceStatus(k *cassandrav1.CassandraKeyspace) error {
+	_, err := c.cassandraClusterClientset.CassandraV1().CassandraKeyspaces(k.Namespace).UpdateStatus(k)
+	return err
+}
+
+// keyspaceName returns the name of the keyspace in Cassandra
+func keyspaceName(k *cassandrav1.CassandraKeyspace) string {
+	if k.Spec.KeyspaceName != "" {
+		return strings.ToLower(k.Spec.KeyspaceName)
+	}
+	return strings.ToLower(k.Name)
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func newCassandraKeyspace(name string, cluster string, factors map[string]int32) *cassandrav1.CassandraKeyspace {
+	return &cassandrav1.CassandraKeyspace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Generation: 1},
+		Spec: cassandrav1.CassandraKeyspaceSpec{
+			Cluster:     cluster,
+			Replication: cassandrav1.KeyspaceReplication{Datacenters: factors},
+		},
+	}
+}
+
+func TestSyncKeyspace(t *testing.T) {
+	cc := newRunningCluster("test")
+	cc.Status.AuthBootstrapped = true
+	pod := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	pod.Status.PodIP = "10.0.0.1"
+	k := newCassandraKeyspace("orders", "test", map[string]int32{"dc1": 3})
+	k.Spec.Tables = []cassandrav1.CassandraTable{{Name: "items", Schema: "(id uuid PRIMARY KEY)"}}
+	f := newFixture(t, cc, pod, k)
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		if strings.Contains(strings.Join(cmd, " "), "system_schema.keyspaces") {
+			return replicationOutput(map[string]string{
+				"orders": "{'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'dc1': '1'}",
+			}), "", nil
+		}
+		return "", "", nil
+	}
+
+	if err := f.controller.syncKeyspace(testNamespace + "/orders"); err != nil {
+		t.Fatal(err)
+	}
+	options := "replication = {'class': 'NetworkTopologyStrategy', 'dc1': 3} AND durable_writes = true"
+	want := []string{
+		"SELECT keyspace_name, replication FROM system_schema.keyspaces",
+		"CREATE KEYSPACE IF NOT EXISTS orders WITH " + options + "; ALTER KEYSPACE orders WITH " + options +
+			"; CREATE TABLE IF NOT EXISTS orders.items (id uuid PRIMARY KEY)",
+	}
+	if len(f.commands) != len(want) {
+		t.Fatalf("got commands %v, want %d commands", f.commands, len(want))
+	}
+	for i, command := range f.commands {
+		if !strings.HasSuffix(command, want[i]) {
+			t.Errorf("got command %q, want %q", command, want[i])
+		}
+	}
+	k, err := f.client.CassandraV1().CassandraKeyspaces(testNamespace).Get("orders", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Status.Phase != cassandrav1.KeyspaceReady || k.Status.ObservedGeneration != 1 || k.Status.Replication["dc1"] != 3 {
+		t.Errorf("got %+v, want the keyspace ready", k.Status)
+	}
+	cc, err = f.client.CassandraV1().CassandraClusters(testNamespace).Get("test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc.Status.PendingRepair != repairTriggerReplication || len(cc.Status.PendingRepairKeyspaces) != 1 || cc.Status.PendingRepairKeyspaces[0] != "orders" {
+		t.Errorf("got %+v, want a repair of the keyspace", cc.Status)
+	}
+	if events := f.events(); len(events) != 2 || !strings.HasPrefix(events[0], "Normal RepairRequested") || !strings.HasPrefix(events[1], "Normal KeyspaceSynced") {
+		t.Errorf("got events %v, want RepairRequested and KeyspaceSynced events", events)
+	}
+}
+
+func TestSyncKeyspaceWaitsForCluster(t *testing.T) {
+	cc := newRunningCluster("test")
+	cc.Status.ReadyNodes = 2
+	k := newCassandraKeyspace("orders", "test", map[string]int32{"dc1": 3})
+	f := newFixture(t, cc, k)
+	if err := f.controller.syncKeyspace(testNamespace + "/orders"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.commands) != 0 {
+		t.Errorf("got commands %v while a node isn't ready", f.commands)
+	}
+	k, err := f.client.CassandraV1().CassandraKeyspaces(testNamespace).Get("orders", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Status.Phase != cassandrav1.KeyspacePending {
+		t.Errorf("got phase %s, want %s", k.Status.Phase, cassandrav1.KeyspacePending)
+	}
+}
+
+func TestKeyspaceReplication(t *testing.T) {
+	cc := newRunningCluster("test")
+	tests := []struct {
+		name        string
+		replication cassandrav1.KeyspaceReplication
+		tables      []cassandrav1.CassandraTable
+		err         bool
+	}{
+		{"orders", cassandrav1.KeyspaceReplication{Datacenters: map[string]int32{"dc1": 3}}, nil, false},
+		{"orders", cassandrav1.KeyspaceReplication{Strategy: cassandrav1.SimpleStrategy, ReplicationFactor: 2}, nil, false},
+		{"orders", cassandrav1.KeyspaceReplication{Datacenters: map[string]int32{"dc1": 4}}, nil, true},
+		{"orders", cassandrav1.KeyspaceReplication{Datacenters: map[string]int32{"dc2": 1}}, nil, true},
+		{"orders", cassandrav1.KeyspaceReplication{Datacenters: map[string]int32{"dc1": 0}}, nil, true},
+		{"orders", cassandrav1.KeyspaceReplication{}, nil, true},
+		{"orders", cassandrav1.KeyspaceReplication{Strategy: "LocalStrategy"}, nil, true},
+		{"orders-v2", cassandrav1.KeyspaceReplication{Datacenters: map[string]int32{"dc1": 3}}, nil, true},
+		{"orders", cassandrav1.KeyspaceReplication{Datacenters: map[string]int32{"dc1": 3}}, []cassandrav1.CassandraTable{{Name: "items"}}, true},
+	}
+	for _, test := range tests {
+		k := newCassandraKeyspace(test.name, "test", nil)
+		k.Spec.Replication = test.replication
+		k.Spec.Tables = test.tables
+		if _, err := keyspaceReplication(cc, k); (err != nil) != test.err {
+			t.Errorf("keyspace %s %+v: got error %v", test.name, test.replication, err)
+		}
+	}
+}
+
+func TestReplicationIncreased(t *testing.T) {
+	if !replicationIncreased(map[string]int32{"dc1": 3}, map[string]int32{"dc1": 3, "dc2": 1}) {
+		t.Error("a new datacenter doesn't increase the replication")
+	}
+	if replicationIncreased(map[string]int32{"dc1": 3, "dc2": 3}, map[string]int32{"dc1": 2}) {
+		t.Error("a lower replication increases the replication")
+	}
+}
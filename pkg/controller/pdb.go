@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	// default number of nodes of the cluster, or of each rack, which can be disrupted at the same time
+	defaultMaxUnavailable = 1
+)
+
+// reconcilePodDisruptionBudgets limits the voluntary disruptions of the nodes with a budget per rack, or a single one,
+// and blocks them while the cluster changes
+func (c *Controller) reconcilePodDisruptionBudgets(cc *cassandrav1.CassandraCluster) error {
+	maxUnavailable := int32(defaultMaxUnavailable)
+	if cc.Spec.DisruptionBudget.MaxUnavailable > 0 {
+		maxUnavailable = cc.Spec.DisruptionBudget.MaxUnavailable
+	}
+	if reason := disruptionsBlocked(cc); reason != "" {
+		glog.V(2).Infof("disruptions of CassandraCluster %s blocked, %s", cc.Name, reason)
+		maxUnavailable = 0
+	}
+
+	var pdbs []*policyv1beta1.PodDisruptionBudget
+	racks := getRacks(cc)
+	if len(racks) > 1 {
+		for _, rack := range racks {
+			pdbs = append(pdbs, buildPodDisruptionBudget(cc, rackStatefulSetName(cc, rack), maxUnavailable, map[string]string{
+				"cassandraCluster": cc.Name,
+				"cassandraDC":      rack.DC.Name,
+				"cassandraRack":    rack.Rack.Name,
+			}))
+		}
+	} else {
+		pdbs = append(pdbs, buildPodDisruptionBudget(cc, cc.Name, maxUnavailable, map[string]string{
+			"cassandraCluster": cc.Name,
+		}))
+	}
+
+	desired := map[string]bool{}
+	for _, pdb := range pdbs {
+		desired[pdb.Name] = true
+		if err := c.createOrUpdatePodDisruptionBudget(cc, pdb); err != nil {
+			return err
+		}
+	}
+	// the budgets of the removed racks, or of the whole cluster once it has several racks
+	current, err := c.pdbsLister.PodDisruptionBudgets(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+	if err != nil {
+		return err
+	}
+	for _, pdb := range current {
+		if desired[pdb.Name] || !metav1.IsControlledBy(pdb, cc) {
+			continue
+		}
+		glog.Infof("deleting the PodDisruptionBudget %s of CassandraCluster %s", pdb.Name, cc.Name)
+		err := c.kubeClientset.PolicyV1beta1().PodDisruptionBudgets(c.namespace).Delete(pdb.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// createOrUpdatePodDisruptionBudget creates the budget or replaces it when it differs from the desired one
+func (c *Controller) createOrUpdatePodDisruptionBudget(cc *cassandrav1.CassandraCluster, pdb *policyv1beta1.PodDisruptionBudget) error {
+	return c.createOrUpdate(cc, c.pdbClient(cc), pdb, func(obj object) {
+		obj.(*policyv1beta1.PodDisruptionBudget).Spec = pdb.Spec
+	})
+}
+
+// pdbClient returns the client of the budgets, read from the cache. The spec of a budget can't be updated before
+// Kubernetes 1.15 so it's deleted and created again
+func (c *Controller) pdbClient(cc *cassandrav1.CassandraCluster) objectClient {
+	client := c.kubeClientset.PolicyV1beta1().PodDisruptionBudgets(c.namespace)
+	create := func(obj object) error {
+		_, err := client.Create(obj.(*policyv1beta1.PodDisruptionBudget))
+		if errors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	return objectClient{
+		get: func(name string) (object, error) {
+			return c.pdbsLister.PodDisruptionBudgets(c.namespace).Get(name)
+		},
+		create: create,
+		update: func(obj object) error {
+			pdb := obj.(*policyv1beta1.PodDisruptionBudget)
+			glog.Infof("replacing the PodDisruptionBudget %s of CassandraCluster %s, %d nodes can be disrupted", pdb.Name, cc.Name, pdb.Spec.MaxUnavailable.IntValue())
+			// the cache may not have seen the budget created by the previous sync yet
+			err := client.Delete(pdb.Name, &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &pdb.UID}})
+			if errors.IsConflict(err) {
+				return nil
+			}
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			pdb.ResourceVersion, pdb.UID = "", ""
+			return create(pdb)
+		},
+	}
+}
+
+// buildPodDisruptionBudget builds the budget of the pods matching the selector
+func buildPodDisruptionBudget(cc *cassandrav1.CassandraCluster, name string, maxUnavailable int32, selector map[string]string) *policyv1beta1.PodDisruptionBudget {
+	value := intstr.FromInt(int(maxUnavailable))
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				"operatorVersion": cassandrav1.SchemeGroupVersion.Version,
+			},
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role":             "cassandraCluster",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &value,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selector,
+			},
+		},
+	}
+}
+
+// disruptionsBlocked returns why the nodes can't be disrupted, or an empty string when they can
+func disruptionsBlocked(cc *cassandrav1.CassandraCluster) string {
+	switch {
+	case cc.Status.Decommission != nil:
+		return "a node is leaving the ring"
+	case cc.Status.Replacement != nil:
+		return "a dead node is being replaced"
+	case upgradeInProgress(cc) || conditionTrue(&cc.Status, cassandrav1.ClusterUpgrading):
+		return "the nodes are being rolled"
+	case cc.Status.Repair != nil && cc.Status.Repair.Phase == cassandrav1.RepairRunning:
+		return "a repair is running"
+	case conditionTrue(&cc.Status, cassandrav1.ClusterScaling):
+		return "the number of nodes is changing"
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func TestReconcilePodDisruptionBudgets(t *testing.T) {
+	cc := newCassandraCluster("test")
+	f := newFixture(t, cc)
+	if err := f.controller.reconcilePodDisruptionBudgets(cc); err != nil {
+		t.Fatal(err)
+	}
+	pdb, err := f.kubeClient.PolicyV1beta1().PodDisruptionBudgets(testNamespace).Get("test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pdb.Spec.MaxUnavailable.IntValue() != defaultMaxUnavailable || !metav1.IsControlledBy(pdb, cc) {
+		t.Errorf("got %+v, want the budget of the cluster", pdb)
+	}
+
+	// a node leaving the ring blocks the disruptions
+	pdb.UID = "uid-pdb"
+	f = newFixture(t, cc, pdb)
+	cc.Status.Decommission = &cassandrav1.DecommissionStatus{Pod: "test-dc1-rack1-2"}
+	if err := f.controller.reconcilePodDisruptionBudgets(cc); err != nil {
+		t.Fatal(err)
+	}
+	replaced, err := f.kubeClient.PolicyV1beta1().PodDisruptionBudgets(testNamespace).Get("test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.Spec.MaxUnavailable.IntValue() != 0 {
+		t.Errorf("got %d nodes disrupted while a node leaves the ring", replaced.Spec.MaxUnavailable.IntValue())
+	}
+
+	// the racks get their own budget
+	cc.Status.Decommission = nil
+	cc.Spec.Racks = []cassandrav1.Rack{{Name: "a"}, {Name: "b"}}
+	f = newFixture(t, cc, replaced)
+	if err := f.controller.reconcilePodDisruptionBudgets(cc); err != nil {
+		t.Fatal(err)
+	}
+	pdbs, err := f.kubeClient.PolicyV1beta1().PodDisruptionBudgets(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pdb := range pdbs.Items {
+		names = append(names, pdb.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "test-dc1-a" || names[1] != "test-dc1-b" {
+		t.Errorf("got budgets %v, want the budgets of the racks", names)
+	}
+}
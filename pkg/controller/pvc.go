@@ -1,28 +1,53 @@
 package controller
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 )
 
-func (c *Controller) DeletePVC(name string) error{
+// DeletePVC deletes the data PVCs of a cluster and returns how many were deleted. The PVCs created before the
+// cassandraCluster label only have the name label
+func (c *Controller) DeletePVC(name string) (int, error){
 	pvcClient := c.kubeClientset.CoreV1().PersistentVolumeClaims(c.namespace)
-	// use the owner of the PVC to get PVC associated with cassandraCluster
 	pvcs,err := pvcClient.List(metav1.ListOptions{LabelSelector: "cassandraCluster="+name})
 	if err != nil {
-		return err
+		return 0,err
+	}
+	legacy,err := pvcClient.List(metav1.ListOptions{LabelSelector: "name="+name})
+	if err != nil {
+		return 0,err
+	}
+	names := map[string]bool{}
+	for _, pvc := range pvcs.Items {
+		names[pvc.Name] = true
+	}
+	for _, pvc := range legacy.Items {
+		if strings.HasPrefix(pvc.Name, "data-"+name+"-") {
+			names[pvc.Name] = true
+		}
 	}
 
-	nbPVC := len(pvcs.Items)
-	for i := 0 ; i < nbPVC ; i++ {
-		err := pvcClient.Delete(pvcs.Items[i].Name, &metav1.DeleteOptions{})
+	deleted := 0
+	for pvcName := range names {
+		err := pvcClient.Delete(pvcName, &metav1.DeleteOptions{})
 		if errors.IsNotFound(err) {
-			err = nil
 			continue
 		}
 		if err != nil {
-			return err
+			return deleted,err
 		}
+		deleted++
+	}
+	return deleted,nil
+}
+
+// DeleteNodePVC deletes the data PVC of a single node of a statefulset
+func (c *Controller) DeleteNodePVC(podName string) error{
+	err := c.kubeClientset.CoreV1().PersistentVolumeClaims(c.namespace).Delete("data-"+podName, &metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		err = nil
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newClusterPVC returns the data PVC of a pod with the given labels
+func newClusterPVC(podName string, labels map[string]string) *corev1.PersistentVolumeClaim {
+	pvc := newDataPVC(podName, "uid-"+podName)
+	pvc.Labels = labels
+	return pvc
+}
+
+// pvcNames returns the sorted names of the PVCs left in the namespace
+func (f *fixture) pvcNames() []string {
+	pvcs, err := f.kubeClient.CoreV1().PersistentVolumeClaims(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	var names []string
+	for _, pvc := range pvcs.Items {
+		names = append(names, pvc.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDeletePVC(t *testing.T) {
+	cc := newCassandraCluster("test")
+	f := newFixture(t, cc,
+		newClusterPVC("test-dc1-rack1-0", map[string]string{"cassandraCluster": "test"}),
+		newClusterPVC("test-dc1-rack1-1", map[string]string{"cassandraCluster": "test"}),
+		// the PVCs created before the cassandraCluster label
+		newClusterPVC("test-2", map[string]string{"name": "test"}),
+		newClusterPVC("other-0", map[string]string{"name": "test"}),
+		newClusterPVC("testing-0", map[string]string{"cassandraCluster": "testing"}),
+	)
+	// the second claim is deleted by someone else between the list and the delete
+	f.kubeClient.PrependReactor("delete", "persistentvolumeclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if name := action.(k8stesting.DeleteAction).GetName(); name == "data-test-dc1-rack1-1" {
+			return true, nil, errors.NewNotFound(corev1.Resource("persistentvolumeclaims"), name)
+		}
+		return false, nil, nil
+	})
+
+	deleted, err := f.controller.DeletePVC("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("got %d PVCs deleted, want the 2 PVCs still there", deleted)
+	}
+	if names := f.pvcNames(); len(names) != 3 || names[0] != "data-other-0" || names[2] != "data-testing-0" {
+		t.Errorf("got PVCs %v, want the PVCs of the other clusters kept", names)
+	}
+
+	// nothing is left to delete
+	f = newFixture(t, cc)
+	if deleted, err := f.controller.DeletePVC("test"); err != nil || deleted != 0 {
+		t.Errorf("got %d PVCs deleted and %v, want none", deleted, err)
+	}
+}
+
+func TestDeleteNodePVC(t *testing.T) {
+	cc := newCassandraCluster("test")
+	f := newFixture(t, cc, newDataPVC("test-dc1-rack1-0", "volume0"), newDataPVC("test-dc1-rack1-1", "volume1"))
+	if err := f.controller.DeleteNodePVC("test-dc1-rack1-0"); err != nil {
+		t.Fatal(err)
+	}
+	// the claim already gone isn't an error
+	if err := f.controller.DeleteNodePVC("test-dc1-rack1-0"); err != nil {
+		t.Fatal(err)
+	}
+	if names := f.pvcNames(); len(names) != 1 || names[0] != "data-test-dc1-rack1-1" {
+		t.Errorf("got PVCs %v, want the PVC of the other node kept", names)
+	}
+}
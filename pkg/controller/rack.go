@@ -0,0 +1,134 @@
+package controller
+
+import (
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	// name of the datacenter used when the CassandraCluster doesn't declare any
+	defaultDatacenterName = "dc1"
+	// name of the rack used when a datacenter doesn't declare any
+	defaultRackName = "rack1"
+)
+
+// cassandraRack is a rack of the cluster with the datacenter it belongs to
+type cassandraRack struct {
+	DC   cassandrav1.Datacenter
+	Rack cassandrav1.Rack
+	// Nodes is the desired number of nodes in the rack
+	Nodes int32
+}
+
+// getDatacenters returns the datacenters of the CassandraCluster. If none is declared, a default datacenter
+// is built from the racks and the number of nodes of the cluster
+func getDatacenters(cc *cassandrav1.CassandraCluster) []cassandrav1.Datacenter {
+	if len(cc.Spec.Datacenters) == 0 {
+		return []cassandrav1.Datacenter{{
+			Name:    defaultDatacenterName,
+			NbNodes: cc.Spec.NbNodes,
+			Racks:   cc.Spec.Racks,
+		}}
+	}
+	return cc.Spec.Datacenters
+}
+
+// getRacks returns all the racks of all the datacenters of the CassandraCluster
+func getRacks(cc *cassandrav1.CassandraCluster) []cassandraRack {
+	var racks []cassandraRack
+	for _, dc := range getDatacenters(cc) {
+		dcRacks := dc.Racks
+		if len(dcRacks) == 0 {
+			dcRacks = []cassandrav1.Rack{{Name: defaultRackName}}
+		}
+		nbNodes := dc.NbNodes
+		if nbNodes == nil {
+			nbNodes = cc.Spec.NbNodes
+		}
+		nodes := getRackNodes(nbNodes, dcRacks)
+		for i, rack := range dcRacks {
+			racks = append(racks, cassandraRack{DC: dc, Rack: rack, Nodes: nodes[i]})
+		}
+	}
+	return racks
+}
+
+// rackStatefulSetName returns the name of the statefulset deploying the rack
+func rackStatefulSetName(cc *cassandrav1.CassandraCluster, r cassandraRack) string {
+	if cc.Status.Legacy {
+		first := getRacks(cc)[0]
+		if r.DC.Name == first.DC.Name && r.Rack.Name == first.Rack.Name {
+			return cc.Name
+		}
+	}
+	return cc.Name + "-" + r.DC.Name + "-" + r.Rack.Name
+}
+
+// datacenterServiceName returns the name of the headless service of the datacenter
+func datacenterServiceName(cc *cassandrav1.CassandraCluster, dc cassandrav1.Datacenter) string {
+	if cc.Status.Legacy && dc.Name == getDatacenters(cc)[0].Name {
+		return cc.Name + "-node"
+	}
+	return cc.Name + "-" + dc.Name + "-node"
+}
+
+// getRackNodes returns the desired number of nodes of each rack of a datacenter, in the same order as the racks.
+// Racks without an explicit number of nodes share the remaining datacenter nodes evenly,
+// the first racks taking one more node when the division isn't exact
+func getRackNodes(nbNodes *int32, racks []cassandrav1.Rack) []int32 {
+	nodes := make([]int32, len(racks))
+	var remaining int32
+	if nbNodes != nil {
+		remaining = *nbNodes
+	}
+	var unset []int
+	for i, rack := range racks {
+		if rack.NbNodes != nil {
+			nodes[i] = *rack.NbNodes
+			remaining -= *rack.NbNodes
+		} else {
+			unset = append(unset, i)
+		}
+	}
+	if len(unset) == 0 || remaining <= 0 {
+		return nodes
+	}
+	share := remaining / int32(len(unset))
+	extra := remaining % int32(len(unset))
+	for j, i := range unset {
+		nodes[i] = share
+		if int32(j) < extra {
+			nodes[i]++
+		}
+	}
+	return nodes
+}
+
+// nextRackReplicas computes the replicas of each rack for the next reconciliation step.
+// Cassandra only supports one node joining or leaving the ring at a time so the cluster
+// moves by one node per step, choosing the rack which keeps the racks the most balanced.
+// It returns the index of the rack changed or -1 if the racks are already at their target
+func nextRackReplicas(current []int32, target []int32) ([]int32, int) {
+	next := make([]int32, len(current))
+	copy(next, current)
+	// scale up first: the rack with the fewest nodes among the ones below target
+	chosen := -1
+	for i := range next {
+		if next[i] < target[i] && (chosen == -1 || next[i] < next[chosen]) {
+			chosen = i
+		}
+	}
+	if chosen != -1 {
+		next[chosen]++
+		return next, chosen
+	}
+	// then scale down: the rack with the most nodes among the ones above target
+	for i := range next {
+		if next[i] > target[i] && (chosen == -1 || next[i] > next[chosen]) {
+			chosen = i
+		}
+	}
+	if chosen != -1 {
+		next[chosen]--
+	}
+	return next, chosen
+}
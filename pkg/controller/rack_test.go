@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+// newLegacyStatefulSet returns the statefulset deployed for the cluster before the racks
+func newLegacyStatefulSet(name string, replicas int32) *appsv1.StatefulSet {
+	sts := newRackStatefulSet(name, replicas)
+	sts.Labels = map[string]string{"cassandraCluster": name, "role": "cassandraCluster"}
+	sts.Spec.ServiceName = name + "-node"
+	sts.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"cassandraCluster": name}}
+	sts.Status.Replicas, sts.Status.ReadyReplicas = replicas, replicas
+	return sts
+}
+
+func TestLegacyDeployment(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data.StorageVolume = "1", "4Gi", "10Gi"
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Name: "test-node", Namespace: testNamespace, Labels: map[string]string{"cassandraCluster": "test"},
+	}}
+	f := newFixture(t, cc, newLegacyStatefulSet("test", 3), service)
+
+	legacy, err := f.controller.legacyDeployment(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !legacy {
+		t.Fatal("the statefulset deployed before the racks isn't detected")
+	}
+	cc.Status.Legacy = true
+	if _, err := f.controller.CreateOrUpdateStatefulSets(cc); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.controller.CreateOrUpdateServices(cc); err != nil {
+		t.Fatal(err)
+	}
+
+	stss, err := f.kubeClient.AppsV1().StatefulSets(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stss.Items) != 1 || stss.Items[0].Name != "test" {
+		t.Fatalf("got %d statefulsets, want the legacy one only", len(stss.Items))
+	}
+	sts := stss.Items[0]
+	if !metav1.IsControlledBy(&sts, cc) || sts.Spec.ServiceName != "test-node" || len(sts.Spec.Selector.MatchLabels) != 1 {
+		t.Errorf("got %+v, want the legacy statefulset adopted with its service and selector", sts.ObjectMeta)
+	}
+	if _, err := f.kubeClient.CoreV1().Services(testNamespace).Get("test-dc1-node", metav1.GetOptions{}); err == nil {
+		t.Error("a second headless service was created for the first datacenter")
+	}
+	svc, err := f.kubeClient.CoreV1().Services(testNamespace).Get("test-node", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !metav1.IsControlledBy(svc, cc) {
+		t.Error("the legacy headless service isn't adopted")
+	}
+
+	// a second datacenter gets the names of the racks
+	cc.Spec.Datacenters = getDatacenters(cc)
+	cc.Spec.Datacenters = append(cc.Spec.Datacenters, cc.Spec.Datacenters[0])
+	cc.Spec.Datacenters[1].Name = "dc2"
+	racks := getRacks(cc)
+	if name := rackStatefulSetName(cc, racks[1]); name != "test-dc2-rack1" {
+		t.Errorf("got statefulset %s, want test-dc2-rack1", name)
+	}
+	if name := datacenterServiceName(cc, racks[1].DC); name != "test-dc2-node" {
+		t.Errorf("got service %s, want test-dc2-node", name)
+	}
+}
+
+func TestGetRacks(t *testing.T) {
+	one, two, five := int32(1), int32(2), int32(5)
+	tests := []struct {
+		name  string
+		spec  func(cc *cassandrav1.CassandraCluster)
+		racks []string
+	}{
+		{"default datacenter", func(cc *cassandrav1.CassandraCluster) {}, []string{"dc1/rack1=3"}},
+		// the remaining nodes are spread evenly, the first racks taking one more
+		{"racks", func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.NbNodes = &five
+			cc.Spec.Racks = []cassandrav1.Rack{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+		}, []string{"dc1/a=2", "dc1/b=2", "dc1/c=1"}},
+		{"rack size", func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.NbNodes = &five
+			cc.Spec.Racks = []cassandrav1.Rack{{Name: "a"}, {Name: "b", NbNodes: &one}, {Name: "c"}}
+		}, []string{"dc1/a=2", "dc1/b=1", "dc1/c=2"}},
+		// the datacenters without a size have the size of the cluster
+		{"datacenters", func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.Datacenters = []cassandrav1.Datacenter{
+				{Name: "transactions", Racks: []cassandrav1.Rack{{Name: "a"}, {Name: "b"}}},
+				{Name: "analytics", NbNodes: &two},
+			}
+		}, []string{"transactions/a=2", "transactions/b=1", "analytics/rack1=2"}},
+		{"racks larger than the datacenter", func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.Datacenters = []cassandrav1.Datacenter{
+				{Name: "dc1", NbNodes: &one, Racks: []cassandrav1.Rack{{Name: "a", NbNodes: &two}, {Name: "b"}}},
+			}
+		}, []string{"dc1/a=2", "dc1/b=0"}},
+	}
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		test.spec(cc)
+		var racks []string
+		for _, rack := range getRacks(cc) {
+			racks = append(racks, rack.DC.Name+"/"+rack.Rack.Name+"="+strconv.Itoa(int(rack.Nodes)))
+		}
+		if strings.Join(racks, " ") != strings.Join(test.racks, " ") {
+			t.Errorf("%s: got racks %v, want %v", test.name, racks, test.racks)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+const (
+	// default time given to the ring to settle before a warning is raised
+	defaultReadinessTimeout = 10 * time.Minute
+	// default maximum delay between two checks of the ring
+	defaultReadinessMaxBackoff = time.Minute
+	// delay before the first check of the ring after a step
+	readinessMinBackoff = 5 * time.Second
+)
+
+// ringSettled returns true when the next step can be taken, otherwise the cluster is requeued with a growing delay
+func (c *Controller) ringSettled(cc *cassandrav1.CassandraCluster) bool {
+	reason := c.checkRing(cc)
+	if reason == "" {
+		if cc.Status.RingNotSettledSince != nil {
+			glog.Infof("ring of CassandraCluster %s settled after %s", cc.Name, time.Since(cc.Status.RingNotSettledSince.Time).Round(time.Second))
+		}
+		cc.Status.RingNotSettledSince = nil
+		setCondition(&cc.Status, cassandrav1.ClusterRingNotSettled, false, "", "")
+		return true
+	}
+
+	if cc.Status.RingNotSettledSince == nil {
+		cc.Status.RingNotSettledSince = now()
+	}
+	waited := time.Since(cc.Status.RingNotSettledSince.Time)
+	timeout := defaultReadinessTimeout
+	if cc.Spec.Readiness.TimeoutSeconds > 0 {
+		timeout = time.Duration(cc.Spec.Readiness.TimeoutSeconds) * time.Second
+	}
+	if waited > timeout && !conditionTrue(&cc.Status, cassandrav1.ClusterRingNotSettled) {
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "RingNotSettled", "The ring didn't settle within %s: %s", timeout, reason)
+	}
+	setCondition(&cc.Status, cassandrav1.ClusterRingNotSettled, waited > timeout, "RingNotSettled", reason)
+
+	maxBackoff := defaultReadinessMaxBackoff
+	if cc.Spec.Readiness.MaxBackoffSeconds > 0 {
+		maxBackoff = time.Duration(cc.Spec.Readiness.MaxBackoffSeconds) * time.Second
+	}
+	delay := readinessBackoff(waited, maxBackoff)
+	glog.V(2).Infof("CassandraCluster %s waiting for the ring to settle (%s), next check in %s", cc.Name, reason, delay)
+	key, err := cache.MetaNamespaceKeyFunc(cc)
+	if err == nil {
+		c.workqueue.AddAfter(key, delay)
+	}
+	return false
+}
+
+// readinessBackoff makes the delay between two checks of the ring grow with the time already spent waiting
+func readinessBackoff(waited time.Duration, maxBackoff time.Duration) time.Duration {
+	delay := waited / 2
+	if delay < readinessMinBackoff {
+		delay = readinessMinBackoff
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// checkRing returns why the ring isn't settled, or an empty string when it is
+func (c *Controller) checkRing(cc *cassandrav1.CassandraCluster) string {
+	if int32(len(cc.Status.Nodes)) != cc.Status.DesiredNodes || cc.Status.ReadyNodes != cc.Status.DesiredNodes {
+		return fmt.Sprintf("%d of %d nodes ready", cc.Status.ReadyNodes, cc.Status.DesiredNodes)
+	}
+	for i, node := range cc.Status.Nodes {
+		client, err := c.adminClient(node.Name)
+		if err != nil {
+			return fmt.Sprintf("node %s unreachable: %v", node.Name, err)
+		}
+		// the gossip view of each node must have converged
+		ring, err := client.Status()
+		if err != nil {
+			return fmt.Sprintf("could not get the ring state from %s: %v", node.Name, err)
+		}
+		if len(ring) != int(cc.Status.DesiredNodes) {
+			return fmt.Sprintf("%d nodes in the ring seen by %s instead of %d", len(ring), node.Name, cc.Status.DesiredNodes)
+		}
+		for _, n := range ring {
+			if n.Code() != "UN" {
+				return fmt.Sprintf("node %s is %s as seen by %s", n.Address, n.Code(), node.Name)
+			}
+		}
+		// describecluster asks all the nodes for their schema version
+		if i == 0 {
+			versions, err := client.SchemaVersions()
+			if err != nil {
+				return fmt.Sprintf("could not get the schema versions from %s: %v", node.Name, err)
+			}
+			if !admin.SchemaAgreement(versions) {
+				return fmt.Sprintf("schema versions disagree: %s", formatSchemaVersions(versions))
+			}
+		}
+		streams, err := client.Streams()
+		if err != nil {
+			return fmt.Sprintf("could not get the streams of %s: %v", node.Name, err)
+		}
+		if len(streams) > 0 {
+			return fmt.Sprintf("node %s streams with %s (%s)", node.Name, streams[0].Peer, streams[0].Operation)
+		}
+	}
+	return ""
+}
+
+func formatSchemaVersions(versions map[string][]string) string {
+	var parts []string
+	for version, addresses := range versions {
+		parts = append(parts, fmt.Sprintf("%s: [%s]", version, strings.Join(addresses, ", ")))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func conditionTrue(status *cassandrav1.CassandraClusterStatus, condType cassandrav1.CassandraClusterConditionType) bool {
+	for _, cond := range status.Conditions {
+		if cond.Type == condType {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
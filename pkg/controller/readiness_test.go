@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+func TestReadinessBackoff(t *testing.T) {
+	tests := []struct {
+		waited time.Duration
+		delay  time.Duration
+	}{
+		{0, readinessMinBackoff},
+		{40 * time.Second, 20 * time.Second},
+		{time.Hour, time.Minute},
+	}
+	for _, test := range tests {
+		if delay := readinessBackoff(test.waited, time.Minute); delay != test.delay {
+			t.Errorf("waited %s: got %s, want %s", test.waited, delay, test.delay)
+		}
+	}
+}
+
+func TestRingSettled(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Status.DesiredNodes, cc.Status.ReadyNodes = 3, 3
+	var ring []admin.NodeStatus
+	for i := 0; i < 3; i++ {
+		cc.Status.Nodes = append(cc.Status.Nodes, cassandrav1.CassandraNodeStatus{Name: fmt.Sprintf("test-dc1-rack1-%d", i)})
+		ring = append(ring, admin.NodeStatus{Address: fmt.Sprintf("10.0.0.%d", i), Up: true, State: admin.NodeNormal})
+	}
+	f := newFixture(t, cc)
+	for _, node := range cc.Status.Nodes {
+		f.node(node.Name).Nodes = ring
+	}
+	f.node(cc.Status.Nodes[0].Name).Schema = map[string][]string{"v1": {"10.0.0.0", "10.0.0.1", "10.0.0.2"}}
+
+	if !f.controller.ringSettled(cc) {
+		t.Fatalf("got the ring not settled: %s", f.controller.checkRing(cc))
+	}
+
+	// the last node is joining as seen by the second node
+	joining := append([]admin.NodeStatus(nil), ring...)
+	joining[2].State = admin.NodeJoining
+	f.node(cc.Status.Nodes[1].Name).Nodes = joining
+	if f.controller.ringSettled(cc) {
+		t.Fatal("got the ring settled while a node joins")
+	}
+	if cc.Status.RingNotSettledSince == nil || conditionTrue(&cc.Status, cassandrav1.ClusterRingNotSettled) {
+		t.Errorf("got %+v, want the wait started without warning", cc.Status)
+	}
+
+	// the wait exceeds the timeout
+	since := metav1.NewTime(time.Now().Add(-defaultReadinessTimeout - time.Minute))
+	cc.Status.RingNotSettledSince = &since
+	f.controller.ringSettled(cc)
+	if !conditionTrue(&cc.Status, cassandrav1.ClusterRingNotSettled) {
+		t.Error("the RingNotSettled condition isn't set after the timeout")
+	}
+	if events := f.events(); len(events) != 1 || !strings.Contains(events[0], "node 10.0.0.2 is UJ as seen by test-dc1-rack1-1") {
+		t.Errorf("got events %v, want a RingNotSettled warning", events)
+	}
+
+	f.node(cc.Status.Nodes[1].Name).Nodes = ring
+	if !f.controller.ringSettled(cc) || cc.Status.RingNotSettledSince != nil || conditionTrue(&cc.Status, cassandrav1.ClusterRingNotSettled) {
+		t.Errorf("got %+v, want the wait over", cc.Status)
+	}
+}
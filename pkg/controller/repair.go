@@ -0,0 +1,389 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/robfig/cron"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+const (
+	// RepairAnnotation requests a repair of the cluster when its value changes
+	RepairAnnotation = "cassandra/repair"
+	// repair triggers recorded in the status
+	repairTriggerTopology = "TopologyChange"
+	repairTriggerOnDemand = "OnDemand"
+	repairTriggerSchedule = "Scheduled"
+	// default timeout of the repair of a keyspace on a node
+	defaultRepairTimeout = 6 * time.Hour
+	// operationStartTimeout is how long a background operation can stay starting before it's considered lost
+	operationStartTimeout = 2 * time.Minute
+	// default age after which a keyspace repair is overdue, the default gc_grace_seconds
+	defaultRepairMaxAge = 10 * 24 * time.Hour
+)
+
+// keyspaces using the LocalStrategy which don't need to be repaired
+var localKeyspaces = map[string]bool{
+	"system":        true,
+	"system_schema": true,
+}
+
+// requestRepair registers a repair of all the keyspaces which will start once the cluster is stable
+func requestRepair(cc *cassandrav1.CassandraCluster, trigger string) {
+	if cc.Status.PendingRepair == "" {
+		cc.Status.PendingRepair = trigger
+	}
+	cc.Status.PendingRepairKeyspaces = nil
+}
+
+// requestKeyspaceRepair registers a repair of a keyspace, it is merged with the pending repair
+func requestKeyspaceRepair(cc *cassandrav1.CassandraCluster, trigger string, keyspace string) {
+	if cc.Status.PendingRepair == "" {
+		cc.Status.PendingRepair = trigger
+	} else if len(cc.Status.PendingRepairKeyspaces) == 0 {
+		// all the keyspaces are already pending
+		return
+	}
+	if !containsString(cc.Status.PendingRepairKeyspaces, keyspace) {
+		cc.Status.PendingRepairKeyspaces = append(cc.Status.PendingRepairKeyspaces, keyspace)
+	}
+}
+
+// reconcileRepair drives the repair of the cluster. Each reconciliation moves the repair one step further:
+// nodes are repaired sequentially with "nodetool repair -pr" on each keyspace, and the progress is recorded in the
+// status of the CassandraCluster so the repair is resumed where it left off after a restart of the operator
+func (c *Controller) reconcileRepair(cc *cassandrav1.CassandraCluster) error {
+	// on demand repair
+	if request := cc.Annotations[RepairAnnotation]; request != "" && request != cc.Status.LastRepairRequest {
+		cc.Status.LastRepairRequest = request
+		requestRepair(cc, repairTriggerOnDemand)
+	}
+	c.scheduleRepair(cc)
+	c.checkRepairOverdue(cc)
+
+	// the repair doesn't run while nodes join or leave the ring
+	if !clusterStable(cc) {
+		return nil
+	}
+
+	r := cc.Status.Repair
+	if (r == nil || r.Phase != cassandrav1.RepairRunning) && cc.Status.PendingRepair != "" {
+		if !c.ringSettled(cc) {
+			return nil
+		}
+		tasks, err := c.buildRepairTasks(cc)
+		if err != nil {
+			return err
+		}
+		r = &cassandrav1.RepairStatus{
+			Trigger:   cc.Status.PendingRepair,
+			Phase:     cassandrav1.RepairRunning,
+			StartTime: now(),
+			Tasks:     tasks,
+		}
+		cc.Status.Repair = r
+		cc.Status.PendingRepair = ""
+		cc.Status.PendingRepairKeyspaces = nil
+		if err := c.persistStatus(cc); err != nil {
+			return err
+		}
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "RepairStarted", "Repair of %d keyspaces on %d nodes started (%s)", countKeyspaces(tasks), countPods(tasks), r.Trigger)
+	}
+	if r == nil || r.Phase != cassandrav1.RepairRunning {
+		return nil
+	}
+
+	// only one task is in flight at a time
+	for i := range r.Tasks {
+		task := &r.Tasks[i]
+		if task.Phase == cassandrav1.RepairSucceeded || task.Phase == cassandrav1.RepairFailed {
+			continue
+		}
+		return c.runRepairTask(cc, task)
+	}
+
+	// all the tasks are done
+	r.Phase = cassandrav1.RepairSucceeded
+	var failed []string
+	for _, task := range r.Tasks {
+		if task.Phase == cassandrav1.RepairFailed {
+			r.Phase = cassandrav1.RepairFailed
+			failed = append(failed, task.Pod+"/"+task.Keyspace)
+		}
+	}
+	r.CompletionTime = now()
+	recordKeyspaceRepairs(cc, r)
+	if r.Phase == cassandrav1.RepairFailed {
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "RepairFailed", "Repair failed for %s", strings.Join(failed, ", "))
+	} else {
+		c.recorder.Event(cc, corev1.EventTypeNormal, "RepairSucceeded", "Repair of the cluster succeeded")
+	}
+	return nil
+}
+
+// runRepairTask launches the repair of a keyspace on a node or follows its progress
+func (c *Controller) runRepairTask(cc *cassandrav1.CassandraCluster, task *cassandrav1.RepairTask) error {
+	client, err := c.adminClient(task.Pod)
+	if err != nil {
+		return err
+	}
+	if task.Phase == cassandrav1.RepairPending {
+		glog.Infof("repairing keyspace %s on node %s of CassandraCluster %s", task.Keyspace, task.Pod, cc.Name)
+		command, err := client.Repair(task.Keyspace, repairOptions(cc))
+		if err != nil {
+			return fmt.Errorf("could not start the repair of %s on %s: %v", task.Keyspace, task.Pod, err)
+		}
+		task.Phase = cassandrav1.RepairRunning
+		task.Attempts++
+		task.StartTime = now()
+		task.Command = int64(command)
+		task.Message = ""
+		return c.persistStatus(cc)
+	}
+
+	state, err := client.RepairStatus(int(task.Command))
+	if err != nil {
+		return fmt.Errorf("could not get the repair state of %s on %s: %v", task.Keyspace, task.Pod, err)
+	}
+	switch state {
+	case admin.OperationStarting:
+		if task.StartTime == nil || time.Since(task.StartTime.Time) > operationStartTimeout {
+			c.failRepairAttempt(cc, task, fmt.Sprintf("repair not started after %s", operationStartTimeout))
+		}
+	case admin.OperationInProgress:
+		timeout := defaultRepairTimeout
+		if cc.Spec.Repair.TimeoutSeconds > 0 {
+			timeout = time.Duration(cc.Spec.Repair.TimeoutSeconds) * time.Second
+		}
+		if task.StartTime != nil && time.Since(task.StartTime.Time) > timeout {
+			if err := client.StopRepair(int(task.Command)); err != nil {
+				glog.Warningf("could not stop the repair of keyspace %s on node %s of CassandraCluster %s: %v", task.Keyspace, task.Pod, cc.Name, err)
+			}
+			c.failRepairAttempt(cc, task, fmt.Sprintf("timeout after %s", timeout))
+		}
+	case admin.OperationUnknown:
+		// the node restarted during the repair
+		c.failRepairAttempt(cc, task, "repair lost")
+	case admin.OperationCompleted:
+		glog.Infof("keyspace %s repaired on node %s of CassandraCluster %s", task.Keyspace, task.Pod, cc.Name)
+		task.Phase = cassandrav1.RepairSucceeded
+	default:
+		c.failRepairAttempt(cc, task, fmt.Sprintf("repair %s, see the logs of the node", state))
+	}
+	return nil
+}
+
+// failRepairAttempt retries the task until the number of retries of the spec is reached
+func (c *Controller) failRepairAttempt(cc *cassandrav1.CassandraCluster, task *cassandrav1.RepairTask, message string) {
+	glog.Warningf("repair of keyspace %s on node %s of CassandraCluster %s failed: %s", task.Keyspace, task.Pod, cc.Name, message)
+	task.Message = message
+	if task.Attempts > cc.Spec.Repair.Retries {
+		task.Phase = cassandrav1.RepairFailed
+		return
+	}
+	task.Phase = cassandrav1.RepairPending
+}
+
+// buildRepairTasks lists the keyspaces to repair on each node of the cluster
+func (c *Controller) buildRepairTasks(cc *cassandrav1.CassandraCluster) ([]cassandrav1.RepairTask, error) {
+	pods, err := c.podLister.Pods(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no node to repair in CassandraCluster %s", cc.Name)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	keyspaces := cc.Status.PendingRepairKeyspaces
+	if len(keyspaces) == 0 {
+		keyspaces, err = c.getKeyspaces(cc, pods[0].Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var tasks []cassandrav1.RepairTask
+	for _, pod := range pods {
+		for _, keyspace := range keyspaces {
+			tasks = append(tasks, cassandrav1.RepairTask{
+				Pod:      pod.Name,
+				Keyspace: keyspace,
+				Phase:    cassandrav1.RepairPending,
+			})
+		}
+	}
+	return tasks, nil
+}
+
+// getKeyspaces returns the replicated keyspaces of the cluster to repair
+func (c *Controller) getKeyspaces(cc *cassandrav1.CassandraCluster, podName string) ([]string, error) {
+	client, err := c.adminClient(podName)
+	if err != nil {
+		return nil, err
+	}
+	all, err := client.Keyspaces()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the keyspaces from %s: %v", podName, err)
+	}
+	include := map[string]bool{}
+	for _, keyspace := range cc.Spec.Repair.Keyspaces {
+		include[keyspace] = true
+	}
+	exclude := map[string]bool{}
+	for _, keyspace := range cc.Spec.Repair.ExcludeKeyspaces {
+		exclude[keyspace] = true
+	}
+	var keyspaces []string
+	for _, keyspace := range all {
+		if localKeyspaces[keyspace] || exclude[keyspace] || len(include) > 0 && !include[keyspace] {
+			continue
+		}
+		keyspaces = append(keyspaces, keyspace)
+	}
+	return keyspaces, nil
+}
+
+// repairOptions repairs the primary ranges of the keyspaces on each node
+func repairOptions(cc *cassandrav1.CassandraCluster) admin.RepairOptions {
+	options := admin.RepairOptions{PrimaryRange: true, Incremental: cc.Spec.Repair.Incremental}
+	switch cc.Spec.Repair.Parallelism {
+	case cassandrav1.RepairSequential:
+		options.Parallelism = "sequential"
+	case cassandrav1.RepairDCParallel:
+		options.Parallelism = "dc_parallel"
+	}
+	return options
+}
+
+// scheduleRepair requests a repair when the cron schedule of the spec is due
+func (c *Controller) scheduleRepair(cc *cassandrav1.CassandraCluster) {
+	if cc.Spec.Repair.Schedule == "" {
+		return
+	}
+	// the schedule is checked by validateSpec, the cluster is rejected once when it changes to an invalid one
+	schedule, err := cron.ParseStandard(cc.Spec.Repair.Schedule)
+	if err != nil {
+		return
+	}
+	last := cc.CreationTimestamp.Time
+	if cc.Status.LastScheduledRepair != nil {
+		last = cc.Status.LastScheduledRepair.Time
+	}
+	if schedule.Next(last).After(clock()) {
+		return
+	}
+	glog.Infof("scheduled repair of CassandraCluster %s is due", cc.Name)
+	cc.Status.LastScheduledRepair = now()
+	requestRepair(cc, repairTriggerSchedule)
+}
+
+// recordKeyspaceRepairs keeps the time of the repair for the keyspaces repaired successfully on all the nodes
+func recordKeyspaceRepairs(cc *cassandrav1.CassandraCluster, r *cassandrav1.RepairStatus) {
+	succeeded := map[string]bool{}
+	for _, task := range r.Tasks {
+		if _, ok := succeeded[task.Keyspace]; !ok {
+			succeeded[task.Keyspace] = true
+		}
+		if task.Phase != cassandrav1.RepairSucceeded {
+			succeeded[task.Keyspace] = false
+		}
+	}
+	for keyspace, ok := range succeeded {
+		if !ok {
+			continue
+		}
+		found := false
+		for i := range cc.Status.KeyspaceRepairs {
+			if cc.Status.KeyspaceRepairs[i].Keyspace == keyspace {
+				cc.Status.KeyspaceRepairs[i].LastSuccessTime = *r.StartTime
+				found = true
+			}
+		}
+		if !found {
+			cc.Status.KeyspaceRepairs = append(cc.Status.KeyspaceRepairs, cassandrav1.KeyspaceRepairStatus{
+				Keyspace:        keyspace,
+				LastSuccessTime: *r.StartTime,
+			})
+		}
+	}
+	sort.Slice(cc.Status.KeyspaceRepairs, func(i, j int) bool {
+		return cc.Status.KeyspaceRepairs[i].Keyspace < cc.Status.KeyspaceRepairs[j].Keyspace
+	})
+}
+
+// checkRepairOverdue warns when keyspaces haven't been repaired successfully within the max age of the spec.
+// The event is only emitted when the cluster becomes overdue
+func (c *Controller) checkRepairOverdue(cc *cassandrav1.CassandraCluster) {
+	if cc.Spec.Repair.Schedule == "" {
+		return
+	}
+	maxAge := defaultRepairMaxAge
+	if cc.Spec.Repair.MaxAgeSeconds > 0 {
+		maxAge = time.Duration(cc.Spec.Repair.MaxAgeSeconds) * time.Second
+	}
+	var overdue []string
+	if len(cc.Status.KeyspaceRepairs) == 0 {
+		if clock().Sub(cc.CreationTimestamp.Time) > maxAge {
+			overdue = append(overdue, "all keyspaces")
+		}
+	}
+	for _, ks := range cc.Status.KeyspaceRepairs {
+		if clock().Sub(ks.LastSuccessTime.Time) > maxAge {
+			overdue = append(overdue, ks.Keyspace)
+		}
+	}
+
+	wasOverdue := false
+	for _, cond := range cc.Status.Conditions {
+		if cond.Type == cassandrav1.ClusterRepairOverdue && cond.Status == corev1.ConditionTrue {
+			wasOverdue = true
+		}
+	}
+	message := fmt.Sprintf("not repaired successfully for more than %s: %s", maxAge, strings.Join(overdue, ", "))
+	setCondition(&cc.Status, cassandrav1.ClusterRepairOverdue, len(overdue) > 0, "RepairOverdue", message)
+	if len(overdue) > 0 && !wasOverdue {
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "RepairOverdue", "Keyspaces %s", message)
+	}
+}
+
+// clusterStable returns true when all the desired nodes are ready, no node is joining or leaving the ring
+// and all the nodes run the same version
+func clusterStable(cc *cassandrav1.CassandraCluster) bool {
+	return cc.Status.Decommission == nil && !upgradeInProgress(cc) &&
+		cc.Status.DesiredNodes > 0 &&
+		cc.Status.ReadyNodes == cc.Status.DesiredNodes &&
+		len(cc.Status.Nodes) == int(cc.Status.DesiredNodes)
+}
+
+func countPods(tasks []cassandrav1.RepairTask) int {
+	pods := map[string]bool{}
+	for _, task := range tasks {
+		pods[task.Pod] = true
+	}
+	return len(pods)
+}
+
+func countKeyspaces(tasks []cassandrav1.RepairTask) int {
+	keyspaces := map[string]bool{}
+	for _, task := range tasks {
+		keyspaces[task.Keyspace] = true
+	}
+	return len(keyspaces)
+}
+
+// clock returns the current time, it's replaced in the tests
+var clock = time.Now
+
+func now() *metav1.Time {
+	t := metav1.NewTime(clock())
+	return &t
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+func TestRunRepairTask(t *testing.T) {
+	cc := newCassandraCluster("test")
+	f := newFixture(t, cc)
+	task := &cassandrav1.RepairTask{Pod: "test-dc1-rack1-0", Keyspace: "orders", Phase: cassandrav1.RepairPending}
+
+	if err := f.controller.runRepairTask(cc, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Phase != cassandrav1.RepairRunning || task.Attempts != 1 || task.Command == 0 {
+		t.Fatalf("got %+v, want a running repair", task)
+	}
+	if err := f.controller.runRepairTask(cc, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Phase != cassandrav1.RepairSucceeded {
+		t.Errorf("got phase %s, want %s", task.Phase, cassandrav1.RepairSucceeded)
+	}
+	want := []string{"repair orders", "repair status 1"}
+	if calls := f.node(task.Pod).Calls; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestRunRepairTaskTimeout(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Repair.TimeoutSeconds = 3600
+	f := newFixture(t, cc)
+	start := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	task := &cassandrav1.RepairTask{Pod: "test-dc1-rack1-0", Keyspace: "orders", Phase: cassandrav1.RepairRunning, Attempts: 1, StartTime: &start, Command: 5}
+	f.node(task.Pod).Operations[5] = admin.OperationInProgress
+
+	if err := f.controller.runRepairTask(cc, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Phase != cassandrav1.RepairFailed || !strings.Contains(task.Message, "timeout") {
+		t.Errorf("got %+v, want a repair failed on timeout", task)
+	}
+	if state := f.node(task.Pod).Operations[5]; state != admin.OperationFailed {
+		t.Errorf("got the repair %s on the node, want it stopped", state)
+	}
+}
+
+func TestRunRepairTaskNotStarted(t *testing.T) {
+	cc := newCassandraCluster("test")
+	f := newFixture(t, cc)
+	task := &cassandrav1.RepairTask{Pod: "test-dc1-rack1-0", Keyspace: "orders", Phase: cassandrav1.RepairRunning, Attempts: 1, StartTime: now(), Command: 5}
+	f.node(task.Pod).Operations[5] = admin.OperationStarting
+
+	if err := f.controller.runRepairTask(cc, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Phase != cassandrav1.RepairRunning {
+		t.Fatalf("got phase %s, want the repair starting", task.Phase)
+	}
+	// the pod restarted before the repair process was started
+	start := metav1.NewTime(time.Now().Add(-operationStartTimeout - time.Second))
+	task.StartTime = &start
+	if err := f.controller.runRepairTask(cc, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Phase != cassandrav1.RepairFailed || !strings.Contains(task.Message, "not started") {
+		t.Errorf("got %+v, want a repair failed as not started", task)
+	}
+}
+
+func TestRunRepairTaskRetry(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Repair.Retries = 1
+	f := newFixture(t, cc)
+	task := &cassandrav1.RepairTask{Pod: "test-dc1-rack1-0", Keyspace: "orders", Phase: cassandrav1.RepairRunning, Attempts: 1, StartTime: now(), Command: 5}
+
+	// the node restarted and lost the repair
+	if err := f.controller.runRepairTask(cc, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Phase != cassandrav1.RepairPending {
+		t.Fatalf("got phase %s, want the repair retried", task.Phase)
+	}
+	task.Phase, task.Attempts = cassandrav1.RepairRunning, 2
+	f.node(task.Pod).Operations[5] = admin.OperationFailed
+	if err := f.controller.runRepairTask(cc, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Phase != cassandrav1.RepairFailed {
+		t.Errorf("got phase %s, want %s once the retries are exhausted", task.Phase, cassandrav1.RepairFailed)
+	}
+}
+
+func TestGetKeyspaces(t *testing.T) {
+	tests := []struct {
+		repair    cassandrav1.RepairSpec
+		keyspaces []string
+	}{
+		{cassandrav1.RepairSpec{}, []string{"system_auth", "orders", "users"}},
+		{cassandrav1.RepairSpec{ExcludeKeyspaces: []string{"users"}}, []string{"system_auth", "orders"}},
+		{cassandrav1.RepairSpec{Keyspaces: []string{"users"}}, []string{"users"}},
+	}
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		cc.Spec.Repair = test.repair
+		f := newFixture(t, cc)
+		f.node("test-dc1-rack1-0").KeyspaceList = []string{"system", "system_schema", "system_auth", "orders", "users"}
+		keyspaces, err := f.controller.getKeyspaces(cc, "test-dc1-rack1-0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(keyspaces, test.keyspaces) {
+			t.Errorf("%+v: got %v, want %v", test.repair, keyspaces, test.keyspaces)
+		}
+	}
+}
+
+func TestRepairOptions(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Repair.Parallelism = cassandrav1.RepairDCParallel
+	want := admin.RepairOptions{PrimaryRange: true, Parallelism: "dc_parallel"}
+	if options := repairOptions(cc); options != want {
+		t.Errorf("got %+v, want %+v", options, want)
+	}
+}
+
+// setClock sets the time returned by clock, the test restores time.Now when it ends
+func setClock(now time.Time) {
+	clock = func() time.Time { return now }
+}
+
+func TestScheduleRepair(t *testing.T) {
+	defer func() { clock = time.Now }()
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cc := newRunningCluster("test")
+	cc.CreationTimestamp = metav1.NewTime(created)
+	cc.Spec.Repair.Schedule = "0 2 * * *"
+	f := newFixture(t, cc)
+
+	setClock(created.Add(time.Hour))
+	f.controller.scheduleRepair(cc)
+	if cc.Status.PendingRepair != "" {
+		t.Fatalf("got repair %s before the schedule", cc.Status.PendingRepair)
+	}
+
+	setClock(created.Add(150 * time.Minute))
+	f.controller.scheduleRepair(cc)
+	if cc.Status.PendingRepair != repairTriggerSchedule || !cc.Status.LastScheduledRepair.Time.Equal(created.Add(150*time.Minute)) {
+		t.Fatalf("got %+v, want the scheduled repair requested", cc.Status)
+	}
+
+	// the next repair is the following night
+	cc.Status.PendingRepair = ""
+	setClock(created.Add(23 * time.Hour))
+	f.controller.scheduleRepair(cc)
+	if cc.Status.PendingRepair != "" {
+		t.Errorf("got repair %s twice the same night", cc.Status.PendingRepair)
+	}
+	setClock(created.Add(26 * time.Hour))
+	f.controller.scheduleRepair(cc)
+	if cc.Status.PendingRepair != repairTriggerSchedule {
+		t.Errorf("got %+v, want the repair of the second night requested", cc.Status)
+	}
+	if events := f.events(); len(events) != 0 {
+		t.Errorf("got events %v", events)
+	}
+}
+
+func TestCheckRepairOverdue(t *testing.T) {
+	defer func() { clock = time.Now }()
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	cc := newRunningCluster("test")
+	cc.CreationTimestamp = metav1.NewTime(created)
+	cc.Spec.Repair.Schedule = "0 2 * * 0"
+	cc.Spec.Repair.MaxAgeSeconds = int64((7 * day).Seconds())
+	f := newFixture(t, cc)
+
+	setClock(created.Add(day))
+	f.controller.checkRepairOverdue(cc)
+	if conditionTrue(&cc.Status, cassandrav1.ClusterRepairOverdue) {
+		t.Fatal("got the cluster overdue the day after its creation")
+	}
+
+	// the warning is emitted once when the cluster becomes overdue
+	setClock(created.Add(8 * day))
+	f.controller.checkRepairOverdue(cc)
+	f.controller.checkRepairOverdue(cc)
+	if !conditionTrue(&cc.Status, cassandrav1.ClusterRepairOverdue) {
+		t.Fatal("got the cluster not overdue without a repair for 8 days")
+	}
+	if events := f.events(); len(events) != 1 || !strings.Contains(events[0], "RepairOverdue Keyspaces not repaired successfully for more than 168h0m0s: all keyspaces") {
+		t.Errorf("got events %v, want a RepairOverdue warning", events)
+	}
+
+	cc.Status.KeyspaceRepairs = []cassandrav1.KeyspaceRepairStatus{
+		{Keyspace: "orders", LastSuccessTime: metav1.NewTime(created.Add(7 * day))},
+		{Keyspace: "users", LastSuccessTime: metav1.NewTime(created.Add(day / 2))},
+	}
+	f.controller.checkRepairOverdue(cc)
+	for _, cond := range cc.Status.Conditions {
+		if cond.Type == cassandrav1.ClusterRepairOverdue && !strings.HasSuffix(cond.Message, ": users") {
+			t.Errorf("got %q, want the keyspace not repaired overdue", cond.Message)
+		}
+	}
+	cc.Status.KeyspaceRepairs = cc.Status.KeyspaceRepairs[:1]
+	f.controller.checkRepairOverdue(cc)
+	if conditionTrue(&cc.Status, cassandrav1.ClusterRepairOverdue) {
+		t.Error("got the cluster overdue after the repair of its keyspaces")
+	}
+}
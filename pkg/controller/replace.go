@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+const (
+	// replaceAddressDir is where the addresses of the dead nodes replaced by the pods are mounted in the init container
+	replaceAddressDir = "/replace-address"
+)
+
+// reconcileReplacement records the node of each pod and restarts the pods which came back with a new PVC while their
+// node is down with -Dcassandra.replace_address_first_boot, so they take over the tokens of the dead node
+func (c *Controller) reconcileReplacement(cc *cassandrav1.CassandraCluster) error {
+	if r := cc.Status.Replacement; r != nil {
+		return c.progressReplacement(cc, r)
+	}
+	pods, err := c.podLister.Pods(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+	if err != nil {
+		return err
+	}
+
+	// the ring is only read when the node of a pod must be recorded or may be dead
+	var unknown, suspects []*corev1.Pod
+	for _, pod := range pods {
+		member := findRingMember(cc.Status.RingMembers, pod.Name)
+		switch {
+		case podState(pod) == cassandrav1.NodeStateReady:
+			if member == nil || member.Address != pod.Status.PodIP {
+				unknown = append(unknown, pod)
+			}
+		case member != nil:
+			suspects = append(suspects, pod)
+		}
+	}
+	if len(unknown) == 0 && len(suspects) == 0 {
+		return nil
+	}
+	ring, err := c.ringStatus(cc, "")
+	if err != nil {
+		glog.V(2).Infof("could not check the ring members of CassandraCluster %s: %v", cc.Name, err)
+		return nil
+	}
+
+	for _, pod := range unknown {
+		node := admin.FindNode(ring, pod.Status.PodIP)
+		if node == nil || node.Code() != "UN" || node.HostID == "" {
+			continue
+		}
+		volumeUID, err := c.volumeUID(pod.Name)
+		if err != nil {
+			return err
+		}
+		setRingMember(&cc.Status, cassandrav1.RingMember{
+			Pod:       pod.Name,
+			Address:   node.Address,
+			HostID:    node.HostID,
+			VolumeUID: volumeUID,
+		})
+	}
+
+	// a single node is replaced at a time, and not while another one leaves the ring
+	if cc.Status.Decommission != nil {
+		return nil
+	}
+	for _, pod := range suspects {
+		member := findRingMember(cc.Status.RingMembers, pod.Name)
+		volumeUID, err := c.volumeUID(pod.Name)
+		if err != nil {
+			return err
+		}
+		if volumeUID == "" || volumeUID == member.VolumeUID {
+			// the pod restarts with the data of its node
+			continue
+		}
+		dead := findHostID(ring, member.HostID)
+		if dead == nil || dead.Up {
+			continue
+		}
+		if clusterConfigHash(cc) == "" {
+			glog.Warningf("pod %s of CassandraCluster %s lost the data of node %s, it can't be replaced as the configuration isn't managed by the operator", pod.Name, cc.Name, dead.Address)
+			continue
+		}
+		r := &cassandrav1.ReplacementStatus{
+			Pod:         pod.Name,
+			DeadAddress: dead.Address,
+			HostID:      member.HostID,
+			Phase:       cassandrav1.ReplacementRestarting,
+			StartTime:   now(),
+		}
+		cc.Status.Replacement = r
+		if err := c.persistStatus(cc); err != nil {
+			return err
+		}
+		glog.Infof("pod %s of CassandraCluster %s lost its data, replacing the dead node %s", pod.Name, cc.Name, dead.Address)
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "ReplacingNode", "Pod %s lost its data, replacing the dead node %s (%s)", pod.Name, dead.Address, member.HostID)
+		return c.progressReplacement(cc, r)
+	}
+	return nil
+}
+
+// progressReplacement restarts the pod with the address of the dead node and removes it once the node replaced it
+func (c *Controller) progressReplacement(cc *cassandrav1.CassandraCluster, r *cassandrav1.ReplacementStatus) error {
+	switch r.Phase {
+	case cassandrav1.ReplacementRestarting:
+		if err := c.setReplaceAddress(cc, r.Pod, r.DeadAddress); err != nil {
+			return err
+		}
+		// the init container of the new pod adds the flag to the JVM options
+		err := c.kubeClientset.CoreV1().Pods(c.namespace).Delete(r.Pod, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.Phase = cassandrav1.ReplacementStreaming
+		return c.persistStatus(cc)
+
+	case cassandrav1.ReplacementStreaming:
+		pod, err := c.podLister.Pods(c.namespace).Get(r.Pod)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if podState(pod) != cassandrav1.NodeStateReady {
+			return nil
+		}
+		ring, err := c.ringStatus(cc, r.Pod)
+		if err != nil {
+			return err
+		}
+		if node := admin.FindNode(ring, pod.Status.PodIP); node == nil || node.Code() != "UN" {
+			return nil
+		}
+		if pod.Status.PodIP != r.DeadAddress && admin.FindNode(ring, r.DeadAddress) != nil {
+			return nil
+		}
+		client, err := c.adminClient(r.Pod)
+		if err != nil {
+			return err
+		}
+		streams, err := client.Streams()
+		if err != nil {
+			return fmt.Errorf("could not get the streams of %s: %v", r.Pod, err)
+		}
+		if len(streams) > 0 {
+			return nil
+		}
+
+		if err := c.setReplaceAddress(cc, r.Pod, ""); err != nil {
+			return err
+		}
+		glog.Infof("pod %s of CassandraCluster %s replaced the dead node %s", r.Pod, cc.Name, r.DeadAddress)
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "NodeReplaced", "Pod %s replaced the dead node %s", r.Pod, r.DeadAddress)
+		// the node is recorded again with its new address
+		removeRingMember(&cc.Status, r.Pod)
+		cc.Status.Replacement = nil
+		return c.persistStatus(cc)
+	}
+	return nil
+}
+
+// volumeUID returns the UID of the data PVC of the pod, or an empty string if it doesn't exist yet
+func (c *Controller) volumeUID(podName string) (string, error) {
+	pvc, err := c.kubeClientset.CoreV1().PersistentVolumeClaims(c.namespace).Get("data-"+podName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(pvc.UID), nil
+}
+
+// replaceAddressesConfigMapName returns the name of the configmap of the addresses of the dead nodes being replaced
+func replaceAddressesConfigMapName(ccName string) string {
+	return ccName + "-replace-addresses"
+}
+
+// setReplaceAddress records the address of the dead node replaced by the pod in the configmap mounted by the
+// statefulsets, an empty address removes it
+func (c *Controller) setReplaceAddress(cc *cassandrav1.CassandraCluster, podName string, address string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: replaceAddressesConfigMapName(cc.Name),
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Data: map[string]string{},
+	}
+	if address != "" {
+		cm.Data[podName] = address
+	}
+	return c.createOrUpdate(cc, c.configMapClient(), cm, func(obj object) {
+		updated := obj.(*corev1.ConfigMap)
+		if address == "" {
+			delete(updated.Data, podName)
+			return
+		}
+		updated.Data = mergeMap(updated.Data, cm.Data)
+	})
+}
+
+// replaceAddressCommand returns the command of the init container adding the replaced address to the JVM options
+func replaceAddressCommand(major int) string {
+	return fmt.Sprintf("if [ -f %[1]s/${POD_NAME} ]; then "+
+		"echo \"-Dcassandra.replace_address_first_boot=$(cat %[1]s/${POD_NAME})\" >> /etc-cassandra/%[2]s; fi",
+		replaceAddressDir, jvmOptionsFile(major))
+}
+
+func findRingMember(members []cassandrav1.RingMember, podName string) *cassandrav1.RingMember {
+	for i := range members {
+		if members[i].Pod == podName {
+			return &members[i]
+		}
+	}
+	return nil
+}
+
+// setRingMember adds or updates the member of the pod, the members are sorted by pod to avoid useless status updates
+func setRingMember(status *cassandrav1.CassandraClusterStatus, member cassandrav1.RingMember) {
+	if m := findRingMember(status.RingMembers, member.Pod); m != nil {
+		*m = member
+		return
+	}
+	status.RingMembers = append(status.RingMembers, member)
+	sort.Slice(status.RingMembers, func(i, j int) bool { return status.RingMembers[i].Pod < status.RingMembers[j].Pod })
+}
+
+func removeRingMember(status *cassandrav1.CassandraClusterStatus, podName string) {
+	var members []cassandrav1.RingMember
+	for _, member := range status.RingMembers {
+		if member.Pod != podName {
+			members = append(members, member)
+		}
+	}
+	status.RingMembers = members
+}
+
+func findHostID(nodes []admin.NodeStatus, hostID string) *admin.NodeStatus {
+	for i := range nodes {
+		if nodes[i].HostID == hostID {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
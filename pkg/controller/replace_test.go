@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+func newDataPVC(podName string, uid string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-" + podName, Namespace: testNamespace, UID: types.UID(uid)},
+	}
+}
+
+func TestReconcileReplacement(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Status.ConfigHash = "h1"
+	cc.Status.RingMembers = []cassandrav1.RingMember{
+		{Pod: "test-dc1-rack1-0", Address: "10.0.0.1", HostID: "host0", VolumeUID: "volume0"},
+		{Pod: "test-dc1-rack1-1", Address: "10.0.0.2", HostID: "host1", VolumeUID: "volume1"},
+	}
+	// the first pod came back with a new PVC
+	lost := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "h1", false)
+	seed := newNodePod(cc, "test-dc1-rack1-1", "cassandra:3.11", "h1", true)
+	seed.Status.PodIP = "10.0.0.2"
+	f := newFixture(t, cc, lost, seed, newDataPVC(lost.Name, "volume2"), newDataPVC(seed.Name, "volume1"))
+	f.node(seed.Name).Nodes = []admin.NodeStatus{
+		{Address: "10.0.0.1", HostID: "host0", State: admin.NodeNormal},
+		{Address: "10.0.0.2", HostID: "host1", Up: true, State: admin.NodeNormal},
+	}
+
+	if err := f.controller.reconcileReplacement(cc); err != nil {
+		t.Fatal(err)
+	}
+	r := cc.Status.Replacement
+	if r == nil || r.Pod != lost.Name || r.DeadAddress != "10.0.0.1" || r.Phase != cassandrav1.ReplacementStreaming {
+		t.Fatalf("got %+v, want the pod restarted to replace the dead node", r)
+	}
+	cm, err := f.kubeClient.CoreV1().ConfigMaps(testNamespace).Get(replaceAddressesConfigMapName("test"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm.Data[lost.Name] != "10.0.0.1" {
+		t.Errorf("got %v, want the address of the dead node", cm.Data)
+	}
+	if _, err := f.kubeClient.CoreV1().Pods(testNamespace).Get(lost.Name, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Error("the pod isn't restarted")
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Normal ReplacingNode") {
+		t.Errorf("got events %v, want a ReplacingNode event", events)
+	}
+
+	// the node took over the tokens of the dead node with a new address
+	replacing := newNodePod(cc, lost.Name, "cassandra:3.11", "h1", true)
+	replacing.Status.PodIP = "10.0.0.3"
+	f = newFixture(t, cc, replacing, seed, cm)
+	f.node(seed.Name).Nodes = []admin.NodeStatus{
+		{Address: "10.0.0.3", HostID: "host0", Up: true, State: admin.NodeNormal},
+		{Address: "10.0.0.2", HostID: "host1", Up: true, State: admin.NodeNormal},
+	}
+	if err := f.controller.reconcileReplacement(cc); err != nil {
+		t.Fatal(err)
+	}
+	if cc.Status.Replacement != nil || findRingMember(cc.Status.RingMembers, lost.Name) != nil {
+		t.Errorf("got %+v, want the replacement completed", cc.Status)
+	}
+	cm, err = f.kubeClient.CoreV1().ConfigMaps(testNamespace).Get(cm.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.Data[lost.Name]; ok {
+		t.Error("the address of the dead node is kept")
+	}
+}
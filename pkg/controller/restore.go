@@ -0,0 +1,415 @@
+package controller
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/backup"
+)
+
+const (
+	// restorePollInterval is the delay before checking again a restore waiting for its cluster
+	restorePollInterval = 30 * time.Second
+	// restoreDir is the directory of the node where the tables are staged before sstableloader streams them
+	restoreDir = "/cassandra_data/restore"
+	// initialTokensDir is where the initial tokens of the nodes of a restored cluster are mounted
+	initialTokensDir = "/initial-tokens"
+)
+
+// systemKeyspaces are created by Cassandra in each cluster, their tables aren't restored
+var systemKeyspaces = map[string]bool{
+	"system":             true,
+	"system_schema":      true,
+	"system_auth":        true,
+	"system_distributed": true,
+	"system_traces":      true,
+}
+
+// syncRestore drives a CassandraRestore through its phases. A new cluster is first deployed with the tokens of the
+// backup, then the schema is created and the files of each node of the backup are loaded one node at a time
+func (c *Controller) syncRestore(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+	r, err := c.cassandraRestoresLister.CassandraRestores(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if r.Status.Phase == cassandrav1.RestoreCompleted || r.Status.Phase == cassandrav1.RestoreFailed {
+		return nil
+	}
+	r = r.DeepCopy()
+
+	switch r.Status.Phase {
+	case "":
+		return c.startRestore(key, r)
+	case cassandrav1.RestoreSeeding:
+		return c.seedRestore(key, r)
+	case cassandrav1.RestoreLoading:
+		return c.loadRestore(r)
+	}
+	return nil
+}
+
+// startRestore reads the manifest of the backup and checks the cluster can receive it
+func (c *Controller) startRestore(key string, r *cassandrav1.CassandraRestore) error {
+	if r.Spec.Mode != cassandrav1.RestoreNewCluster && r.Spec.Mode != cassandrav1.RestoreExistingCluster {
+		return c.failRestore(r, fmt.Errorf("unknown restore mode %q", r.Spec.Mode))
+	}
+	store, err := c.objectStore(r.Namespace, r.Spec.Storage)
+	if err != nil {
+		return c.failRestore(r, err)
+	}
+	var manifest backup.Manifest
+	if err := backup.ReadJSON(store, backup.ManifestKey(r.Spec.SourceCluster, r.Spec.Backup), &manifest); err != nil {
+		return c.failRestore(r, fmt.Errorf("could not read the manifest of backup %s: %v", r.Spec.Backup, err))
+	}
+
+	cc, err := c.CassandraClustersLister.CassandraClusters(r.Namespace).Get(r.Spec.Cluster)
+	if errors.IsNotFound(err) && r.Spec.Mode == cassandrav1.RestoreNewCluster {
+		// the CassandraCluster to rebuild may be created after the restore
+		glog.V(2).Infof("restore %s waiting for CassandraCluster %s to be created", r.Name, r.Spec.Cluster)
+		c.restoreQueue.AddAfter(key, restorePollInterval)
+		return nil
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.failRestore(r, fmt.Errorf("CassandraCluster %s not found", r.Spec.Cluster))
+		}
+		return err
+	}
+	if err := checkRestoreTopology(cc, &manifest, r.Spec.Mode); err != nil {
+		return c.failRestore(r, err)
+	}
+
+	r.Status.StartTime = now()
+	r.Status.Nodes = nil
+	if r.Spec.Mode == cassandrav1.RestoreNewCluster {
+		stss, err := c.statefulsetsLister.StatefulSets(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+		if err != nil {
+			return err
+		}
+		if len(stss) > 0 || len(cc.Status.Nodes) > 0 {
+			return c.failRestore(r, fmt.Errorf("CassandraCluster %s is already deployed, its tokens can't be changed", cc.Name))
+		}
+		// each node of the backup is restored on the node of the same rack with the same ordinal
+		tokens := map[string]string{}
+		for _, node := range manifest.Nodes {
+			pod := restoreTargetPod(cc, node)
+			tokens[pod] = strings.Join(node.Tokens, ",")
+			r.Status.Nodes = append(r.Status.Nodes, cassandrav1.RestoreNodeStatus{
+				SourcePod: node.Pod,
+				Pod:       pod,
+				Phase:     cassandrav1.RestoreNodePending,
+			})
+		}
+		if err := c.createInitialTokens(cc, tokens); err != nil {
+			return err
+		}
+		r.Status.Phase = cassandrav1.RestoreSeeding
+		c.recorder.Eventf(r, corev1.EventTypeNormal, "Seeding", "Deploying CassandraCluster %s with the tokens of backup %s", cc.Name, r.Spec.Backup)
+		if err := c.persistRestoreStatus(r); err != nil {
+			return err
+		}
+		// the CassandraCluster was waiting for its tokens
+		c.workqueue.Add(r.Namespace + "/" + cc.Name)
+		return nil
+	}
+
+	if !clusterStable(cc) {
+		return fmt.Errorf("CassandraCluster %s is not stable, waiting to start the restore %s", cc.Name, r.Name)
+	}
+	// sstableloader streams the data to the nodes owning it so the files can be loaded from any node
+	for i, node := range manifest.Nodes {
+		r.Status.Nodes = append(r.Status.Nodes, cassandrav1.RestoreNodeStatus{
+			SourcePod: node.Pod,
+			Pod:       cc.Status.Nodes[i%len(cc.Status.Nodes)].Name,
+			Phase:     cassandrav1.RestoreNodePending,
+		})
+	}
+	r.Status.Phase = cassandrav1.RestoreLoading
+	c.recorder.Eventf(r, corev1.EventTypeNormal, "Loading", "Loading backup %s into CassandraCluster %s", r.Spec.Backup, cc.Name)
+	return c.persistRestoreStatus(r)
+}
+
+// seedRestore waits for the new cluster to be running then creates the schema of the backup
+func (c *Controller) seedRestore(key string, r *cassandrav1.CassandraRestore) error {
+	cc, err := c.CassandraClustersLister.CassandraClusters(r.Namespace).Get(r.Spec.Cluster)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.failRestore(r, fmt.Errorf("CassandraCluster %s was deleted", r.Spec.Cluster))
+		}
+		return err
+	}
+	if cc.Status.Phase != cassandrav1.ClusterPhaseRunning || !clusterStable(cc) {
+		glog.V(2).Infof("restore %s waiting for CassandraCluster %s to be running", r.Name, cc.Name)
+		c.restoreQueue.AddAfter(key, restorePollInterval)
+		return nil
+	}
+	if !r.Status.SchemaRestored {
+		store, err := c.objectStore(r.Namespace, r.Spec.Storage)
+		if err != nil {
+			return err
+		}
+		if err := c.restoreSchema(store, r, cc.Status.Nodes[0].Name); err != nil {
+			return c.failRestore(r, err)
+		}
+		r.Status.SchemaRestored = true
+	}
+	r.Status.Phase = cassandrav1.RestoreLoading
+	return c.persistRestoreStatus(r)
+}
+
+// loadRestore restores the files of the next node of the backup, the status update requeues the restore for the
+// following one
+func (c *Controller) loadRestore(r *cassandrav1.CassandraRestore) error {
+	store, err := c.objectStore(r.Namespace, r.Spec.Storage)
+	if err != nil {
+		return err
+	}
+	for i := range r.Status.Nodes {
+		node := &r.Status.Nodes[i]
+		if node.Phase != cassandrav1.RestoreNodePending {
+			continue
+		}
+		var nodeManifest backup.NodeManifest
+		if err := backup.ReadJSON(store, backup.NodeManifestKey(r.Spec.SourceCluster, r.Spec.Backup, node.SourcePod), &nodeManifest); err != nil {
+			return fmt.Errorf("could not read the manifest of node %s: %v", node.SourcePod, err)
+		}
+		if err := c.restoreNode(r, store, &nodeManifest, node); err != nil {
+			node.Phase = cassandrav1.RestoreNodeFailed
+			node.Message = err.Error()
+			return c.failRestore(r, fmt.Errorf("could not restore node %s on %s: %v", node.SourcePod, node.Pod, err))
+		}
+		node.Phase = cassandrav1.RestoreNodeRestored
+		glog.Infof("restored %d files of node %s on %s for restore %s", node.Files, node.SourcePod, node.Pod, r.Name)
+		return c.persistRestoreStatus(r)
+	}
+
+	// the tokens are only used at the first boot of the nodes
+	if r.Spec.Mode == cassandrav1.RestoreNewCluster {
+		err := c.kubeClientset.CoreV1().ConfigMaps(r.Namespace).Delete(initialTokensConfigMapName(r.Spec.Cluster), &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	r.Status.Phase = cassandrav1.RestoreCompleted
+	r.Status.CompletionTime = now()
+	c.recorder.Eventf(r, corev1.EventTypeNormal, "RestoreCompleted", "Backup %s restored into CassandraCluster %s", r.Spec.Backup, r.Spec.Cluster)
+	return c.persistRestoreStatus(r)
+}
+
+// restoreNode copies the files of a node of the backup to the target pod and loads them. In a new cluster the files
+// are copied in the table directories and loaded with nodetool refresh as the pod owns the same tokens, otherwise
+// they are staged and streamed to the cluster with sstableloader
+func (c *Controller) restoreNode(r *cassandrav1.CassandraRestore, store backup.ObjectStore, nodeManifest *backup.NodeManifest, node *cassandrav1.RestoreNodeStatus) error {
+	pod, err := c.podLister.Pods(r.Namespace).Get(node.Pod)
+	if err != nil {
+		return err
+	}
+	include := map[string]bool{}
+	for _, keyspace := range r.Spec.Keyspaces {
+		include[keyspace] = true
+	}
+	node.Files = 0
+	for _, table := range nodeManifest.Tables {
+		if systemKeyspaces[table.Keyspace] || len(include) > 0 && !include[table.Keyspace] {
+			continue
+		}
+		var dir string
+		if r.Spec.Mode == cassandrav1.RestoreNewCluster {
+			// the table was created by the schema with a new id
+			stdout, stderr, err := c.ExecCmd(pod.Name, []string{"/bin/sh", "-c", fmt.Sprintf("ls -d %s/%s/%s-*", cassandraDataDir, table.Keyspace, table.Table)})
+			if err != nil {
+				return fmt.Errorf("table %s.%s not found: %v %s", table.Keyspace, table.Table, err, stderr)
+			}
+			dir = strings.TrimSpace(strings.Split(stdout, "\n")[0])
+		} else {
+			// sstableloader expects the files in a <keyspace>/<table> directory
+			dir = path.Join(restoreDir, table.Keyspace, table.Table)
+			if _, stderr, err := c.ExecCmd(pod.Name, []string{"mkdir", "-p", dir}); err != nil {
+				return fmt.Errorf("could not create %s: %v %s", dir, err, stderr)
+			}
+		}
+		for _, file := range table.Files {
+			if err := c.downloadFile(store, pod.Name, file.Key, path.Join(dir, file.Name)); err != nil {
+				return err
+			}
+			node.Files++
+		}
+		cmd := []string{"nodetool", "refresh", table.Keyspace, table.Table}
+		if r.Spec.Mode != cassandrav1.RestoreNewCluster {
+			cmd = sstableloaderCmd(pod.Status.PodIP, dir)
+		}
+		if _, stderr, err := c.ExecCmd(pod.Name, cmd); err != nil {
+			return fmt.Errorf("could not load %s.%s: %v %s", table.Keyspace, table.Table, err, stderr)
+		}
+	}
+	return nil
+}
+
+// sstableloaderCmd returns the command streaming the tables of the directory to the cluster, then removing them.
+// The internode encryption comes from cassandra.yaml, the credentials of cqlsh are used to log in and the
+// truststore of the node to check the certificates when the clients use TLS
+func sstableloaderCmd(address string, dir string) []string {
+	script := fmt.Sprintf(`tls=; if [ -n "$CQLSH_SSL" ]; then `+
+		`tls="-ts %[2]s -tspw $(sed -n 's/^ *truststore_password: *//p' %[1]s/cassandra.yaml | head -n 1 | tr -d "'\"")"; fi; `+
+		`sstableloader -f %[1]s/cassandra.yaml ${CQLSH_USERNAME:+-u "$CQLSH_USERNAME" -pw "$CQLSH_PASSWORD"} $tls -d "$1" "$2" && rm -rf "$2"`,
+		cassandraConfDir, tlsDir+"/"+truststoreKey)
+	return []string{"/bin/sh", "-c", script, "sstableloader", address, dir}
+}
+
+// downloadFile streams an object of the store to a file of the pod
+func (c *Controller) downloadFile(store backup.ObjectStore, pod string, key string, file string) error {
+	reader, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if _, stderr, err := c.ExecCmdInput(pod, []string{"/bin/sh", "-c", fmt.Sprintf("cat > '%s'", file)}, reader); err != nil {
+		return fmt.Errorf("could not write %s: %v %s", file, err, stderr)
+	}
+	return nil
+}
+
+// restoreSchema creates the keyspaces and tables of the backup
+func (c *Controller) restoreSchema(store backup.ObjectStore, r *cassandrav1.CassandraRestore, podName string) error {
+	var manifest backup.Manifest
+	if err := backup.ReadJSON(store, backup.ManifestKey(r.Spec.SourceCluster, r.Spec.Backup), &manifest); err != nil {
+		return err
+	}
+	reader, err := store.Get(manifest.Schema)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, stderr, err := c.ExecCmdInput(podName, []string{"/bin/sh", "-c", "cat > /tmp/schema.cql && cqlsh $CQLSH_SSL ${CQLSH_USERNAME:+-u \"$CQLSH_USERNAME\" -p \"$CQLSH_PASSWORD\"} $POD_IP -f /tmp/schema.cql"}, reader)
+	if err != nil {
+		return fmt.Errorf("could not create the schema of backup %s: %v %s", r.Spec.Backup, err, stderr)
+	}
+	return nil
+}
+
+// checkRestoreTopology refuses a restore in a cluster which can't hold the backup. The datacenters of the backup
+// must exist in the cluster as the replication of the keyspaces refers to them, and a new cluster must have the
+// same racks with the same number of nodes and tokens to own the same data
+func checkRestoreTopology(cc *cassandrav1.CassandraCluster, manifest *backup.Manifest, mode cassandrav1.RestoreMode) error {
+	dcs := map[string]bool{}
+	racks := map[string]int32{}
+	for _, rack := range getRacks(cc) {
+		dcs[rack.DC.Name] = true
+		racks[rack.DC.Name+"/"+rack.Rack.Name] = rack.Nodes
+	}
+	backupRacks := map[string]int32{}
+	for _, node := range manifest.Nodes {
+		if !dcs[node.DC] {
+			return fmt.Errorf("datacenter %s of the backup doesn't exist in CassandraCluster %s", node.DC, cc.Name)
+		}
+		if mode == cassandrav1.RestoreNewCluster && cc.Spec.CassandraSpec.NbToken > 0 && len(node.Tokens) != cc.Spec.CassandraSpec.NbToken {
+			return fmt.Errorf("node %s of the backup has %d tokens, CassandraCluster %s uses %d", node.Pod, len(node.Tokens), cc.Name, cc.Spec.CassandraSpec.NbToken)
+		}
+		backupRacks[node.DC+"/"+node.Rack]++
+	}
+	if mode != cassandrav1.RestoreNewCluster {
+		return nil
+	}
+	var names []string
+	for name := range racks {
+		names = append(names, name)
+	}
+	for name := range backupRacks {
+		if _, ok := racks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if racks[name] != backupRacks[name] {
+			return fmt.Errorf("rack %s has %d nodes in the backup and %d in CassandraCluster %s", name, backupRacks[name], racks[name], cc.Name)
+		}
+	}
+	return nil
+}
+
+// restoreTargetPod returns the pod of the new cluster restoring a node of the backup: the pod of the same rack
+// with the same ordinal
+func restoreTargetPod(cc *cassandrav1.CassandraCluster, node backup.NodeManifest) string {
+	ordinal := node.Pod[strings.LastIndex(node.Pod, "-")+1:]
+	for _, rack := range getRacks(cc) {
+		if rack.DC.Name == node.DC && rack.Rack.Name == node.Rack {
+			return rackStatefulSetName(cc, rack) + "-" + ordinal
+		}
+	}
+	return ""
+}
+
+// initialTokensConfigMapName returns the name of the configmap holding the initial tokens of the nodes of a cluster
+func initialTokensConfigMapName(ccName string) string {
+	return ccName + "-initial-tokens"
+}
+
+// createInitialTokens stores the tokens of each pod of the cluster in a configmap mounted by the statefulsets
+func (c *Controller) createInitialTokens(cc *cassandrav1.CassandraCluster, tokens map[string]string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: initialTokensConfigMapName(cc.Name),
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Data: tokens,
+	}
+	return c.createOrUpdate(cc, c.configMapClient(), cm, mergeConfigMap(cm))
+}
+
+// waitingForRestore returns true if the CassandraCluster isn't deployed yet and a restore will provide
+// the tokens of its nodes
+func (c *Controller) waitingForRestore(cc *cassandrav1.CassandraCluster) (bool, error) {
+	if len(cc.Status.Nodes) > 0 {
+		return false, nil
+	}
+	restores, err := c.cassandraRestoresLister.CassandraRestores(cc.Namespace).List(labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for _, r := range restores {
+		if r.Spec.Cluster == cc.Name && r.Spec.Mode == cassandrav1.RestoreNewCluster && r.Status.Phase == "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// failRestore marks the restore as failed
+func (c *Controller) failRestore(r *cassandrav1.CassandraRestore, err error) error {
+	glog.Errorf("restore %s failed: %v", r.Name, err)
+	r.Status.Phase = cassandrav1.RestoreFailed
+	r.Status.Message = err.Error()
+	r.Status.CompletionTime = now()
+	c.recorder.Event(r, corev1.EventTypeWarning, "RestoreFailed", err.Error())
+	return c.persistRestoreStatus(r)
+}
+
+// persistRestoreStatus writes the status of the restore
+func (c *Controller) persistRestoreStatus(r *cassandrav1.CassandraRestore) error {
+	_, err := c.cassandraClusterClientset.CassandraV1().CassandraRestores(r.Namespace).UpdateStatus(r)
+	return err
+}
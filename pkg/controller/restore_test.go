@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/backup"
+)
+
+// newBackupNode returns the manifest of a node of a backup with the tokens
+func newBackupNode(pod string, dc string, rack string, tokens ...string) backup.NodeManifest {
+	return backup.NodeManifest{Pod: pod, DC: dc, Rack: rack, Tokens: tokens}
+}
+
+// newTwoRacksCluster returns a cluster of 2 nodes in dc1 spread on racks a and b, and 1 node in dc2
+func newTwoRacksCluster(name string) *cassandrav1.CassandraCluster {
+	cc := newCassandraCluster(name)
+	two, one := int32(2), int32(1)
+	cc.Spec.Datacenters = []cassandrav1.Datacenter{
+		{Name: "dc1", NbNodes: &two, Racks: []cassandrav1.Rack{{Name: "a"}, {Name: "b"}}},
+		{Name: "dc2", NbNodes: &one},
+	}
+	cc.Spec.CassandraSpec.NbToken = 1
+	return cc
+}
+
+func TestCheckRestoreTopology(t *testing.T) {
+	cc := newTwoRacksCluster("test")
+	nodes := []backup.NodeManifest{
+		newBackupNode("prod-dc1-a-0", "dc1", "a", "-100"),
+		newBackupNode("prod-dc1-b-0", "dc1", "b", "0"),
+		newBackupNode("prod-dc2-rack1-0", "dc2", "rack1", "100"),
+	}
+	tests := []struct {
+		nodes []backup.NodeManifest
+		mode  cassandrav1.RestoreMode
+		err   string
+	}{
+		{nodes, cassandrav1.RestoreNewCluster, ""},
+		{nodes, cassandrav1.RestoreExistingCluster, ""},
+		// the existing clusters only need the datacenters of the backup
+		{nodes[:1], cassandrav1.RestoreExistingCluster, ""},
+		{nodes[:1], cassandrav1.RestoreNewCluster, "rack dc1/b has 0 nodes in the backup and 1 in CassandraCluster test"},
+		{append(nodes[:2:2], newBackupNode("prod-dc3-rack1-0", "dc3", "rack1", "100")), cassandrav1.RestoreExistingCluster,
+			"datacenter dc3 of the backup doesn't exist in CassandraCluster test"},
+		{append(nodes[:2:2], newBackupNode("prod-dc2-rack2-0", "dc2", "rack2", "100")), cassandrav1.RestoreNewCluster,
+			"rack dc2/rack1 has 0 nodes in the backup and 1 in CassandraCluster test"},
+		{append(nodes[:2:2], newBackupNode("prod-dc2-rack1-0", "dc2", "rack1", "100", "200")), cassandrav1.RestoreNewCluster,
+			"node prod-dc2-rack1-0 of the backup has 2 tokens, CassandraCluster test uses 1"},
+		{append(nodes[:2:2], newBackupNode("prod-dc2-rack1-0", "dc2", "rack1", "100", "200")), cassandrav1.RestoreExistingCluster, ""},
+	}
+	for i, test := range tests {
+		err := checkRestoreTopology(cc, &backup.Manifest{Nodes: test.nodes}, test.mode)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%d: got %v, want %q", i, err, test.err)
+		}
+	}
+}
+
+func TestRestoreTargetPod(t *testing.T) {
+	cc := newTwoRacksCluster("test")
+	tests := []struct {
+		node backup.NodeManifest
+		pod  string
+	}{
+		{newBackupNode("prod-dc1-b-0", "dc1", "b"), "test-dc1-b-0"},
+		{newBackupNode("prod-dc2-rack1-3", "dc2", "rack1"), "test-dc2-rack1-3"},
+		// the first rack of the clusters deployed before the racks
+		{newBackupNode("prod-2", "dc1", "a"), "test-dc1-a-2"},
+		{newBackupNode("prod-dc3-rack1-0", "dc3", "rack1"), ""},
+	}
+	for _, test := range tests {
+		if pod := restoreTargetPod(cc, test.node); pod != test.pod {
+			t.Errorf("%s: got %q, want %q", test.node.Pod, pod, test.pod)
+		}
+	}
+
+	cc.Status.Legacy = true
+	if pod := restoreTargetPod(cc, newBackupNode("prod-1", "dc1", "a")); pod != "test-1" {
+		t.Errorf("got %q, want the pod of the legacy statefulset", pod)
+	}
+}
+
+func TestSstableloaderCmd(t *testing.T) {
+	cmd := sstableloaderCmd("10.0.0.1", restoreDir+"/orders/items")
+	if len(cmd) != 6 || cmd[4] != "10.0.0.1" || cmd[5] != restoreDir+"/orders/items" {
+		t.Fatalf("got %v, want the address and the directory passed as arguments", cmd)
+	}
+	for _, option := range []string{
+		"-f " + cassandraConfDir + "/cassandra.yaml",
+		`-u "$CQLSH_USERNAME" -pw "$CQLSH_PASSWORD"`,
+		"-ts " + tlsDir + "/" + truststoreKey + " -tspw",
+	} {
+		if !strings.Contains(cmd[2], option) {
+			t.Errorf("got script %q, want %s", cmd[2], option)
+		}
+	}
+}
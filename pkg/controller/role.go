@@ -0,0 +1,351 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	roleFinalizer = "cassandra/role"
+	// roleSyncInterval is the time between two checks of the permissions of a role
+	roleSyncInterval = 5 * time.Minute
+)
+
+// keyspacePermissions and tablePermissions are the permissions given by ALL on the data resources
+var (
+	keyspacePermissions = []string{"CREATE", "ALTER", "DROP", "SELECT", "MODIFY", "AUTHORIZE"}
+	tablePermissions    = []string{"ALTER", "DROP", "SELECT", "MODIFY", "AUTHORIZE"}
+)
+
+// syncRole applies a CassandraRole to its cluster
+func (c *Controller) syncRole(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+	r, err := c.cassandraRolesLister.CassandraRoles(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	r = r.DeepCopy()
+
+	if r.DeletionTimestamp != nil {
+		return c.deleteRole(r)
+	}
+	if !containsString(r.Finalizers, roleFinalizer) {
+		// the update requeues the role
+		r.Finalizers = append(r.Finalizers, roleFinalizer)
+		_, err := c.cassandraClusterClientset.CassandraV1().CassandraRoles(r.Namespace).Update(r)
+		return err
+	}
+
+	cc, err := c.CassandraClustersLister.CassandraClusters(r.Namespace).Get(r.Spec.Cluster)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.setRolePhase(r, cassandrav1.RolePending, fmt.Sprintf("CassandraCluster %s not found", r.Spec.Cluster))
+		}
+		return err
+	}
+	// requeued by the informer resync
+	if cc.Status.Phase != cassandrav1.ClusterPhaseRunning || !clusterStable(cc) ||
+		(passwordAuthentication(cc) && !cc.Status.AuthBootstrapped) {
+		return c.setRolePhase(r, cassandrav1.RolePending, fmt.Sprintf("waiting for CassandraCluster %s to be running", cc.Name))
+	}
+
+	password, passwordVersion, err := c.rolePassword(r)
+	if err != nil {
+		return c.failRole(r, err)
+	}
+	if r.Status.Phase == cassandrav1.RoleReady && r.Status.ObservedGeneration == r.Generation &&
+		r.Status.PasswordVersion == passwordVersion && r.Status.LastSyncTime != nil &&
+		time.Since(r.Status.LastSyncTime.Time) < roleSyncInterval {
+		return nil
+	}
+	if err := c.checkRole(cc, r); err != nil {
+		return c.failRole(r, err)
+	}
+	pod, err := c.readyPod(cc)
+	if err != nil {
+		return err
+	}
+	if err := c.applyRole(pod, r, password); err != nil {
+		c.recorder.Event(r, corev1.EventTypeWarning, "RoleFailed", err.Error())
+		r.Status.Phase = cassandrav1.RoleFailed
+		r.Status.Message = err.Error()
+		if err := c.persistRoleStatus(r); err != nil {
+			return err
+		}
+		// retried with the backoff of the queue
+		return err
+	}
+
+	if r.Status.Phase != cassandrav1.RoleReady || r.Status.ObservedGeneration != r.Generation {
+		c.recorder.Eventf(r, corev1.EventTypeNormal, "RoleSynced", "Role %s applied to CassandraCluster %s", roleName(r), cc.Name)
+	}
+	r.Status.Phase = cassandrav1.RoleReady
+	r.Status.ObservedGeneration = r.Generation
+	r.Status.PasswordVersion = passwordVersion
+	r.Status.LastSyncTime = now()
+	r.Status.Message = ""
+	return c.persistRoleStatus(r)
+}
+
+// checkRole rejects the roles the cluster can't apply
+func (c *Controller) checkRole(cc *cassandrav1.CassandraCluster, r *cassandrav1.CassandraRole) error {
+	name := roleName(r)
+	if name == cassandraRole {
+		return fmt.Errorf("role %s is managed by Cassandra", name)
+	}
+	if passwordAuthentication(cc) {
+		username, _, err := c.getSuperuser(cc)
+		if err != nil {
+			return err
+		}
+		if name == username {
+			return fmt.Errorf("role %s is the superuser of the operator", name)
+		}
+	} else if r.Spec.PasswordSecret != nil {
+		return fmt.Errorf("CassandraCluster %s doesn't use the %s", cc.Name, cassandrav1.PasswordAuthenticator)
+	}
+	if len(r.Spec.Grants) > 0 && !strings.HasSuffix(cc.Spec.CassandraSpec.Config.Authorizer, cassandrav1.CassandraAuthorizer) {
+		return fmt.Errorf("CassandraCluster %s doesn't use the %s, the permissions can't be granted", cc.Name, cassandrav1.CassandraAuthorizer)
+	}
+	_, err := desiredPermissions(r)
+	return err
+}
+
+// applyRole creates or alters the role and grants the permissions of the spec
+func (c *Controller) applyRole(pod *corev1.Pod, r *cassandrav1.CassandraRole, password string) error {
+	name := cqlString(roleName(r))
+	options := fmt.Sprintf("LOGIN = %t AND SUPERUSER = %t", r.Spec.Login, r.Spec.Superuser)
+	if password != "" {
+		options += " AND PASSWORD = " + cqlString(password)
+	}
+	statements := []string{
+		fmt.Sprintf("CREATE ROLE IF NOT EXISTS %s WITH %s", name, options),
+		fmt.Sprintf("ALTER ROLE %s WITH %s", name, options),
+	}
+	if _, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", strings.Join(statements, "; "))); err != nil {
+		return fmt.Errorf("could not create the role %s: %v %s", roleName(r), err, stderr)
+	}
+
+	stdout, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", fmt.Sprintf("LIST ALL PERMISSIONS OF %s NORECURSIVE", name)))
+	if err != nil {
+		return fmt.Errorf("could not list the permissions of the role %s: %v %s", roleName(r), err, stderr)
+	}
+	current := parsePermissions(stdout)
+	desired, err := desiredPermissions(r)
+	if err != nil {
+		return err
+	}
+	statements = nil
+	for _, permission := range sortedKeys(desired) {
+		if !current[permission] {
+			resource, perm := splitPermission(permission)
+			statements = append(statements, fmt.Sprintf("GRANT %s ON %s TO %s", perm, cqlResource(resource), name))
+		}
+	}
+	for _, permission := range sortedKeys(current) {
+		if !desired[permission] {
+			resource, perm := splitPermission(permission)
+			glog.Infof("revoking %s on %s from role %s of CassandraRole %s", perm, resource, roleName(r), r.Name)
+			statements = append(statements, fmt.Sprintf("REVOKE %s ON %s FROM %s", perm, cqlResource(resource), name))
+		}
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+	if _, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", strings.Join(statements, "; "))); err != nil {
+		return fmt.Errorf("could not update the permissions of the role %s: %v %s", roleName(r), err, stderr)
+	}
+	return nil
+}
+
+// deleteRole drops the role and removes the finalizer
+func (c *Controller) deleteRole(r *cassandrav1.CassandraRole) error {
+	if !containsString(r.Finalizers, roleFinalizer) {
+		return nil
+	}
+	cc, err := c.CassandraClustersLister.CassandraClusters(r.Namespace).Get(r.Spec.Cluster)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && cc.DeletionTimestamp == nil && r.Status.ObservedGeneration > 0 {
+		if cc.Status.Phase != cassandrav1.ClusterPhaseRunning {
+			return fmt.Errorf("CassandraCluster %s is not running, waiting to drop the role %s", cc.Name, roleName(r))
+		}
+		pod, err := c.readyPod(cc)
+		if err != nil {
+			return err
+		}
+		drop := fmt.Sprintf("DROP ROLE IF EXISTS %s", cqlString(roleName(r)))
+		if _, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", drop)); err != nil {
+			return fmt.Errorf("could not drop the role %s: %v %s", roleName(r), err, stderr)
+		}
+		c.recorder.Eventf(r, corev1.EventTypeNormal, "RoleDropped", "Role %s dropped from CassandraCluster %s", roleName(r), cc.Name)
+	}
+	r.Finalizers = removeString(r.Finalizers, roleFinalizer)
+	_, err = c.cassandraClusterClientset.CassandraV1().CassandraRoles(r.Namespace).Update(r)
+	return err
+}
+
+// rolePassword returns the password of the role and the version of its secret
+func (c *Controller) rolePassword(r *cassandrav1.CassandraRole) (string, string, error) {
+	if r.Spec.PasswordSecret == nil {
+		return "", "", nil
+	}
+	secret, err := c.kubeClientset.CoreV1().Secrets(r.Namespace).Get(r.Spec.PasswordSecret.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("could not get the password of the role: %v", err)
+	}
+	password := string(secret.Data[r.Spec.PasswordSecret.Key])
+	if password == "" {
+		return "", "", fmt.Errorf("secret %s has no %s", secret.Name, r.Spec.PasswordSecret.Key)
+	}
+	return password, secret.ResourceVersion, nil
+}
+
+// setRolePhase updates the status of the role when it changes
+func (c *Controller) setRolePhase(r *cassandrav1.CassandraRole, phase cassandrav1.RolePhase, message string) error {
+	if r.Status.Phase == phase && r.Status.Message == message {
+		return nil
+	}
+	glog.Infof("CassandraRole %s: %s", r.Name, message)
+	r.Status.Phase = phase
+	r.Status.Message = message
+	return c.persistRoleStatus(r)
+}
+
+// failRole records an error of the spec of the role
+func (c *Controller) failRole(r *cassandrav1.CassandraRole, err error) error {
+	if r.Status.Phase != cassandrav1.RoleFailed || r.Status.Message != err.Error() {
+		c.recorder.Event(r, corev1.EventTypeWarning, "RoleFailed", err.Error())
+	}
+	return c.setRolePhase(r, cassandrav1.RoleFailed, err.Error())
+}
+
+func (c *Controller) persistRoleStatus(r *cassandrav1.CassandraRole) error {
+	_, err := c.cassandraClusterClientset.CassandraV1().CassandraRoles(r.Namespace).UpdateStatus(r)
+	return err
+}
+
+// roleName returns the name of the role in Cassandra
+func roleName(r *cassandrav1.CassandraRole) string {
+	if r.Spec.RoleName != "" {
+		return r.Spec.RoleName
+	}
+	return r.Name
+}
+
+// desiredPermissions returns the permissions of the grants as "<resource>|<permission>"
+func desiredPermissions(r *cassandrav1.CassandraRole) (map[string]bool, error) {
+	permissions := map[string]bool{}
+	for _, grant := range r.Spec.Grants {
+		if grant.Keyspace != "" && !identifierRegexp.MatchString(grant.Keyspace) {
+			return nil, fmt.Errorf("%q isn't a valid keyspace name", grant.Keyspace)
+		}
+		if grant.Table != "" && !identifierRegexp.MatchString(grant.Table) {
+			return nil, fmt.Errorf("%q isn't a valid table name", grant.Table)
+		}
+		resource, allowed := "<all keyspaces>", keyspacePermissions
+		if grant.Keyspace != "" {
+			resource = "<keyspace " + strings.ToLower(grant.Keyspace) + ">"
+		} else if grant.Table != "" {
+			return nil, fmt.Errorf("table %s has no keyspace", grant.Table)
+		}
+		if grant.Table != "" {
+			resource, allowed = "<table "+strings.ToLower(grant.Keyspace+"."+grant.Table)+">", tablePermissions
+		}
+		for _, permission := range grant.Permissions {
+			permission = strings.ToUpper(permission)
+			if permission == "ALL" {
+				for _, p := range allowed {
+					permissions[resource+"|"+p] = true
+				}
+				continue
+			}
+			if !containsString(allowed, permission) {
+				return nil, fmt.Errorf("permission %s can't be granted on %s", permission, resource)
+			}
+			permissions[resource+"|"+permission] = true
+		}
+	}
+	return permissions, nil
+}
+
+// parsePermissions returns the permissions on the keyspaces and tables listed by LIST PERMISSIONS:
+//
+//	 role | username | resource          | permission
+//	------+----------+-------------------+------------
+//	 app  |      app | <keyspace orders> |     SELECT
+func parsePermissions(output string) map[string]bool {
+	permissions := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) != 4 {
+			continue
+		}
+		resource, permission := strings.TrimSpace(fields[2]), strings.TrimSpace(fields[3])
+		if resource == "<all keyspaces>" || strings.HasPrefix(resource, "<keyspace ") || strings.HasPrefix(resource, "<table ") {
+			permissions[resource+"|"+permission] = true
+		}
+	}
+	return permissions
+}
+
+func splitPermission(permission string) (string, string) {
+	i := strings.LastIndex(permission, "|")
+	return permission[:i], permission[i+1:]
+}
+
+// cqlResource converts <keyspace orders> to KEYSPACE orders
+func cqlResource(resource string) string {
+	resource = strings.TrimSuffix(strings.TrimPrefix(resource, "<"), ">")
+	fields := strings.SplitN(resource, " ", 2)
+	if len(fields) == 1 {
+		return strings.ToUpper(resource)
+	}
+	if fields[0] == "all" {
+		return "ALL KEYSPACES"
+	}
+	return strings.ToUpper(fields[0]) + " " + fields[1]
+}
+
+// cqlString quotes a CQL string literal
+func cqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func removeString(list []string, s string) []string {
+	var result []string
+	for _, item := range list {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
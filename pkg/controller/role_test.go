@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func newCassandraRole(name string, cluster string) *cassandrav1.CassandraRole {
+	return &cassandrav1.CassandraRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  testNamespace,
+			Generation: 1,
+			Finalizers: []string{roleFinalizer},
+		},
+		Spec: cassandrav1.CassandraRoleSpec{Cluster: cluster, Login: true},
+	}
+}
+
+func TestSyncRole(t *testing.T) {
+	cc := newRunningCluster("test")
+	cc.Spec.CassandraSpec.Config.Authorizer = cassandrav1.CassandraAuthorizer
+	cc.Status.AuthBootstrapped = true
+	pod := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	pod.Status.PodIP = "10.0.0.1"
+	r := newCassandraRole("app", "test")
+	r.Spec.Grants = []cassandrav1.CassandraGrant{{Permissions: []string{"SELECT"}, Keyspace: "Orders"}}
+	f := newFixture(t, cc, pod, r)
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		if strings.Contains(strings.Join(cmd, " "), "LIST ALL PERMISSIONS") {
+			return " role | username | resource | permission\n" +
+				"------+----------+-------------------+------------\n" +
+				"  app |      app | <keyspace orders> |     MODIFY\n" +
+				"  app |      app |     <role other> |  AUTHORIZE\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	if err := f.controller.syncRole(testNamespace + "/app"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"CREATE ROLE IF NOT EXISTS 'app' WITH LOGIN = true AND SUPERUSER = false; ALTER ROLE 'app' WITH LOGIN = true AND SUPERUSER = false",
+		"LIST ALL PERMISSIONS OF 'app' NORECURSIVE",
+		"GRANT SELECT ON KEYSPACE orders TO 'app'; REVOKE MODIFY ON KEYSPACE orders FROM 'app'",
+	}
+	if len(f.commands) != len(want) {
+		t.Fatalf("got commands %v, want %d commands", f.commands, len(want))
+	}
+	for i, command := range f.commands {
+		if !strings.HasSuffix(command, want[i]) {
+			t.Errorf("got command %q, want %q", command, want[i])
+		}
+	}
+	r, err := f.client.CassandraV1().CassandraRoles(testNamespace).Get("app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status.Phase != cassandrav1.RoleReady || r.Status.ObservedGeneration != 1 || r.Status.LastSyncTime == nil {
+		t.Errorf("got %+v, want the role ready", r.Status)
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Normal RoleSynced") {
+		t.Errorf("got events %v, want a RoleSynced event", events)
+	}
+}
+
+func TestSyncRoleWaitsForCluster(t *testing.T) {
+	cc := newRunningCluster("test")
+	r := newCassandraRole("app", "test")
+	f := newFixture(t, cc, r)
+	if err := f.controller.syncRole(testNamespace + "/app"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.commands) != 0 {
+		t.Errorf("got commands %v before the superuser is bootstrapped", f.commands)
+	}
+	r, err := f.client.CassandraV1().CassandraRoles(testNamespace).Get("app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status.Phase != cassandrav1.RolePending {
+		t.Errorf("got phase %s, want %s", r.Status.Phase, cassandrav1.RolePending)
+	}
+}
+
+func TestDeleteRole(t *testing.T) {
+	cc := newRunningCluster("test")
+	pod := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	r := newCassandraRole("app", "test")
+	r.Spec.RoleName = "o'neil"
+	r.Status.ObservedGeneration = 1
+	f := newFixture(t, cc, pod, r)
+	if err := f.controller.deleteRole(r); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.commands) != 1 || !strings.HasSuffix(f.commands[0], "DROP ROLE IF EXISTS 'o''neil'") {
+		t.Errorf("got commands %v, want the role dropped", f.commands)
+	}
+	r, err := f.client.CassandraV1().CassandraRoles(testNamespace).Get("app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containsString(r.Finalizers, roleFinalizer) {
+		t.Error("the finalizer isn't removed")
+	}
+}
+
+func TestDesiredPermissions(t *testing.T) {
+	r := newCassandraRole("app", "test")
+	r.Spec.Grants = []cassandrav1.CassandraGrant{
+		{Permissions: []string{"select"}},
+		{Permissions: []string{"ALL"}, Keyspace: "orders", Table: "Items"},
+	}
+	permissions, err := desiredPermissions(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(permissions) != 1+len(tablePermissions) || !permissions["<all keyspaces>|SELECT"] || !permissions["<table orders.items>|MODIFY"] {
+		t.Errorf("got %v", permissions)
+	}
+
+	for _, grant := range []cassandrav1.CassandraGrant{
+		{Permissions: []string{"SELECT"}, Table: "items"},
+		{Permissions: []string{"CREATE"}, Keyspace: "orders", Table: "items"},
+		{Permissions: []string{"SELECT"}, Keyspace: "orders TO 'app'; DROP KEYSPACE orders; --"},
+		{Permissions: []string{"SELECT"}, Keyspace: "orders", Table: "items; DROP TABLE orders.items"},
+		{Permissions: []string{"SELECT"}, Keyspace: `"Orders"`},
+	} {
+		r.Spec.Grants = []cassandrav1.CassandraGrant{grant}
+		if _, err := desiredPermissions(r); err == nil {
+			t.Errorf("grant %+v: got no error", grant)
+		}
+	}
+}
+
+func TestCqlResource(t *testing.T) {
+	tests := map[string]string{
+		"<all keyspaces>":      "ALL KEYSPACES",
+		"<keyspace orders>":    "KEYSPACE orders",
+		"<table orders.items>": "TABLE orders.items",
+	}
+	for resource, want := range tests {
+		if got := cqlResource(resource); got != want {
+			t.Errorf("%s: got %s, want %s", resource, got, want)
+		}
+	}
+}
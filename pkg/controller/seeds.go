@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	// default number of seeds of each datacenter
+	defaultSeedsPerDatacenter = 3
+	// seedLabel marks the pods selected as seeds, they are the endpoints of the seed service
+	seedLabel = "cassandraSeed"
+	// seedsKey is the key of the seeds in the seeds configmap
+	seedsKey = "seeds"
+)
+
+// reconcileSeeds selects the seeds of each datacenter, labels their pods so the seed service publishes them and
+// writes their DNS names in the seeds configmap read by the nodes when they start, so the selection changes without
+// restarting them. Only the pods whose node is already a member of the ring are selected, a new node listing itself
+// as seed wouldn't bootstrap. The seeds stay selected while they restart and are replaced when they are
+// decommissioned or replaced
+func (c *Controller) reconcileSeeds(cc *cassandrav1.CassandraCluster) error {
+	pods, err := c.podLister.Pods(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+	if err != nil {
+		return err
+	}
+	byName := map[string]*corev1.Pod{}
+	for _, pod := range pods {
+		byName[pod.Name] = pod
+	}
+	seeds := selectSeeds(cc, byName)
+
+	selected := map[string]bool{}
+	for _, seed := range seeds {
+		selected[seed] = true
+	}
+	for _, pod := range pods {
+		if (pod.Labels[seedLabel] == "true") == selected[pod.Name] {
+			continue
+		}
+		pod = pod.DeepCopy()
+		if selected[pod.Name] {
+			if pod.Labels == nil {
+				pod.Labels = map[string]string{}
+			}
+			pod.Labels[seedLabel] = "true"
+		} else {
+			delete(pod.Labels, seedLabel)
+		}
+		if _, err := c.kubeClientset.CoreV1().Pods(c.namespace).Update(pod); err != nil {
+			return fmt.Errorf("could not update the seed label of %s: %v", pod.Name, err)
+		}
+	}
+	if !reflect.DeepEqual(cc.Status.Seeds, seeds) {
+		glog.Infof("seeds of CassandraCluster %s: %v", cc.Name, seeds)
+		cc.Status.Seeds = seeds
+	}
+	return c.reconcileSeedsConfigMap(cc)
+}
+
+// reconcileSeedsConfigMap writes the seeds given to the nodes in the seeds configmap
+func (c *Controller) reconcileSeedsConfigMap(cc *cassandrav1.CassandraCluster) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: seedsConfigMapName(cc.Name),
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role":             "seeds",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Data: map[string]string{seedsKey: strings.Join(c.clusterSeeds(cc), ",")},
+	}
+	return c.createOrUpdate(cc, c.configMapClient(), cm, mergeConfigMap(cm))
+}
+
+// selectSeeds returns the seeds of each datacenter. The current seeds are kept while they are members of the ring,
+// the missing ones are picked among the lowest ordinals of the racks in turn, as they are the last to be removed by
+// a scale down. A new cluster starts with the first pod of the first rack as single seed
+func selectSeeds(cc *cassandrav1.CassandraCluster, pods map[string]*corev1.Pod) []string {
+	perDC := int32(defaultSeedsPerDatacenter)
+	if cc.Spec.SeedsPerDatacenter > 0 {
+		perDC = cc.Spec.SeedsPerDatacenter
+	}
+	candidate := func(name string) bool {
+		if pods[name] == nil || findRingMember(cc.Status.RingMembers, name) == nil {
+			return false
+		}
+		if d := cc.Status.Decommission; d != nil && d.Pod == name {
+			return false
+		}
+		if r := cc.Status.Replacement; r != nil && r.Pod == name {
+			return false
+		}
+		return true
+	}
+
+	var seeds []string
+	racks := getRacks(cc)
+	for _, dc := range getDatacenters(cc) {
+		var dcRacks []cassandraRack
+		for _, rack := range racks {
+			if rack.DC.Name == dc.Name {
+				dcRacks = append(dcRacks, rack)
+			}
+		}
+		var dcSeeds []string
+		for _, rack := range dcRacks {
+			for ordinal := int32(0); ordinal < rack.Nodes; ordinal++ {
+				name := fmt.Sprintf("%s-%d", rackStatefulSetName(cc, rack), ordinal)
+				if int32(len(dcSeeds)) < perDC && containsString(cc.Status.Seeds, name) && candidate(name) {
+					dcSeeds = append(dcSeeds, name)
+				}
+			}
+		}
+		for ordinal := int32(0); int32(len(dcSeeds)) < perDC; ordinal++ {
+			found := false
+			for _, rack := range dcRacks {
+				if ordinal >= rack.Nodes {
+					continue
+				}
+				found = true
+				name := fmt.Sprintf("%s-%d", rackStatefulSetName(cc, rack), ordinal)
+				if int32(len(dcSeeds)) < perDC && !containsString(dcSeeds, name) && candidate(name) {
+					dcSeeds = append(dcSeeds, name)
+				}
+			}
+			if !found {
+				break
+			}
+		}
+		seeds = append(seeds, dcSeeds...)
+	}
+
+	if len(seeds) == 0 && len(racks) > 0 {
+		first := fmt.Sprintf("%s-0", rackStatefulSetName(cc, racks[0]))
+		if pods[first] != nil {
+			seeds = []string{first}
+		}
+	}
+	return seeds
+}
+
+// seedServiceName returns the name of the headless service publishing the seeds of the cluster
+func seedServiceName(ccName string) string {
+	return ccName + "-seeds"
+}
+
+// seedsConfigMapName returns the name of the configmap holding the seeds given to the nodes
+func seedsConfigMapName(ccName string) string {
+	return ccName + "-seeds"
+}
+
+// clusterSeeds returns the seeds given to the nodes: the stable DNS names of the pods of the seeds under the
+// headless service of their datacenter, or of the first pod of the cluster until the seeds are selected
+func (c *Controller) clusterSeeds(cc *cassandrav1.CassandraCluster) []string {
+	seeds := cc.Status.Seeds
+	if racks := getRacks(cc); len(seeds) == 0 && len(racks) > 0 {
+		seeds = []string{fmt.Sprintf("%s-0", rackStatefulSetName(cc, racks[0]))}
+	}
+	var addresses []string
+	for _, seed := range seeds {
+		for _, rack := range getRacks(cc) {
+			ordinal := strings.TrimPrefix(seed, rackStatefulSetName(cc, rack)+"-")
+			if _, err := strconv.Atoi(ordinal); err == nil && ordinal != seed {
+				addresses = append(addresses, fmt.Sprintf("%s.%s.%s.svc.cluster.local", seed, datacenterServiceName(cc, rack.DC), c.namespace))
+				break
+			}
+		}
+	}
+	return addresses
+}
+
+// seedsCommand returns the command of the init container writing the seeds of the seeds configmap in the
+// cassandra.yaml, like the entrypoint of the Cassandra image does
+func seedsCommand() string {
+	return `sed -i "s/- seeds:.*/- seeds: \"${CASSANDRA_SEEDS}\"/" /etc-cassandra/cassandra.yaml`
+}
+
+// seedsEnv reads the seeds from the seeds configmap when the container starts
+func seedsEnv(cc *cassandrav1.CassandraCluster) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: "CASSANDRA_SEEDS",
+		ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: seedsConfigMapName(cc.Name)},
+				Key:                  seedsKey,
+			},
+		},
+	}
+}
+
+// BuildSeedService builds the headless service resolving to the seeds of the cluster
+func (c *Controller) BuildSeedService(cc *cassandrav1.CassandraCluster) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: seedServiceName(cc.Name),
+			Annotations: map[string]string{
+				"operatorVersion": cassandrav1.SchemeGroupVersion.Version,
+				// the seeds must be resolved while they start, before Kubernetes 1.9
+				"service.alpha.kubernetes.io/tolerate-unready-endpoints": "true",
+			},
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role":             "cassandraCluster",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"cassandraCluster": cc.Name,
+				seedLabel:          "true",
+			},
+			Ports: []corev1.ServicePort{
+				servicePort("intra-node", 7000),
+				servicePort("tls-intra-node", 7001),
+			},
+			ClusterIP:                "None",
+			PublishNotReadyAddresses: true,
+		},
+	}
+}
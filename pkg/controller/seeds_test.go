@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func newPod(cc *cassandrav1.CassandraCluster, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels:    map[string]string{"cassandraCluster": cc.Name},
+		},
+	}
+}
+
+func TestClusterSeeds(t *testing.T) {
+	cc := newCassandraCluster("test")
+	two := int32(2)
+	cc.Spec.Datacenters = []cassandrav1.Datacenter{
+		{Name: "dc1", NbNodes: &two, Racks: []cassandrav1.Rack{{Name: "a"}, {Name: "a-1"}}},
+		{Name: "dc2", NbNodes: &two},
+	}
+	f := newFixture(t, cc)
+
+	// the first pod is the seed of a new cluster
+	want := []string{"test-dc1-a-0.test-dc1-node.default.svc.cluster.local"}
+	if seeds := f.controller.clusterSeeds(cc); !reflect.DeepEqual(seeds, want) {
+		t.Errorf("got %v, want %v", seeds, want)
+	}
+
+	cc.Status.Seeds = []string{"test-dc1-a-0", "test-dc1-a-1-0", "test-dc2-rack1-1"}
+	want = []string{
+		"test-dc1-a-0.test-dc1-node.default.svc.cluster.local",
+		"test-dc1-a-1-0.test-dc1-node.default.svc.cluster.local",
+		"test-dc2-rack1-1.test-dc2-node.default.svc.cluster.local",
+	}
+	if seeds := f.controller.clusterSeeds(cc); !reflect.DeepEqual(seeds, want) {
+		t.Errorf("got %v, want %v", seeds, want)
+	}
+}
+
+func TestSelectSeeds(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Racks = []cassandrav1.Rack{{Name: "r1"}, {Name: "r2"}}
+	cc.Spec.SeedsPerDatacenter = 2
+	pods := map[string]*corev1.Pod{}
+	for _, name := range []string{"test-dc1-r1-0", "test-dc1-r1-1", "test-dc1-r2-0"} {
+		pods[name] = newPod(cc, name)
+	}
+
+	// no node is in the ring yet
+	if seeds := selectSeeds(cc, pods); !reflect.DeepEqual(seeds, []string{"test-dc1-r1-0"}) {
+		t.Errorf("got %v, want the first pod", seeds)
+	}
+
+	for name := range pods {
+		cc.Status.RingMembers = append(cc.Status.RingMembers, cassandrav1.RingMember{Pod: name})
+	}
+	want := []string{"test-dc1-r1-0", "test-dc1-r2-0"}
+	if seeds := selectSeeds(cc, pods); !reflect.DeepEqual(seeds, want) {
+		t.Errorf("got %v, want the first pod of each rack %v", seeds, want)
+	}
+
+	// a seed being decommissioned is replaced
+	cc.Status.Seeds = want
+	cc.Status.Decommission = &cassandrav1.DecommissionStatus{Pod: "test-dc1-r2-0"}
+	want = []string{"test-dc1-r1-0", "test-dc1-r1-1"}
+	if seeds := selectSeeds(cc, pods); !reflect.DeepEqual(seeds, want) {
+		t.Errorf("got %v, want %v", seeds, want)
+	}
+}
+
+func TestReconcileSeeds(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.NbNodes = func(i int32) *int32 { return &i }(2)
+	pods := []runtime.Object{newPod(cc, "test-dc1-rack1-0"), newPod(cc, "test-dc1-rack1-1")}
+	f := newFixture(t, cc, pods...)
+	cc.Status.RingMembers = []cassandrav1.RingMember{{Pod: "test-dc1-rack1-0"}, {Pod: "test-dc1-rack1-1"}}
+
+	if err := f.controller.reconcileSeeds(cc); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := f.kubeClient.CoreV1().ConfigMaps(testNamespace).Get("test-seeds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "test-dc1-rack1-0.test-dc1-node.default.svc.cluster.local,test-dc1-rack1-1.test-dc1-node.default.svc.cluster.local"
+	if cm.Data[seedsKey] != want {
+		t.Errorf("got seeds %q, want %q", cm.Data[seedsKey], want)
+	}
+	if !metav1.IsControlledBy(cm, cc) {
+		t.Error("the seeds configmap isn't owned by the cluster")
+	}
+	pod, err := f.kubeClient.CoreV1().Pods(testNamespace).Get("test-dc1-rack1-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Labels[seedLabel] != "true" {
+		t.Errorf("the seed %s isn't labelled", pod.Name)
+	}
+
+	// the seeds are updated when the selection changes
+	cc.Status.Decommission = &cassandrav1.DecommissionStatus{Pod: "test-dc1-rack1-1"}
+	if err := f.controller.reconcileSeeds(cc); err != nil {
+		t.Fatal(err)
+	}
+	cm, err = f.kubeClient.CoreV1().ConfigMaps(testNamespace).Get("test-seeds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "test-dc1-rack1-0.test-dc1-node.default.svc.cluster.local"; cm.Data[seedsKey] != want {
+		t.Errorf("got seeds %q, want %q", cm.Data[seedsKey], want)
+	}
+}
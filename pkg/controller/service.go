@@ -5,72 +5,197 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/api/core/v1"
 	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
-
-	"encoding/xml"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func (c *Controller) DeleteService(svcName string) error{
-	err := c.kubeClientset.CoreV1().Services(c.namespace).Delete(svcName, &metav1.DeleteOptions{})
-	if errors.IsNotFound(err) {
-		err = nil
+// CreateOrUpdateServices reconciliates the internode, seed, client and metrics services of the cluster
+func (c *Controller) CreateOrUpdateServices(cc *cassandrav1.CassandraCluster) error {
+	services := []*v1.Service{c.BuildSeedService(cc), c.BuildClientService(cc), c.BuildMetricsService(cc)}
+	for _, dc := range getDatacenters(cc) {
+		services = append(services, c.BuildHeadlessService(cc, dc))
+	}
+	for _, svc := range services {
+		err := c.CreateOrUpdateService(cc, svc)
+		if err != nil {
+			return err
+		}
 	}
-	return err
+	return nil
 }
 
-func (c *Controller) CreateOrUpdateService(cc *cassandrav1.CassandraCluster) error {
-	// build the service
-	svc := c.BuildHeadlessService(cc)
+// CreateOrUpdateService creates the service or updates it, keeping the cluster IP, the allocated node ports and the
+// labels and annotations added by others
+func (c *Controller) CreateOrUpdateService(cc *cassandrav1.CassandraCluster, svc *v1.Service) error {
+	return c.createOrUpdate(cc, c.serviceClient(), svc, func(obj object) {
+		updated := obj.(*v1.Service)
+		updated.Labels = mergeMap(updated.Labels, svc.Labels)
+		updated.Annotations = mergeMap(updated.Annotations, svc.Annotations)
+		updated.Spec.Type = svc.Spec.Type
+		if updated.Spec.Type == "" {
+			updated.Spec.Type = v1.ServiceTypeClusterIP
+		}
+		updated.Spec.Selector = svc.Spec.Selector
+		updated.Spec.PublishNotReadyAddresses = svc.Spec.PublishNotReadyAddresses
+		updated.Spec.LoadBalancerSourceRanges = svc.Spec.LoadBalancerSourceRanges
+		ports := make([]v1.ServicePort, len(svc.Spec.Ports))
+		for i, port := range svc.Spec.Ports {
+			// the node ports allocated by Kubernetes are kept as long as the service exposes them
+			if port.NodePort == 0 && updated.Spec.Type != v1.ServiceTypeClusterIP {
+				for _, current := range updated.Spec.Ports {
+					if current.Name == port.Name {
+						port.NodePort = current.NodePort
+					}
+				}
+			}
+			ports[i] = port
+		}
+		updated.Spec.Ports = ports
+		if updated.Spec.Type == v1.ServiceTypeClusterIP {
+			updated.Spec.ExternalTrafficPolicy = ""
+		}
+	})
+}
 
+// serviceClient returns the client of the services, read from the cache
+func (c *Controller) serviceClient() objectClient {
 	client := c.kubeClientset.CoreV1().Services(c.namespace)
-	// TODO use lister instead of direct query ?
-	service, err := c.servicesLister.Services(c.namespace).Get(cc.Name)
-	//service, err := client.Get(svc.Name, metav1.GetOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return err
-	}
-
-	if errors.IsNotFound(err) {
-		_, err = client.Create(svc)
-		if err != nil {
+	return objectClient{
+		get: func(name string) (object, error) {
+			return c.servicesLister.Services(c.namespace).Get(name)
+		},
+		create: func(obj object) error {
+			_, err := client.Create(obj.(*v1.Service))
 			return err
-		}
-	} else {
-		svc.ResourceVersion = service.ResourceVersion
-		svc.Spec.ClusterIP = service.Spec.ClusterIP
-		_, err := client.Update(svc)
-		if err != nil && !errors.IsNotFound(err) {
+		},
+		update: func(obj object) error {
+			_, err := client.Update(obj.(*v1.Service))
+			if errors.IsNotFound(err) {
+				return nil
+			}
 			return err
-		}
+		},
 	}
-	return nil
 }
 
-func (c *Controller) BuildHeadlessService(cc *cassandrav1.CassandraCluster) *v1.Service{
+// BuildHeadlessService builds the internode service of a datacenter, resolving the pods before they are ready
+func (c *Controller) BuildHeadlessService(cc *cassandrav1.CassandraCluster, dc cassandrav1.Datacenter) *v1.Service{
 	service := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: cc.Name+"-node",
+			Name: datacenterServiceName(cc, dc),
 			Annotations: map[string]string{
-				"operatorVersion": cassandrav1.CassandraCluster.APIVersion,
-
+				"operatorVersion": cassandrav1.SchemeGroupVersion.Version,
+				// publishNotReadyAddresses is only supported from Kubernetes 1.9
+				"service.alpha.kubernetes.io/tolerate-unready-endpoints": "true",
 			},
 			Labels: map[string]string{
 				"cassandraCluster": cc.Name,
+				"cassandraDC": dc.Name,
 				"role": "cassandraCluster",
 			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
 		},
 		Spec: v1.ServiceSpec{
 			Selector: map[string]string{
 				"cassandraCluster": cc.Name,
+				"cassandraDC": dc.Name,
 			},
 			Ports: []v1.ServicePort{
-				{
-					Name: "cassandra",
-					Port: 9042,
-				},
+				servicePort("intra-node", 7000),
+				servicePort("tls-intra-node", 7001),
 			},
 			ClusterIP: "None",
+			PublishNotReadyAddresses: true,
 		},
 	}
 	return service
 }
 
+// BuildClientService builds the service exposing the CQL port of the ready nodes to the clients
+func (c *Controller) BuildClientService(cc *cassandrav1.CassandraCluster) *v1.Service{
+	spec := cc.Spec.ClientService
+	annotations := map[string]string{
+		"operatorVersion": cassandrav1.SchemeGroupVersion.Version,
+	}
+	for k, v := range spec.Annotations {
+		annotations[k] = v
+	}
+	serviceType := spec.Type
+	if serviceType == "" {
+		serviceType = v1.ServiceTypeClusterIP
+	}
+	cql := servicePort("cql", 9042)
+	if serviceType != v1.ServiceTypeClusterIP {
+		cql.NodePort = spec.NodePort
+	}
+	var sourceRanges []string
+	if serviceType == v1.ServiceTypeLoadBalancer {
+		sourceRanges = spec.LoadBalancerSourceRanges
+	}
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clientServiceName(cc.Name),
+			Annotations: annotations,
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role": "cassandraCluster",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Spec: v1.ServiceSpec{
+			Type: serviceType,
+			Selector: map[string]string{
+				"cassandraCluster": cc.Name,
+			},
+			Ports: []v1.ServicePort{cql},
+			LoadBalancerSourceRanges: sourceRanges,
+		},
+	}
+}
+
+// BuildMetricsService builds the headless service of the JMX and Jolokia ports of the nodes
+func (c *Controller) BuildMetricsService(cc *cassandrav1.CassandraCluster) *v1.Service{
+	ports := []v1.ServicePort{servicePort("jmx", 7199)}
+	if c.jolokiaPort != 0 {
+		ports = append(ports, servicePort("jolokia", int32(c.jolokiaPort)))
+	}
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: metricsServiceName(cc.Name),
+			Annotations: map[string]string{
+				"operatorVersion": cassandrav1.SchemeGroupVersion.Version,
+			},
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role": "cassandraCluster",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{
+				"cassandraCluster": cc.Name,
+			},
+			Ports: ports,
+			ClusterIP: "None",
+		},
+	}
+}
+
+// servicePort returns a TCP port with the defaults of Kubernetes, so the services compare equal once created
+func servicePort(name string, port int32) v1.ServicePort {
+	return v1.ServicePort{
+		Name: name,
+		Protocol: v1.ProtocolTCP,
+		Port: port,
+		TargetPort: intstr.FromInt(int(port)),
+	}
+}
+
+// clientServiceName returns the name of the service of the clients of the cluster
+func clientServiceName(ccName string) string {
+	return ccName + "-access"
+}
+
+// metricsServiceName returns the name of the service of the management ports of the nodes
+func metricsServiceName(ccName string) string {
+	return ccName + "-metrics"
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreateOrUpdateService(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.ClientService.Type = corev1.ServiceTypeNodePort
+	current := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clientServiceName("test"),
+			Namespace: testNamespace,
+			Labels:    map[string]string{"cassandraCluster": "test", "team": "db"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeNodePort,
+			ClusterIP: "10.0.0.1",
+			Ports:     []corev1.ServicePort{{Name: "cql", Port: 9042, NodePort: 31042}},
+		},
+	}
+	f := newFixture(t, cc, current)
+	if err := f.controller.CreateOrUpdateService(cc, f.controller.BuildClientService(cc)); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := f.kubeClient.CoreV1().Services(testNamespace).Get(current.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Spec.ClusterIP != "10.0.0.1" || updated.Spec.Ports[0].NodePort != 31042 || updated.Labels["team"] != "db" {
+		t.Errorf("got %+v, want the fields set by Kubernetes and others kept", updated)
+	}
+	if !metav1.IsControlledBy(updated, cc) || updated.Spec.Selector["cassandraCluster"] != "test" {
+		t.Errorf("got %+v, want the service of the cluster", updated)
+	}
+
+	// the service is up to date
+	f = newFixture(t, cc, updated)
+	if err := f.controller.CreateOrUpdateService(cc, f.controller.BuildClientService(cc)); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range f.kubeClient.Actions() {
+		t.Errorf("unexpected %s of the service up to date", action.GetVerb())
+	}
+}
+
+func TestCreateOrUpdateServices(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Datacenters = getDatacenters(cc)
+	cc.Spec.Datacenters = append(cc.Spec.Datacenters, cc.Spec.Datacenters[0])
+	cc.Spec.Datacenters[1].Name = "dc2"
+	f := newFixture(t, cc)
+	if err := f.controller.CreateOrUpdateServices(cc); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"test-dc1-node", "test-dc2-node", seedServiceName("test"), "test-access", "test-metrics"} {
+		svc, err := f.kubeClient.CoreV1().Services(testNamespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("service %s: %v", name, err)
+			continue
+		}
+		if !metav1.IsControlledBy(svc, cc) {
+			t.Errorf("service %s isn't owned by the cluster", name)
+		}
+	}
+	svc, err := f.kubeClient.CoreV1().Services(testNamespace).Get("test-dc2-node", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !svc.Spec.PublishNotReadyAddresses || svc.Spec.Selector["cassandraDC"] != "dc2" {
+		t.Errorf("got %+v, want the internode service of dc2", svc.Spec)
+	}
+}
@@ -1,170 +1,347 @@
 package controller
 
 import (
+	"encoding/json"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"github.com/golang/glog"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"time"
 	"k8s.io/apimachinery/pkg/api/resource"
 	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
-
-	"os/exec"
-	"k8s.io/client-go/tools/cache"
+	"strconv"
+	"fmt"
 )
 
-func (c *Controller) DeleteStatefulSet(stsName string) error{
-	err := c.kubeClientset.AppsV1().StatefulSets(c.namespace).Delete(stsName, &metav1.DeleteOptions{
-		PropagationPolicy: func() *metav1.DeletionPropagation {
-			foreground := metav1.DeletePropagationForeground
-			return &foreground
-		}(),
-	})
-	if errors.IsNotFound(err) {
-		err = nil
+// SpecHashAnnotation is the hash of the spec of the statefulset built by the operator
+const SpecHashAnnotation = "cassandra/spec-hash"
+
+// CreateOrUpdateStatefulSets reconciliates the statefulsets of all the racks. Nodes are added or removed one at a time,
+// only when all the racks are stable, and it returns true if the number of nodes changed
+func (c *Controller) CreateOrUpdateStatefulSets(cc *cassandrav1.CassandraCluster) (bool,error) {
+	racks := getRacks(cc)
+	target := make([]int32, len(racks))
+	for i, rack := range racks {
+		target[i] = rack.Nodes
 	}
-	return err
-}
 
-func (c *Controller) CreateOrUpdateStatefulSet(cc *cassandrav1.CassandraCluster) (bool,error) {
-	// get the client
-	client := c.kubeClientset.AppsV1().StatefulSets(c.namespace)
-	// get the current statefulset
-	oldSts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(cc.Name)
-	if err != nil && !errors.IsNotFound(err) {
-		return false,err
+	// get the current statefulsets
+	oldStss := make([]*v1.StatefulSet, len(racks))
+	current := make([]int32, len(racks))
+	stable := true
+	for i, rack := range racks {
+		sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(rackStatefulSetName(cc, rack))
+		if err != nil && !errors.IsNotFound(err) {
+			return false,err
+		}
+		// a statefulset of the same name not generated for the cluster is left untouched, createOrUpdate reports it
+		if errors.IsNotFound(err) || !ownedBy(cc, sts) {
+			continue
+		}
+		oldStss[i] = sts
+		current[i] = *sts.Spec.Replicas
+		if sts.Status.Replicas != *sts.Spec.Replicas || sts.Status.ReadyReplicas != *sts.Spec.Replicas {
+			stable = false
+		}
+	}
+
+	// the topology doesn't change while the nodes run different versions
+	if upgradeInProgress(cc) {
+		stable = false
 	}
-	// build the target statefulset
-	newSts := c.BuildStatefulSet(cc)
-	if errors.IsNotFound(err) {
-		_, err = client.Create(newSts)
+
+	// only move one node when the previous step is completed
+	replicas := current
+	changed := -1
+	if d := cc.Status.Decommission; d != nil {
+		// resume the decommission in progress whatever the state of the racks as the leaving node isn't ready
+		for i := range racks {
+			if oldStss[i] != nil && oldStss[i].Name == d.StatefulSet && d.Pod == fmt.Sprintf("%s-%d", d.StatefulSet, current[i]-1) {
+				replicas = make([]int32, len(current))
+				copy(replicas, current)
+				replicas[i]--
+				changed = i
+			}
+		}
+		if changed == -1 {
+			// the statefulset was already scaled down
+			cc.Status.Decommission = nil
+		}
+	} else if stable {
+		replicas, changed = nextRackReplicas(current, target)
+		// the next node only joins or leaves once the ring settled after the previous one
+		if changed != -1 && !c.ringSettled(cc) {
+			replicas, changed = current, -1
+		}
+		// a node is only removed while the keyspaces keep enough replicas
+		if changed != -1 && replicas[changed] < current[changed] {
+			nodes := map[string]int32{}
+			for i, rack := range racks {
+				nodes[rack.DC.Name] += replicas[i]
+				nodes[""] += replicas[i]
+			}
+			allowed, err := c.scaleDownAllowed(cc, racks[changed].DC.Name, nodes)
+			if err != nil {
+				return false,err
+			}
+			if !allowed {
+				replicas, changed = current, -1
+			}
+		}
+	}
+
+	// a node must leave the ring before its pod is removed
+	if changed != -1 && replicas[changed] < current[changed] {
+		done, err := c.decommissionNode(cc, oldStss[changed])
 		if err != nil {
 			return false,err
 		}
-	} else {
-		newSts.ResourceVersion = oldSts.ResourceVersion
-		_, err := client.Update(newSts)
-		if err != nil && !errors.IsNotFound(err) {
+		if !done {
+			replicas = current
+			changed = -1
+		}
+	}
+
+	for i, rack := range racks {
+		// build the target statefulset
+		newSts, err := c.BuildStatefulSet(cc, rack, replicas[i])
+		if err != nil {
+			return false,err
+		}
+		err = c.createOrUpdate(cc, c.statefulSetClient(), newSts, mergeStatefulSet(newSts))
+		if err != nil {
 			return false,err
 		}
 	}
+	if changed != -1 {
+		glog.Infof("rack %s of CassandraCluster %s scaled from %d to %d nodes", racks[changed].DC.Name+"/"+racks[changed].Rack.Name, cc.Name, current[changed], replicas[changed])
+	}
+	// the decommissioned node is removed, clean up after it
+	if d := cc.Status.Decommission; d != nil && changed != -1 {
+		data := cc.Spec.Data
+		if racks[changed].DC.Data != nil {
+			data = *racks[changed].DC.Data
+		}
+		if data.DeleteOnScaleDown {
+			err := c.DeleteNodePVC(d.Pod)
+			if err != nil {
+				return false,err
+			}
+		}
+		removeRingMember(&cc.Status, d.Pod)
+		cc.Status.Decommission = nil
+	}
 	// TODO check what requires a repair
 	// test what requires a repair (change in # of node, change in version ?)
-	if newSts.Spec.Replicas != oldSts.Spec.Replicas {
-		return true,nil
+	return changed != -1,nil
+}
+
+// statefulSetClient returns the client of the statefulsets, read from the cache
+func (c *Controller) statefulSetClient() objectClient {
+	client := c.kubeClientset.AppsV1().StatefulSets(c.namespace)
+	return objectClient{
+		get: func(name string) (object, error) {
+			return c.statefulsetsLister.StatefulSets(c.namespace).Get(name)
+		},
+		create: func(obj object) error {
+			_, err := client.Create(obj.(*v1.StatefulSet))
+			return err
+		},
+		update: func(obj object) error {
+			_, err := client.Update(obj.(*v1.StatefulSet))
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		},
 	}
-	return false,nil
 }
 
-// query API server until the stateful set is completely deployed (use an exponential back off and a timeout)
-func (c *Controller) WaitForStatefulSet(sts *v1.StatefulSet) error {
-	glog.V(2).Infof("waiting for statefulset %s to be ready", sts.Name)
-	return wait.Poll(5*time.Second, 30*time.Second, func() (bool, error) {
-		statefulSet,err := c.kubeClientset.AppsV1().StatefulSets(c.namespace).Get(sts.Name, metav1.GetOptions{})
-		if err != nil {
-			return false,err
-		}
-		if statefulSet.Status.ReadyReplicas < *sts.Spec.Replicas {
-			return false,nil
+// mergeStatefulSet returns the merge replacing the spec of the statefulset when its hash changed, as the spec read
+// back holds the defaults of the API server
+func mergeStatefulSet(desired *v1.StatefulSet) func(updated object) {
+	return func(updated object) {
+		sts := updated.(*v1.StatefulSet)
+		sts.Labels = mergeMap(sts.Labels, desired.Labels)
+		if sts.Annotations[SpecHashAnnotation] != desired.Annotations[SpecHashAnnotation] {
+			// the selector and the volume claim templates can't be updated
+			selector, claims := sts.Spec.Selector, sts.Spec.VolumeClaimTemplates
+			sts.Spec = desired.Spec
+			sts.Spec.Selector, sts.Spec.VolumeClaimTemplates = selector, claims
 		}
-		return true, nil
-	})
+		sts.Annotations = mergeMap(sts.Annotations, desired.Annotations)
+	}
 }
 
-func (c *Controller) BuildStatefulSet(cc *cassandrav1.CassandraCluster) *v1.StatefulSet{
+// specHash returns a short hash of the spec of a statefulset
+func specHash(spec v1.StatefulSetSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return configHash(map[string]string{"spec": string(data)}), nil
+}
 
-	limitCPU, _ := resource.ParseQuantity(cc.Spec.Cpu)
-	limitMemory, _ := resource.ParseQuantity(cc.Spec.Memory)
-	requestCPU, _ := resource.ParseQuantity(cc.Spec.Cpu)
-	requestMemory, _ := resource.ParseQuantity(cc.Spec.Memory)
-	requestDataStorage,_ := resource.ParseQuantity(cc.Spec.Data.StorageVolume)
+func (c *Controller) BuildStatefulSet(cc *cassandrav1.CassandraCluster, rack cassandraRack, replicas int32) (*v1.StatefulSet,error){
 
-	var antiAffinity *corev1.Affinity
+	// the datacenter can override the resources of the cluster
+	cpu, memory, data := cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data
+	if rack.DC.Cpu != "" {
+		cpu = rack.DC.Cpu
+	}
+	if rack.DC.Memory != "" {
+		memory = rack.DC.Memory
+	}
+	if rack.DC.Data != nil {
+		data = *rack.DC.Data
+	}
+
+	limitCPU, err := resource.ParseQuantity(cpu)
+	if err != nil {
+		return nil,fmt.Errorf("invalid cpu %q for datacenter %s: %v", cpu, rack.DC.Name, err)
+	}
+	limitMemory, err := resource.ParseQuantity(memory)
+	if err != nil {
+		return nil,fmt.Errorf("invalid memory %q for datacenter %s: %v", memory, rack.DC.Name, err)
+	}
+	requestDataStorage, err := resource.ParseQuantity(data.StorageVolume)
+	if err != nil {
+		return nil,fmt.Errorf("invalid storageVolume %q for datacenter %s: %v", data.StorageVolume, rack.DC.Name, err)
+	}
+	requestCPU, requestMemory := limitCPU.DeepCopy(), limitMemory.DeepCopy()
+
+	affinity := &corev1.Affinity{}
 	if (cc.Spec.AntiAffinity == true){
-		antiAffinity = &corev1.Affinity{
-			PodAntiAffinity: &corev1.PodAntiAffinity{
-				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
-					{
-						LabelSelector: &metav1.LabelSelector{
-							MatchExpressions: []metav1.LabelSelectorRequirement{
-								{
-									Key:      "cassandraCluster",
-									Operator: metav1.LabelSelectorOpIn,
-									Values:   []string{cc.ObjectMeta.Name},
-								},
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{
+								Key:      "cassandraCluster",
+								Operator: metav1.LabelSelectorOpIn,
+								Values:   []string{cc.ObjectMeta.Name},
 							},
 						},
-						TopologyKey: "kubernetes.io/hostname",
+					},
+					TopologyKey: "kubernetes.io/hostname",
+				},
+			},
+		}
+	}
+	// pin the pods of the rack on the nodes of the matching datacenter and failure zone
+	var nodeRequirements []corev1.NodeSelectorRequirement
+	if cc.Spec.DCLabel != "" && rack.DC.LabelValue != "" {
+		nodeRequirements = append(nodeRequirements, corev1.NodeSelectorRequirement{
+			Key:      cc.Spec.DCLabel,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{rack.DC.LabelValue},
+		})
+	}
+	if cc.Spec.RackLabel != "" && rack.Rack.LabelValue != "" {
+		nodeRequirements = append(nodeRequirements, corev1.NodeSelectorRequirement{
+			Key:      cc.Spec.RackLabel,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{rack.Rack.LabelValue},
+		})
+	}
+	if len(nodeRequirements) > 0 {
+		affinity.NodeAffinity = &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: nodeRequirements,
 					},
 				},
 			},
 		}
-	}else{
-		antiAffinity = nil
+	}
+	if affinity.PodAntiAffinity == nil && affinity.NodeAffinity == nil {
+		affinity = nil
+	}
+
+	// the configuration rendered by the operator replaces the configuration of the image
+	configHash := clusterConfigHash(cc)
+	configVolumes, initContainers := configVolumes(cc, rack, configHash)
+	volumeMounts := []corev1.VolumeMount{}
+	if configHash != "" {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "etc-cassandra",
+			MountPath: cassandraConfDir,
+		})
 	}
 
 	statefulSet := &v1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: cc.Name,
+			Name: rackStatefulSetName(cc, rack),
 			Labels: map[string]string{
 				"cassandraCluster": cc.Name,
+				"cassandraDC": rack.DC.Name,
+				"cassandraRack": rack.Rack.Name,
 				"role": "cassandraCluster",
 			},
 			Annotations: map[string]string{
 				"operatorVersion": cassandrav1.SchemeGroupVersion.Version,
 
 			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
 		},
 		Spec: v1.StatefulSetSpec{
-			ServiceName: cc.Name+"-node",
+			ServiceName: datacenterServiceName(cc, rack.DC),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string {
 					"cassandraCluster": cc.Name,
+					"cassandraDC": rack.DC.Name,
+					"cassandraRack": rack.Rack.Name,
 				},
 			},
 			UpdateStrategy: v1.StatefulSetUpdateStrategy{
 				Type: v1.RollingUpdateStatefulSetStrategyType,
 				RollingUpdate: &v1.RollingUpdateStatefulSetStrategy{
-					// partition can be used to introduce
-					Partition: func(i int) *int32 { j:=int32(i);return &j}(0),
+					// the partition moves the pods to a new image one at a time during an upgrade
+					Partition: func(i int32) *int32 { return &i }(upgradePartition(cc, rackStatefulSetName(cc, rack), replicas)),
 				},
 			},
-			Replicas: cc.Spec.NbNodes,
+			Replicas: &replicas,
 			//ServiceName: mc.ObjectMeta.Name,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
 						"cassandraCluster": cc.Name,
+						"cassandraDC": rack.DC.Name,
+						"cassandraRack": rack.Rack.Name,
 						"role": "cassandraCluster",
 					},
 					Annotations: map[string]string{
 						"operatorVersion": cassandrav1.SchemeGroupVersion.Version,
-
+						ConfigHashAnnotation: configHash,
 					},
 				},
 				Spec: corev1.PodSpec{
 
-					Affinity: antiAffinity,
+					Affinity: affinity,
 					TerminationGracePeriodSeconds: func(i int64) *int64 { return &i}(10),
-					/*Volumes: []corev1.Volume{
+					InitContainers: initContainers,
+					Volumes: append([]corev1.Volume{
 						{
-							Name:	"secret",
+							// tokens of the nodes of a cluster restored from a backup, only present during the restore
+							Name: "initial-tokens",
 							VolumeSource: corev1.VolumeSource{
-								Secret: &corev1.SecretVolumeSource{
-									SecretName: cc.Name+"-node-token",
-									DefaultMode: func(i int32) *int32 {return &i}(256),
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: initialTokensConfigMapName(cc.Name),
+									},
+									Optional: func(b bool) *bool { return &b }(true),
 								},
 							},
 						},
-					},*/
+					}, configVolumes...),
 					Containers: []corev1.Container{
 						{
 							Name:            "cassandra",
-							Image:           cc.Spec.BaseImage,
+							Image:           clusterImage(cc),
 							ImagePullPolicy: "Always",
-							Env: []corev1.EnvVar{
+							Env: append([]corev1.EnvVar{
 								{
 									Name: "NAMESPACE",
 									ValueFrom: &corev1.EnvVarSource{
@@ -181,25 +358,26 @@ func (c *Controller) BuildStatefulSet(cc *cassandrav1.CassandraCluster) *v1.Stat
 									Name: "HEAP_NEW_SIZE",
 									Value: cc.Spec.CassandraSpec.HeapNewSize,
 								},
-								{
-									Name: "CASSANDRA_SEEDS",
-									Value: cc.Name+"-0."+cc.Name+"-node."+c.namespace+".svc.cluster.local",
-								},
+								seedsEnv(cc),
 								{
 									Name: "CASSANDRA_CLUSTER_NAME",
 									Value: cc.Name,
 								},
+								{
+									Name: "CASSANDRA_NUM_TOKENS",
+									Value: strconv.Itoa(cc.Spec.CassandraSpec.NbToken),
+								},
 								{
 									Name: "CASSANDRA_DC",
-									Value: cc.Spec.CassandraSpec.MaxHeapSize,
+									Value: rack.DC.Name,
 								},
 								{
 									Name: "CASSANDRA_RACK",
-									Value: cc.Spec.CassandraSpec.MaxHeapSize,
+									Value: rack.Rack.Name,
 								},
 								{
-									Name: "CASSANDRA_AUTO_BOOTSTRAP",
-									Value: cc.Spec.CassandraSpec.MaxHeapSize,
+									Name: "CASSANDRA_ENDPOINT_SNITCH",
+									Value: "GossipingPropertyFileSnitch",
 								},
 								{
 									Name: "POD_IP",
@@ -209,7 +387,19 @@ func (c *Controller) BuildStatefulSet(cc *cassandrav1.CassandraCluster) *v1.Stat
 										},
 									},
 								},
-							},
+								{
+									Name: "POD_NAME",
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.name",
+										},
+									},
+								},
+								{
+									Name: "CASSANDRA_INITIAL_TOKEN_FILE",
+									Value: initialTokensDir+"/$(POD_NAME)",
+								},
+							}, append(tlsEnv(cc), authEnv(cc)...)...),
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "cql",
@@ -259,17 +449,17 @@ func (c *Controller) BuildStatefulSet(cc *cassandrav1.CassandraCluster) *v1.Stat
 								InitialDelaySeconds: int32(15),
 								TimeoutSeconds: int32(5),
 							},
-							VolumeMounts: []corev1.VolumeMount{
+							VolumeMounts: append(volumeMounts, []corev1.VolumeMount{
 								{
 									Name:      "data",
 									MountPath: "/cassandra_data",
 								},
-								/*{
-									Name:		"secret",
-									MountPath:	"/etc/secrets-volume",
-									ReadOnly: 	true,
-								},*/
-							},
+								{
+									Name:      "initial-tokens",
+									MountPath: initialTokensDir,
+									ReadOnly:  true,
+								},
+							}...),
 							Resources: corev1.ResourceRequirements{
 								Limits: corev1.ResourceList{
 									"cpu":    limitCPU,
@@ -290,10 +480,11 @@ func (c *Controller) BuildStatefulSet(cc *cassandrav1.CassandraCluster) *v1.Stat
 					ObjectMeta: metav1.ObjectMeta{
 						Name: "data",
 						Annotations: map[string]string{
-							"volume.beta.kubernetes.io/storage-class": cc.Spec.Data.StorageClass,
+							"volume.beta.kubernetes.io/storage-class": data.StorageClass,
 						},
 						Labels: map[string]string{
 							"name":      cc.Name,
+							"cassandraCluster": cc.Name,
 						},
 					},
 					Spec: corev1.PersistentVolumeClaimSpec{
@@ -310,5 +501,10 @@ func (c *Controller) BuildStatefulSet(cc *cassandrav1.CassandraCluster) *v1.Stat
 			},
 		},
 	}
-	return statefulSet
+	hash, err := specHash(statefulSet.Spec)
+	if err != nil {
+		return nil,err
+	}
+	statefulSet.Annotations[SpecHashAnnotation] = hash
+	return statefulSet,nil
 }
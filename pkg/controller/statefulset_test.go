@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func TestCreateOrUpdateStatefulSets(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data.StorageVolume = "1", "4Gi", "10Gi"
+	current, err := newFixture(t, cc).controller.BuildStatefulSet(cc, getRacks(cc)[0], 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fields set by Kubernetes and others
+	current.Namespace = testNamespace
+	current.Labels["team"] = "db"
+	current.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+	current.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"cassandraCluster": "test"}}
+	current.Status.Replicas, current.Status.ReadyReplicas = 3, 3
+
+	// the statefulset is up to date
+	f := newFixture(t, cc, current)
+	if _, err := f.controller.CreateOrUpdateStatefulSets(cc); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range f.kubeClient.Actions() {
+		t.Errorf("unexpected %s of the statefulset up to date", action.GetVerb())
+	}
+
+	cc.Spec.Cpu = "2"
+	if _, err := f.controller.CreateOrUpdateStatefulSets(cc); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := f.kubeClient.AppsV1().StatefulSets(testNamespace).Get(current.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpu := updated.Spec.Template.Spec.Containers[0].Resources.Limits.Cpu().String(); cpu != "2" {
+		t.Errorf("got cpu %s, want the statefulset updated", cpu)
+	}
+	if len(updated.Spec.Selector.MatchLabels) != 1 || len(updated.Spec.VolumeClaimTemplates) != len(current.Spec.VolumeClaimTemplates) {
+		t.Errorf("got %+v, want the selector and the volume claim templates kept", updated.Spec)
+	}
+	if updated.Labels["team"] != "db" || updated.Annotations[SpecHashAnnotation] == current.Annotations[SpecHashAnnotation] {
+		t.Errorf("got %+v, want the labels of others kept and the hash of the new spec", updated.ObjectMeta)
+	}
+}
+
+// containerEnv returns the value of an environment variable of the container
+func containerEnv(container corev1.Container, name string) string {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+func TestBuildStatefulSetDatacenters(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data = "1", "4Gi", cassandrav1.Storage{StorageVolume: "10Gi", StorageClass: "standard"}
+	cc.Spec.DCLabel, cc.Spec.RackLabel = "region", "zone"
+	two := int32(2)
+	cc.Spec.Datacenters = []cassandrav1.Datacenter{
+		{Name: "transactions", LabelValue: "eu-west-1", Racks: []cassandrav1.Rack{{Name: "a", LabelValue: "eu-west-1a"}, {Name: "b"}}},
+		// the analytics datacenter has its own resources
+		{Name: "analytics", NbNodes: &two, Cpu: "4", Memory: "16Gi", Data: &cassandrav1.Storage{StorageVolume: "100Gi", StorageClass: "ssd"}},
+	}
+	f := newFixture(t, cc)
+
+	tests := []struct {
+		name     string
+		service  string
+		nodes    int32
+		cpu      string
+		memory   string
+		storage  string
+		class    string
+		affinity []string
+	}{
+		{"test-transactions-a", "test-transactions-node", 2, "1", "4Gi", "10Gi", "standard", []string{"region=eu-west-1", "zone=eu-west-1a"}},
+		{"test-transactions-b", "test-transactions-node", 1, "1", "4Gi", "10Gi", "standard", []string{"region=eu-west-1"}},
+		{"test-analytics-rack1", "test-analytics-node", 2, "4", "16Gi", "100Gi", "ssd", nil},
+	}
+	racks := getRacks(cc)
+	if len(racks) != len(tests) {
+		t.Fatalf("got %d racks, want %d", len(racks), len(tests))
+	}
+	for i, test := range tests {
+		rack := racks[i]
+		sts, err := f.controller.BuildStatefulSet(cc, rack, rack.Nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sts.Name != test.name || sts.Spec.ServiceName != test.service || *sts.Spec.Replicas != test.nodes {
+			t.Errorf("got statefulset %s of service %s with %d nodes, want %s of %s with %d", sts.Name, sts.Spec.ServiceName,
+				*sts.Spec.Replicas, test.name, test.service, test.nodes)
+		}
+		selector := sts.Spec.Selector.MatchLabels
+		if selector["cassandraDC"] != rack.DC.Name || selector["cassandraRack"] != rack.Rack.Name ||
+			sts.Spec.Template.Labels["cassandraDC"] != rack.DC.Name || sts.Spec.Template.Labels["cassandraRack"] != rack.Rack.Name {
+			t.Errorf("%s: got selector %v and labels %v, want the datacenter and the rack", test.name, selector, sts.Spec.Template.Labels)
+		}
+		container := sts.Spec.Template.Spec.Containers[0]
+		if dc, r := containerEnv(container, "CASSANDRA_DC"), containerEnv(container, "CASSANDRA_RACK"); dc != rack.DC.Name || r != rack.Rack.Name {
+			t.Errorf("%s: got CASSANDRA_DC %s and CASSANDRA_RACK %s", test.name, dc, r)
+		}
+		limits := container.Resources.Limits
+		if limits.Cpu().String() != test.cpu || limits.Memory().String() != test.memory {
+			t.Errorf("%s: got limits %v, want cpu %s and memory %s", test.name, limits, test.cpu, test.memory)
+		}
+		claim := sts.Spec.VolumeClaimTemplates[0]
+		storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if storage.String() != test.storage || claim.Annotations["volume.beta.kubernetes.io/storage-class"] != test.class {
+			t.Errorf("%s: got %s of %s, want %s of %s", test.name, storage.String(),
+				claim.Annotations["volume.beta.kubernetes.io/storage-class"], test.storage, test.class)
+		}
+		var affinity []string
+		if a := sts.Spec.Template.Spec.Affinity; a != nil && a.NodeAffinity != nil {
+			for _, requirement := range a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions {
+				affinity = append(affinity, requirement.Key+"="+requirement.Values[0])
+			}
+		}
+		if strings.Join(affinity, " ") != strings.Join(test.affinity, " ") {
+			t.Errorf("%s: got node affinity %v, want %v", test.name, affinity, test.affinity)
+		}
+	}
+}
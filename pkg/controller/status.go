@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+// computeStatus fills the status of the CassandraCluster from the statefulset and the pods it owns.
+// The object passed must be a copy as it is modified in place
+func (c *Controller) computeStatus(cc *cassandrav1.CassandraCluster) error {
+	status := &cc.Status
+	status.ObservedGeneration = cc.Generation
+	racks := getRacks(cc)
+	status.DesiredNodes = 0
+	for _, rack := range racks {
+		status.DesiredNodes += rack.Nodes
+	}
+
+	// get the pods of the cluster to build the per node status
+	pods, err := c.podLister.Pods(c.namespace).List(labels.SelectorFromSet(labels.Set{"cassandraCluster": cc.Name}))
+	if err != nil {
+		return err
+	}
+	status.Nodes = nil
+	status.ReadyNodes = 0
+	failed := false
+	for _, pod := range pods {
+		state := podState(pod)
+		if state == cassandrav1.NodeStateReady {
+			status.ReadyNodes++
+		}
+		if state == cassandrav1.NodeStateFailed {
+			failed = true
+		}
+		status.Nodes = append(status.Nodes, cassandrav1.CassandraNodeStatus{
+			Name:   pod.Name,
+			PodIP:  pod.Status.PodIP,
+			HostIP: pod.Status.HostIP,
+			State:  state,
+		})
+	}
+	// keep the order stable to avoid useless status updates
+	sort.Slice(status.Nodes, func(i, j int) bool { return status.Nodes[i].Name < status.Nodes[j].Name })
+
+	// the statefulsets tell if a scaling or a rolling update is in progress
+	scaling, upgrading := false, false
+	for _, rack := range racks {
+		sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(rackStatefulSetName(cc, rack))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if errors.IsNotFound(err) {
+			scaling = scaling || rack.Nodes != 0
+			continue
+		}
+		scaling = scaling || sts.Status.Replicas != rack.Nodes
+		upgrading = upgrading || sts.Status.UpdateRevision != "" && sts.Status.CurrentRevision != sts.Status.UpdateRevision
+	}
+	upgrading = upgrading || upgradeInProgress(cc)
+	ready := status.DesiredNodes > 0 && status.ReadyNodes == status.DesiredNodes
+	// a cluster never deployed because of its invalid spec is created once the spec is fixed
+	invalid := conditionTrue(status, cassandrav1.ClusterSpecInvalid) && status.Image == ""
+
+	switch {
+	case failed:
+		status.Phase = cassandrav1.ClusterPhaseFailed
+	case (status.Phase == "" || status.Phase == cassandrav1.ClusterPhaseCreating || invalid) && !ready:
+		status.Phase = cassandrav1.ClusterPhaseCreating
+	case scaling:
+		status.Phase = cassandrav1.ClusterPhaseScaling
+	case upgrading:
+		status.Phase = cassandrav1.ClusterPhaseUpgrading
+	case ready:
+		status.Phase = cassandrav1.ClusterPhaseRunning
+	}
+
+	setCondition(status, cassandrav1.ClusterReady, ready, "NodesReady", "all the desired nodes are ready")
+	setCondition(status, cassandrav1.ClusterScaling, scaling, "ReplicasMismatch", "the number of nodes is converging to the desired one")
+	setCondition(status, cassandrav1.ClusterUpgrading, upgrading, "RollingUpdate", "nodes are being rolled to a new revision")
+	repairing := status.Repair != nil && status.Repair.Phase == cassandrav1.RepairRunning
+	setCondition(status, cassandrav1.ClusterRepairing, repairing, "RepairRunning", "a repair is running")
+	setCondition(status, cassandrav1.ClusterSpecInvalid, false, "", "")
+	// the wait for the ring to settle is over when no step is pending
+	if !scaling && !upgrading && status.PendingRepair == "" {
+		status.RingNotSettledSince = nil
+		setCondition(status, cassandrav1.ClusterRingNotSettled, false, "", "")
+	}
+	return nil
+}
+
+// podState maps the Kubernetes view of a pod to the Cassandra node state reported in the status
+func podState(pod *corev1.Pod) cassandrav1.CassandraNodeState {
+	if pod.Status.Phase == corev1.PodFailed {
+		return cassandrav1.NodeStateFailed
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff" {
+			return cassandrav1.NodeStateFailed
+		}
+	}
+	if pod.Status.Phase == corev1.PodPending {
+		return cassandrav1.NodeStatePending
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return cassandrav1.NodeStateReady
+		}
+	}
+	return cassandrav1.NodeStateStarting
+}
+
+// setCondition updates the condition of the given type, the transition time is only changed when the status flips
+func setCondition(status *cassandrav1.CassandraClusterStatus, condType cassandrav1.CassandraClusterConditionType, value bool, reason string, message string) {
+	condStatus := corev1.ConditionFalse
+	if value {
+		condStatus = corev1.ConditionTrue
+	} else {
+		reason, message = "", ""
+	}
+	for i := range status.Conditions {
+		cond := &status.Conditions[i]
+		if cond.Type != condType {
+			continue
+		}
+		if cond.Status != condStatus {
+			cond.Status = condStatus
+			cond.LastTransitionTime = *now()
+		}
+		cond.Reason = reason
+		cond.Message = message
+		return
+	}
+	status.Conditions = append(status.Conditions, cassandrav1.CassandraClusterCondition{
+		Type:               condType,
+		Status:             condStatus,
+		LastTransitionTime: *now(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func getCondition(status *cassandrav1.CassandraClusterStatus, condType cassandrav1.CassandraClusterConditionType) *cassandrav1.CassandraClusterCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestComputeStatus(t *testing.T) {
+	ready := func(name string) *corev1.Pod {
+		return newNodePod(newCassandraCluster("test"), name, "cassandra:3.11", "", true)
+	}
+	starting := func(name string) *corev1.Pod {
+		return newNodePod(newCassandraCluster("test"), name, "cassandra:3.11", "", false)
+	}
+	crashing := func(name string) *corev1.Pod {
+		pod := starting(name)
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: "cassandra", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+		}
+		return pod
+	}
+	sts := func(replicas int32, current string, update string) *appsv1.StatefulSet {
+		s := newRackStatefulSet("test-dc1-rack1", replicas)
+		s.Status.Replicas, s.Status.CurrentRevision, s.Status.UpdateRevision = replicas, current, update
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		phase      cassandrav1.CassandraClusterPhase
+		wasReady   bool
+		sts        *appsv1.StatefulSet
+		pods       []*corev1.Pod
+		want       cassandrav1.CassandraClusterPhase
+		readyNodes int32
+	}{
+		{"new cluster", "", false, nil, nil, cassandrav1.ClusterPhaseCreating, 0},
+		{"nodes starting", cassandrav1.ClusterPhaseCreating, false, sts(3, "r1", "r1"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), ready("test-dc1-rack1-1"), starting("test-dc1-rack1-2")}, cassandrav1.ClusterPhaseCreating, 2},
+		{"created", cassandrav1.ClusterPhaseCreating, false, sts(3, "r1", "r1"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), ready("test-dc1-rack1-1"), ready("test-dc1-rack1-2")}, cassandrav1.ClusterPhaseRunning, 3},
+		{"running", cassandrav1.ClusterPhaseRunning, true, sts(3, "r1", "r1"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), ready("test-dc1-rack1-1"), ready("test-dc1-rack1-2")}, cassandrav1.ClusterPhaseRunning, 3},
+		// a node down degrades the cluster: it stays Running but isn't Ready
+		{"degraded", cassandrav1.ClusterPhaseRunning, true, sts(3, "r1", "r1"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), starting("test-dc1-rack1-1"), ready("test-dc1-rack1-2")}, cassandrav1.ClusterPhaseRunning, 2},
+		{"node crashing", cassandrav1.ClusterPhaseRunning, true, sts(3, "r1", "r1"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), crashing("test-dc1-rack1-1"), ready("test-dc1-rack1-2")}, cassandrav1.ClusterPhaseFailed, 2},
+		{"recovered", cassandrav1.ClusterPhaseFailed, false, sts(3, "r1", "r1"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), ready("test-dc1-rack1-1"), ready("test-dc1-rack1-2")}, cassandrav1.ClusterPhaseRunning, 3},
+		{"scaling", cassandrav1.ClusterPhaseRunning, true, sts(2, "r1", "r1"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), ready("test-dc1-rack1-1")}, cassandrav1.ClusterPhaseScaling, 2},
+		{"upgrading", cassandrav1.ClusterPhaseRunning, true, sts(3, "r1", "r2"),
+			[]*corev1.Pod{ready("test-dc1-rack1-0"), ready("test-dc1-rack1-1"), ready("test-dc1-rack1-2")}, cassandrav1.ClusterPhaseUpgrading, 3},
+	}
+	before := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	after := before.Add(time.Hour)
+	defer func() { clock = time.Now }()
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		cc.Status.Phase = test.phase
+		setClock(before)
+		setCondition(&cc.Status, cassandrav1.ClusterReady, test.wasReady, "NodesReady", "")
+		var objects []runtime.Object
+		if test.sts != nil {
+			objects = append(objects, test.sts)
+		}
+		for _, pod := range test.pods {
+			objects = append(objects, pod)
+		}
+		f := newFixture(t, cc, objects...)
+
+		setClock(after)
+		if err := f.controller.computeStatus(cc); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if cc.Status.Phase != test.want || cc.Status.ReadyNodes != test.readyNodes || cc.Status.DesiredNodes != 3 {
+			t.Errorf("%s: got phase %s with %d/%d nodes ready, want %s with %d/3", test.name, cc.Status.Phase,
+				cc.Status.ReadyNodes, cc.Status.DesiredNodes, test.want, test.readyNodes)
+		}
+		isReady := test.readyNodes == 3
+		cond := getCondition(&cc.Status, cassandrav1.ClusterReady)
+		if (cond.Status == corev1.ConditionTrue) != isReady {
+			t.Errorf("%s: got Ready %s", test.name, cond.Status)
+		}
+		// the transition time only moves when the Ready condition flips
+		want := before
+		if isReady != test.wasReady {
+			want = after
+		}
+		if !cond.LastTransitionTime.Time.Equal(want) {
+			t.Errorf("%s: got the Ready transition at %s, want %s", test.name, cond.LastTransitionTime, want)
+		}
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	defer func() { clock = time.Now }()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := &cassandrav1.CassandraClusterStatus{}
+
+	setClock(t0)
+	setCondition(status, cassandrav1.ClusterScaling, true, "ReplicasMismatch", "scaling")
+	setClock(t0.Add(time.Minute))
+	setCondition(status, cassandrav1.ClusterScaling, true, "ReplicasMismatch", "still scaling")
+	cond := getCondition(status, cassandrav1.ClusterScaling)
+	if len(status.Conditions) != 1 || !cond.LastTransitionTime.Time.Equal(t0) || cond.Message != "still scaling" {
+		t.Errorf("got %+v, want the message updated and the transition time kept", status.Conditions)
+	}
+
+	setClock(t0.Add(2 * time.Minute))
+	setCondition(status, cassandrav1.ClusterScaling, false, "ReplicasMismatch", "scaling")
+	cond = getCondition(status, cassandrav1.ClusterScaling)
+	if cond.Status != corev1.ConditionFalse || !cond.LastTransitionTime.Time.Equal(t0.Add(2*time.Minute)) || cond.Reason != "" || cond.Message != "" {
+		t.Errorf("got %+v, want the condition false since the flip", cond)
+	}
+
+	setCondition(status, cassandrav1.ClusterReady, false, "", "")
+	if len(status.Conditions) != 2 || getCondition(status, cassandrav1.ClusterReady).Status != corev1.ConditionFalse {
+		t.Errorf("got %+v, want the Ready condition added", status.Conditions)
+	}
+}
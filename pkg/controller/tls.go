@@ -0,0 +1,361 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/certs"
+)
+
+const (
+	// tlsDir is the directory of the keystores in the nodes
+	tlsDir = cassandraConfDir + "/tls"
+	// tlsSecretDir is where the secret of the keystores is mounted in the init container
+	tlsSecretDir = "/cassandra-tls"
+	// keys of the secret of the keystores, the keystore of each node is stored under <pod>.jks
+	caCertKey           = "ca.crt"
+	truststoreKey       = "truststore.jks"
+	keystorePasswordKey = "keystore.password"
+	// previousCACertKey holds the CAs replaced by a rotation, trusted until all the nodes restarted with the
+	// certificates of the new CA
+	previousCACertKey = "previous-ca.crt"
+	// keystorePasswordPlaceholder is replaced in cassandra.yaml by the password of the keystores when the node starts,
+	// so the password isn't stored in the configmap
+	keystorePasswordPlaceholder = "@KEYSTORE_PASSWORD@"
+	// annotations of the secret of the keystores
+	certificatesRevisionAnnotation = "cassandra/certificates-revision"
+	certificatesExpiryAnnotation   = "cassandra/certificates-expiry"
+
+	defaultCertificateValidityDays = 365
+	defaultRenewBeforeDays         = 30
+)
+
+// tlsEnabled returns true if the nodes need certificates
+func tlsEnabled(cc *cassandrav1.CassandraCluster) bool {
+	return cc.Spec.CassandraSpec.InterNodeTLS || cc.Spec.CassandraSpec.ClientTLS
+}
+
+// caSecretName returns the name of the secret of the CA generated by the operator
+func caSecretName(ccName string) string {
+	return ccName + "-ca"
+}
+
+// tlsSecretName returns the name of the secret of the keystores of the nodes
+func tlsSecretName(ccName string) string {
+	return ccName + "-tls"
+}
+
+// reconcileTLS issues the certificates of the nodes, signed by the CA of the cluster, and stores them as JKS keystores
+// in a secret. The certificates are all renewed before they expire and the returned revision changes, it's part of
+// the hash of the configuration so the nodes are restarted one at a time to load the new certificates
+func (c *Controller) reconcileTLS(cc *cassandrav1.CassandraCluster) (string, error) {
+	if !tlsEnabled(cc) {
+		cc.Status.CertificatesExpiry = nil
+		return "", nil
+	}
+	ca, err := c.getCA(cc)
+	if err != nil {
+		return "", err
+	}
+	client := c.kubeClientset.CoreV1().Secrets(c.namespace)
+	secret, err := client.Get(tlsSecretName(cc.Name), metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	exists := err == nil
+	if exists {
+		if err := c.checkOwner(cc, secret); err != nil {
+			return "", err
+		}
+	} else {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: tlsSecretName(cc.Name),
+				Labels: map[string]string{
+					"cassandraCluster": cc.Name,
+					"role":             "tls",
+				},
+				OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+			},
+		}
+	}
+
+	renewBefore := time.Duration(defaultRenewBeforeDays) * 24 * time.Hour
+	if cc.Spec.CassandraSpec.TLS.RenewBeforeDays > 0 {
+		renewBefore = time.Duration(cc.Spec.CassandraSpec.TLS.RenewBeforeDays) * 24 * time.Hour
+	}
+	expiry, err := time.Parse(time.RFC3339, secret.Annotations[certificatesExpiryAnnotation])
+	previous := secret.Data[previousCACertKey]
+	switch {
+	case !exists:
+	case err != nil:
+		glog.Warningf("invalid expiration of the certificates of CassandraCluster %s, renewing them: %v", cc.Name, err)
+	case time.Now().Add(renewBefore).After(expiry):
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "CertificatesRenewed", "Renewing the certificates of the nodes expiring on %s", expiry.Format(time.RFC3339))
+	case !bytes.Equal(secret.Data[caCertKey], ca.CertPEM()):
+		// the nodes not restarted yet keep the certificates of the previous CA
+		c.recorder.Event(cc, corev1.EventTypeNormal, "CertificatesRenewed", "The CA changed, renewing the certificates of the nodes")
+		previous = append(append([]byte(nil), previous...), secret.Data[caCertKey]...)
+	case previous != nil:
+		deployed, err := c.certificatesDeployed(cc, secret.Annotations[certificatesRevisionAnnotation])
+		if err != nil {
+			return "", err
+		}
+		if !deployed {
+			return c.issueCertificates(cc, ca, secret, expiry, false)
+		}
+		truststore, err := ca.TrustStore(string(secret.Data[keystorePasswordKey]))
+		if err != nil {
+			return "", err
+		}
+		secret.Data[truststoreKey] = truststore
+		delete(secret.Data, previousCACertKey)
+		secret.Annotations[certificatesRevisionAnnotation] = nextRevision(secret)
+		c.recorder.Event(cc, corev1.EventTypeNormal, "PreviousCARemoved", "All the nodes have a certificate of the new CA, the previous CA isn't trusted anymore")
+		return c.issueCertificates(cc, ca, secret, expiry, true)
+	default:
+		// the certificates are valid, only the new nodes need one
+		return c.issueCertificates(cc, ca, secret, expiry, false)
+	}
+
+	password, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	trusted, err := certs.ParseCertificates(previous)
+	if err != nil {
+		return "", fmt.Errorf("invalid previous CA in secret %s: %v", secret.Name, err)
+	}
+	truststore, err := ca.TrustStore(password, trusted...)
+	if err != nil {
+		return "", err
+	}
+	validity := time.Duration(defaultCertificateValidityDays) * 24 * time.Hour
+	if cc.Spec.CassandraSpec.TLS.CertificateValidityDays > 0 {
+		validity = time.Duration(cc.Spec.CassandraSpec.TLS.CertificateValidityDays) * 24 * time.Hour
+	}
+	expiry = time.Now().Add(validity).UTC().Truncate(time.Second)
+	if ca.Cert.NotAfter.Before(expiry) {
+		expiry = ca.Cert.NotAfter
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[certificatesRevisionAnnotation] = nextRevision(secret)
+	secret.Annotations[certificatesExpiryAnnotation] = expiry.Format(time.RFC3339)
+	secret.Data = map[string][]byte{
+		caCertKey:           ca.CertPEM(),
+		truststoreKey:       truststore,
+		keystorePasswordKey: []byte(password),
+	}
+	if previous != nil {
+		secret.Data[previousCACertKey] = previous
+	}
+	if !exists {
+		// the keystores are added to the new secret by an update like for the new nodes
+		if secret, err = client.Create(secret); err != nil {
+			return "", err
+		}
+	}
+	return c.issueCertificates(cc, ca, secret, expiry, true)
+}
+
+// issueCertificates adds the keystores of the nodes which don't have one yet to the secret. The secret is updated
+// when it's changed by the caller
+func (c *Controller) issueCertificates(cc *cassandrav1.CassandraCluster, ca *certs.CA, secret *corev1.Secret, expiry time.Time, updated bool) (string, error) {
+	password := string(secret.Data[keystorePasswordKey])
+	// the secrets created before the owner references are adopted
+	updated = updated || metav1.GetControllerOf(secret) == nil
+	adopt(cc, secret)
+	for _, rack := range getRacks(cc) {
+		stsName := rackStatefulSetName(cc, rack)
+		// the pods of a rack being scaled down still need their certificate
+		replicas := rack.Nodes
+		if sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(stsName); err == nil && *sts.Spec.Replicas > replicas {
+			replicas = *sts.Spec.Replicas
+		}
+		svc := datacenterServiceName(cc, rack.DC)
+		for i := int32(0); i < replicas; i++ {
+			pod := fmt.Sprintf("%s-%d", stsName, i)
+			if _, ok := secret.Data[pod+".jks"]; ok {
+				continue
+			}
+			cert, err := ca.Issue([]string{
+				pod + "." + svc + "." + c.namespace + ".svc.cluster.local",
+				pod + "." + svc + "." + c.namespace + ".svc",
+				pod + "." + svc + "." + c.namespace,
+				pod + "." + svc,
+				pod,
+			}, expiry)
+			if err != nil {
+				return "", fmt.Errorf("could not issue the certificate of %s: %v", pod, err)
+			}
+			keystore, err := ca.KeyStore(cert, pod, password)
+			if err != nil {
+				return "", fmt.Errorf("could not create the keystore of %s: %v", pod, err)
+			}
+			secret.Data[pod+".jks"] = keystore
+			updated = true
+		}
+	}
+	if updated {
+		glog.Infof("updating the certificates of CassandraCluster %s", cc.Name)
+		if _, err := c.kubeClientset.CoreV1().Secrets(c.namespace).Update(secret); err != nil {
+			return "", err
+		}
+	}
+	cc.Status.CertificatesExpiry = &metav1.Time{Time: expiry}
+	return secret.Annotations[certificatesRevisionAnnotation], nil
+}
+
+// certificatesDeployed returns true when all the nodes run the configuration of the revision of the certificates
+func (c *Controller) certificatesDeployed(cc *cassandrav1.CassandraCluster, revision string) (bool, error) {
+	if upgradeInProgress(cc) || cc.Status.ConfigHash == "" {
+		return false, nil
+	}
+	cm, err := c.kubeClientset.CoreV1().ConfigMaps(c.namespace).Get(configMapName(cc.Name, cc.Status.ConfigHash), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return cm.Data["certificates.revision"] == revision, nil
+}
+
+// nextRevision returns a revision of the certificates later than the one of the secret
+func nextRevision(secret *corev1.Secret) string {
+	revision := time.Now().Unix()
+	if current, err := strconv.ParseInt(secret.Annotations[certificatesRevisionAnnotation], 10, 64); err == nil && current >= revision {
+		revision = current + 1
+	}
+	return strconv.FormatInt(revision, 10)
+}
+
+// getCA returns the CA of the secret of the spec, or the CA generated by the operator for the cluster
+func (c *Controller) getCA(cc *cassandrav1.CassandraCluster) (*certs.CA, error) {
+	client := c.kubeClientset.CoreV1().Secrets(c.namespace)
+	if name := cc.Spec.CassandraSpec.TLS.CASecretName; name != "" {
+		secret, err := client.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get the CA secret %s: %v", name, err)
+		}
+		ca, err := certs.ParseCA(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CA in secret %s: %v", name, err)
+		}
+		return ca, nil
+	}
+
+	secret, err := client.Get(caSecretName(cc.Name), metav1.GetOptions{})
+	if err == nil {
+		if err := c.checkOwner(cc, secret); err != nil {
+			return nil, err
+		}
+		return certs.ParseCA(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	ca, err := certs.NewCA(cc.Name + " Cassandra CA")
+	if err != nil {
+		return nil, fmt.Errorf("could not generate the CA of CassandraCluster %s: %v", cc.Name, err)
+	}
+	_, err = client.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: caSecretName(cc.Name),
+			Labels: map[string]string{
+				"cassandraCluster": cc.Name,
+				"role":             "ca",
+			},
+			OwnerReferences: []metav1.OwnerReference{newControllerRef(cc)},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       ca.CertPEM(),
+			corev1.TLSPrivateKeyKey: ca.KeyPEM(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.recorder.Eventf(cc, corev1.EventTypeNormal, "CAGenerated", "Generated the CA of the cluster in secret %s", caSecretName(cc.Name))
+	return ca, nil
+}
+
+// encryptionOptions returns the server_encryption_options and client_encryption_options of cassandra.yaml
+func encryptionOptions(cc *cassandrav1.CassandraCluster) (map[string]interface{}, map[string]interface{}) {
+	server := map[string]interface{}{"internode_encryption": "none"}
+	client := map[string]interface{}{"enabled": false}
+	stores := map[string]interface{}{
+		"keystore":            tlsDir + "/keystore.jks",
+		"keystore_password":   keystorePasswordPlaceholder,
+		"truststore":          tlsDir + "/" + truststoreKey,
+		"truststore_password": keystorePasswordPlaceholder,
+	}
+	if cc.Spec.CassandraSpec.InterNodeTLS {
+		server["internode_encryption"] = "all"
+		// the nodes authenticate each other with the certificates signed by the CA of the cluster. The endpoint
+		// verification checks the IP address of the peer, also in 4.x, and the certificates only name the pods
+		// as their addresses change when they restart
+		server["require_client_auth"] = true
+		server["require_endpoint_verification"] = false
+		for key, value := range stores {
+			server[key] = value
+		}
+	}
+	if cc.Spec.CassandraSpec.ClientTLS {
+		client["enabled"] = true
+		client["optional"] = false
+		client["require_client_auth"] = false
+		for key, value := range stores {
+			client[key] = value
+		}
+	}
+	return server, client
+}
+
+// tlsCopyCommand returns the shell command of the init container installing the keystore of the node
+func tlsCopyCommand() string {
+	return fmt.Sprintf("mkdir -p /etc-cassandra/tls && cp %[1]s/%[2]s %[1]s/%[3]s /etc-cassandra/tls/ && "+
+		"cp %[1]s/${POD_NAME}.jks /etc-cassandra/tls/keystore.jks && "+
+		"sed -i \"s/%[4]s/$(cat %[1]s/%[5]s)/g\" /etc-cassandra/cassandra.yaml",
+		tlsSecretDir, caCertKey, truststoreKey, keystorePasswordPlaceholder, keystorePasswordKey)
+}
+
+// tlsEnv returns the environment of cqlsh when the clients must use TLS. The certificate of the node is validated
+// against the CA of the cluster, cqlsh doesn't check the address it connects to
+func tlsEnv(cc *cassandrav1.CassandraCluster) []corev1.EnvVar {
+	if !cc.Spec.CassandraSpec.ClientTLS {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "CQLSH_SSL", Value: "--ssl"},
+		{Name: "SSL_CERTFILE", Value: tlsDir + "/" + caCertKey},
+		{Name: "SSL_VALIDATE", Value: "true"},
+	}
+}
+
+// cqlshCmd returns the command running cqlsh in a node with the TLS and credentials of its environment
+func cqlshCmd(args ...string) []string {
+	return append([]string{"/bin/sh", "-c", `exec cqlsh $CQLSH_SSL ${CQLSH_USERNAME:+-u "$CQLSH_USERNAME" -p "$CQLSH_PASSWORD"} "$@"`, "cqlsh"}, args...)
+}
+
+// randomString returns a random hexadecimal string of n bytes
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavel-v-chernykh/keystore-go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vgkowski/cassandra-operator/pkg/certs"
+)
+
+// getTLSSecret returns the secret of the keystores and the number of certificates of its truststore
+func getTLSSecret(t *testing.T, f *fixture) (*corev1.Secret, int) {
+	secret, err := f.kubeClient.CoreV1().Secrets(testNamespace).Get(tlsSecretName("test"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	truststore, err := keystore.Decode(bytes.NewReader(secret.Data[truststoreKey]), secret.Data[keystorePasswordKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret, len(truststore)
+}
+
+func TestReconcileTLS(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.CassandraSpec.InterNodeTLS = true
+	f := newFixture(t, cc)
+
+	revision, err := f.controller.reconcileTLS(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, trusted := getTLSSecret(t, f)
+	if revision == "" || secret.Annotations[certificatesRevisionAnnotation] != revision || trusted != 1 || !metav1.IsControlledBy(secret, cc) {
+		t.Fatalf("got revision %q and %+v, want the keystores of the cluster", revision, secret.ObjectMeta)
+	}
+	for _, pod := range []string{"test-dc1-rack1-0", "test-dc1-rack1-1", "test-dc1-rack1-2"} {
+		if len(secret.Data[pod+".jks"]) == 0 {
+			t.Errorf("no keystore for %s", pod)
+		}
+	}
+	if _, err := f.kubeClient.CoreV1().Secrets(testNamespace).Get(caSecretName("test"), metav1.GetOptions{}); err != nil {
+		t.Errorf("the CA isn't generated: %v", err)
+	}
+	if expiry := cc.Status.CertificatesExpiry; expiry == nil || expiry.Time.Before(time.Now().Add(364*24*time.Hour)) {
+		t.Errorf("got expiry %v, want the default validity", expiry)
+	}
+
+	// a new node gets a certificate without restarting the others
+	nbNodes := int32(4)
+	cc.Spec.NbNodes = &nbNodes
+	if revision2, err := f.controller.reconcileTLS(cc); err != nil || revision2 != revision {
+		t.Fatalf("got revision %q, %v, want %q", revision2, err, revision)
+	}
+	updated, _ := getTLSSecret(t, f)
+	if len(updated.Data["test-dc1-rack1-3.jks"]) == 0 || !bytes.Equal(updated.Data["test-dc1-rack1-0.jks"], secret.Data["test-dc1-rack1-0.jks"]) {
+		t.Error("got the keystores reissued, want the keystore of the new node added")
+	}
+
+	// the secret is up to date
+	actions := len(f.kubeClient.Actions())
+	if _, err := f.controller.reconcileTLS(cc); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range f.kubeClient.Actions()[actions:] {
+		if action.GetVerb() != "get" {
+			t.Errorf("unexpected %s of the certificates up to date", action.GetVerb())
+		}
+	}
+}
+
+func TestReconcileTLSRenewal(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.CassandraSpec.InterNodeTLS = true
+	f := newFixture(t, cc)
+	revision, err := f.controller.reconcileTLS(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := getTLSSecret(t, f)
+	f.events()
+
+	// the certificates expire within the 30 days before the renewal
+	expiry := time.Now().Add(20 * 24 * time.Hour).UTC().Truncate(time.Second)
+	secret.Annotations[certificatesExpiryAnnotation] = expiry.Format(time.RFC3339)
+	if _, err := f.kubeClient.CoreV1().Secrets(testNamespace).Update(secret); err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := f.controller.reconcileTLS(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := getTLSSecret(t, f)
+	if renewed == revision || bytes.Equal(updated.Data["test-dc1-rack1-0.jks"], secret.Data["test-dc1-rack1-0.jks"]) {
+		t.Errorf("got revision %s, want the certificates renewed", renewed)
+	}
+	if !cc.Status.CertificatesExpiry.Time.After(expiry) {
+		t.Errorf("got expiry %v, want a later expiry", cc.Status.CertificatesExpiry)
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Normal CertificatesRenewed") {
+		t.Errorf("got events %v, want a CertificatesRenewed event", events)
+	}
+}
+
+func TestReconcileTLSCARotation(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.CassandraSpec.InterNodeTLS = true
+	f := newFixture(t, cc)
+	if _, err := f.controller.reconcileTLS(cc); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := getTLSSecret(t, f)
+	f.events()
+
+	ca, err := certs.NewCA("new CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.kubeClient.CoreV1().Secrets(testNamespace).Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-ca", Namespace: testNamespace},
+		Data:       map[string][]byte{corev1.TLSCertKey: ca.CertPEM(), corev1.TLSPrivateKeyKey: ca.KeyPEM()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc.Spec.CassandraSpec.TLS.CASecretName = "my-ca"
+	revision, err := f.controller.reconcileTLS(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, trusted := getTLSSecret(t, f)
+	if !bytes.Equal(rotated.Data[caCertKey], ca.CertPEM()) || !bytes.Equal(rotated.Data[previousCACertKey], secret.Data[caCertKey]) || trusted != 2 {
+		t.Fatalf("got %d trusted certificates, want the new and the previous CA trusted during the rolling restart", trusted)
+	}
+	if events := f.events(); len(events) != 1 || !strings.Contains(events[0], "The CA changed") {
+		t.Errorf("got events %v, want a CertificatesRenewed event", events)
+	}
+
+	// the previous CA is trusted until the nodes restarted with the new certificates
+	cc.Status.ConfigHash = "h2"
+	if again, err := f.controller.reconcileTLS(cc); err != nil || again != revision {
+		t.Fatalf("got revision %q, %v, want %q", again, err, revision)
+	}
+	if _, trusted := getTLSSecret(t, f); trusted != 2 {
+		t.Errorf("got %d trusted certificates before the rolling restart completed", trusted)
+	}
+
+	_, err = f.kubeClient.CoreV1().ConfigMaps(testNamespace).Create(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: configMapName("test", "h2"), Namespace: testNamespace},
+		Data:       map[string]string{"certificates.revision": revision},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped, err := f.controller.reconcileTLS(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, trusted := getTLSSecret(t, f)
+	if dropped == revision || trusted != 1 || updated.Data[previousCACertKey] != nil {
+		t.Errorf("got revision %s and %d trusted certificates, want the previous CA removed", dropped, trusted)
+	}
+	if !bytes.Equal(updated.Data["test-dc1-rack1-0.jks"], rotated.Data["test-dc1-rack1-0.jks"]) {
+		t.Error("the keystores are reissued when the previous CA is removed")
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Normal PreviousCARemoved") {
+		t.Errorf("got events %v, want a PreviousCARemoved event", events)
+	}
+}
+
+func TestEncryptionOptions(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.CassandraSpec.InterNodeTLS = true
+	cc.Spec.CassandraSpec.ClientTLS = true
+	server, client := encryptionOptions(cc)
+	if server["internode_encryption"] != "all" || server["require_client_auth"] != true {
+		t.Errorf("got %v, want the nodes authenticating each other", server)
+	}
+	if client["enabled"] != true || client["truststore"] != tlsDir+"/"+truststoreKey {
+		t.Errorf("got %v, want the clients using TLS", client)
+	}
+}
+
+func TestTLSEnv(t *testing.T) {
+	cc := newCassandraCluster("test")
+	if env := tlsEnv(cc); env != nil {
+		t.Errorf("got %v without client TLS", env)
+	}
+	cc.Spec.CassandraSpec.ClientTLS = true
+	env := map[string]string{}
+	for _, e := range tlsEnv(cc) {
+		env[e.Name] = e.Value
+	}
+	if env["SSL_VALIDATE"] != "true" || env["SSL_CERTFILE"] != tlsDir+"/"+caCertKey {
+		t.Errorf("got %v, want cqlsh validating the certificates against the CA", env)
+	}
+}
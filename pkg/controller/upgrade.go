@@ -0,0 +1,543 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+const (
+	// default time given to an upgraded node to be back Up and Normal
+	defaultUpgradeTimeout = 10 * time.Minute
+	// attempts of upgradesstables on a node before the upgrade fails
+	maxSSTablesAttempts = 3
+	// delay before upgradesstables is retried on a node, doubled after each attempt
+	sstablesRetryDelay = time.Minute
+)
+
+// versionRegexp extracts the major and minor version from an image tag like 3.11.2 or v3.0
+var versionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// reconcileUpgrade drives the upgrade of the Cassandra image and the rolling restarts applying a new configuration.
+// A change of the base image is checked against the supported version jumps, then the nodes are drained and restarted
+// one at a time, rack after rack, by lowering the partition of the statefulsets. The next node is only restarted when
+// the previous one is Up and Normal in the ring. Once all the nodes run the new image, their sstables are rewritten
+// one node at a time with nodetool upgradesstables
+func (c *Controller) reconcileUpgrade(cc *cassandrav1.CassandraCluster) error {
+	configHash, err := c.reconcileConfig(cc)
+	if err != nil {
+		return err
+	}
+	if cc.Status.Image == "" || cc.Status.ConfigHash == "" {
+		image, deployedHash, err := c.deployedImage(cc)
+		if err != nil {
+			return err
+		}
+		if cc.Status.Image == "" {
+			cc.Status.Image = image
+		}
+		if deployedHash == nil {
+			// a new cluster starts with the rendered configuration
+			deployedHash = &configHash
+		}
+		// the hash stays empty for the clusters deployed before the configuration was rendered by the operator
+		cc.Status.ConfigHash = *deployedHash
+	}
+
+	u := cc.Status.Upgrade
+	// an upgrade which didn't complete is kept until the base image or the configuration changes
+	if u != nil && (u.Phase == cassandrav1.UpgradeRejected || u.Phase == cassandrav1.UpgradeRolledBack) &&
+		(u.ToImage != cc.Spec.BaseImage || u.ToConfigHash != configHash) {
+		cc.Status.Upgrade = nil
+		u = nil
+	}
+	if u == nil {
+		if cc.Spec.BaseImage == cc.Status.Image && configHash == cc.Status.ConfigHash {
+			return nil
+		}
+		return c.startUpgrade(cc, configHash)
+	}
+
+	switch u.Phase {
+	case cassandrav1.UpgradeRunning, cassandrav1.UpgradePaused:
+		return c.upgradeNextNode(cc, u)
+	case cassandrav1.UpgradeSSTables:
+		return c.upgradeSSTables(cc, u)
+	case cassandrav1.UpgradeRollingBack:
+		return c.rollbackNextNode(cc, u)
+	}
+	return nil
+}
+
+// startUpgrade validates the version jump and starts the upgrade once the cluster is stable. A change of the
+// configuration alone restarts the nodes with the same image
+func (c *Controller) startUpgrade(cc *cassandrav1.CassandraCluster, configHash string) error {
+	u := &cassandrav1.UpgradeStatus{
+		FromImage:      cc.Status.Image,
+		ToImage:        cc.Spec.BaseImage,
+		FromConfigHash: cc.Status.ConfigHash,
+		ToConfigHash:   configHash,
+	}
+	if u.FromImage != u.ToImage {
+		if err := checkVersionJump(u.FromImage, u.ToImage); err != nil {
+			u.Phase = cassandrav1.UpgradeRejected
+			u.Message = err.Error()
+			cc.Status.Upgrade = u
+			c.recorder.Eventf(cc, corev1.EventTypeWarning, "UpgradeRejected", "Upgrade from %s to %s rejected: %v", u.FromImage, u.ToImage, err)
+			return nil
+		}
+	}
+	// pre-flight checks: all the nodes are in the ring and no repair is running
+	if !clusterStable(cc) || cc.Status.Repair != nil && cc.Status.Repair.Phase == cassandrav1.RepairRunning {
+		glog.V(2).Infof("upgrade of CassandraCluster %s waiting for the cluster to be stable", cc.Name)
+		return nil
+	}
+	u.Phase = cassandrav1.UpgradeRunning
+	u.StartTime = now()
+	cc.Status.Upgrade = u
+	if u.FromImage == u.ToImage {
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "UpgradeStarted", "Rolling restart to apply the configuration %s", u.ToConfigHash)
+	} else {
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "UpgradeStarted", "Upgrading from %s to %s", u.FromImage, u.ToImage)
+	}
+	return c.persistStatus(cc)
+}
+
+// upgradeNextNode waits for the node being upgraded to be back in the ring, then drains and restarts the next one
+func (c *Controller) upgradeNextNode(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus) error {
+	if u.Pod != "" {
+		upgraded, err := c.nodeUpgraded(cc, u.Pod, u.ToImage, u.ToConfigHash)
+		if err != nil {
+			return err
+		}
+		if !upgraded {
+			timeout := defaultUpgradeTimeout
+			if cc.Spec.Upgrade.TimeoutSeconds > 0 {
+				timeout = time.Duration(cc.Spec.Upgrade.TimeoutSeconds) * time.Second
+			}
+			if u.Phase == cassandrav1.UpgradeRunning && u.PodStartTime != nil && time.Since(u.PodStartTime.Time) > timeout {
+				return c.failUpgrade(cc, u, fmt.Sprintf("node %s not Up and Normal after %s", u.Pod, timeout))
+			}
+			return nil
+		}
+		glog.Infof("node %s of CassandraCluster %s upgraded to %s", u.Pod, cc.Name, u.ToImage)
+		if u.Phase == cassandrav1.UpgradePaused && u.Message != "" {
+			c.recorder.Eventf(cc, corev1.EventTypeNormal, "UpgradeResumed", "Node %s is back in the ring", u.Pod)
+			u.Phase = cassandrav1.UpgradeRunning
+			u.Message = ""
+		}
+		u.Pod = ""
+		u.PodStartTime = nil
+	}
+
+	if cc.Spec.Upgrade.Paused {
+		u.Phase = cassandrav1.UpgradePaused
+		return nil
+	}
+	u.Phase = cassandrav1.UpgradeRunning
+
+	// the pods of the rack are all upgraded
+	if u.StatefulSet != "" && u.Partition == 0 {
+		u.UpgradedStatefulSets = append(u.UpgradedStatefulSets, u.StatefulSet)
+		u.StatefulSet = ""
+	}
+	if u.StatefulSet == "" {
+		for _, rack := range getRacks(cc) {
+			name := rackStatefulSetName(cc, rack)
+			if containsString(u.UpgradedStatefulSets, name) {
+				continue
+			}
+			sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(name)
+			if err != nil {
+				return err
+			}
+			u.StatefulSet = name
+			u.Partition = *sts.Spec.Replicas
+			break
+		}
+		if u.StatefulSet == "" && u.FromImage == u.ToImage {
+			// the sstables are already in the format of the image
+			return c.completeUpgrade(cc, u)
+		}
+		if u.StatefulSet == "" {
+			glog.Infof("all the nodes of CassandraCluster %s run %s, upgrading the sstables", cc.Name, u.ToImage)
+			u.Phase = cassandrav1.UpgradeSSTables
+			return c.persistStatus(cc)
+		}
+	}
+	if u.Partition == 0 {
+		return c.persistStatus(cc)
+	}
+	// the next node is only restarted once the ring settled after the previous one
+	if !c.ringSettled(cc) {
+		return nil
+	}
+
+	// the node flushes its memtables and stops accepting writes before its pod is deleted
+	pod := fmt.Sprintf("%s-%d", u.StatefulSet, u.Partition-1)
+	glog.Infof("draining node %s of CassandraCluster %s before its upgrade", pod, cc.Name)
+	client, err := c.adminClient(pod)
+	if err != nil {
+		return err
+	}
+	if err := client.Drain(); err != nil {
+		return fmt.Errorf("could not drain %s: %v", pod, err)
+	}
+	u.Partition--
+	u.Pod = pod
+	u.PodStartTime = now()
+	// the new partition is applied to the statefulset by this reconciliation
+	return c.persistStatus(cc)
+}
+
+// nodeUpgraded returns true when the pod runs the image with the configuration and is Up and Normal in the ring
+func (c *Controller) nodeUpgraded(cc *cassandrav1.CassandraCluster, podName string, image string, configHash string) (bool, error) {
+	pod, err := c.podLister.Pods(c.namespace).Get(podName)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if pod.Spec.Containers[0].Image != image || pod.Annotations[ConfigHashAnnotation] != configHash ||
+		podState(pod) != cassandrav1.NodeStateReady {
+		return false, nil
+	}
+	state, err := c.getNodeRingState(cc, pod.Status.PodIP, pod.Name)
+	if err != nil {
+		return false, err
+	}
+	return state == "UN", nil
+}
+
+// upgradeSSTables rewrites the sstables of the nodes in the format of the new version, one node at a time
+func (c *Controller) upgradeSSTables(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus) error {
+	if u.Pod != "" && u.Command != 0 {
+		client, err := c.adminClient(u.Pod)
+		if err != nil {
+			return err
+		}
+		state, err := client.UpgradeSSTablesStatus(int(u.Command))
+		if err != nil {
+			return fmt.Errorf("could not get the upgradesstables state of %s: %v", u.Pod, err)
+		}
+		switch state {
+		case admin.OperationStarting:
+			if u.PodStartTime != nil && time.Since(u.PodStartTime.Time) < operationStartTimeout {
+				return nil
+			}
+			return c.failSSTablesUpgrade(cc, u, fmt.Sprintf("upgradesstables not started on %s after %s", u.Pod, operationStartTimeout))
+		case admin.OperationInProgress:
+			return nil
+		case admin.OperationUnknown:
+			// the node or the operator restarted, the rewrite is started again unless it still runs
+			compactions, err := client.CompactionStats()
+			if err != nil {
+				return fmt.Errorf("could not get the compactions of %s: %v", u.Pod, err)
+			}
+			for _, compaction := range compactions {
+				if compaction.Type == admin.UpgradeSSTablesType {
+					return nil
+				}
+			}
+			u.Command = 0
+		case admin.OperationCompleted:
+			u.SSTablesUpgradedPods = append(u.SSTablesUpgradedPods, u.Pod)
+			u.Pod = ""
+			u.PodStartTime = nil
+			u.Command = 0
+			u.Attempts = 0
+		default:
+			return c.failSSTablesUpgrade(cc, u, fmt.Sprintf("upgradesstables failed on %s, see the logs of the node", u.Pod))
+		}
+	}
+
+	// a failed rewrite is retried on the same node
+	if u.Pod != "" {
+		retry, err := c.sstablesRetry(cc, u)
+		if err != nil || !retry {
+			return err
+		}
+		return c.startSSTablesUpgrade(cc, u, u.Pod)
+	}
+	for _, node := range cc.Status.Nodes {
+		if !containsString(u.SSTablesUpgradedPods, node.Name) {
+			return c.startSSTablesUpgrade(cc, u, node.Name)
+		}
+	}
+	return c.completeUpgrade(cc, u)
+}
+
+// startSSTablesUpgrade runs upgradesstables in the background on the node
+func (c *Controller) startSSTablesUpgrade(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus, podName string) error {
+	glog.Infof("upgrading the sstables of node %s of CassandraCluster %s", podName, cc.Name)
+	client, err := c.adminClient(podName)
+	if err != nil {
+		return err
+	}
+	command, err := client.UpgradeSSTables()
+	if err != nil {
+		return fmt.Errorf("could not start upgradesstables on %s: %v", podName, err)
+	}
+	u.Pod = podName
+	u.PodStartTime = now()
+	u.Command = int64(command)
+	return c.persistStatus(cc)
+}
+
+// failSSTablesUpgrade counts a failed upgradesstables of the node, the upgrade fails once the attempts are exhausted
+func (c *Controller) failSSTablesUpgrade(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus, message string) error {
+	u.Attempts++
+	u.Command = 0
+	u.PodStartTime = now()
+	if u.Attempts >= maxSSTablesAttempts {
+		return c.failUpgrade(cc, u, fmt.Sprintf("%s, %d attempts", message, u.Attempts))
+	}
+	c.recorder.Eventf(cc, corev1.EventTypeWarning, "UpgradeSSTablesFailed", "%s, attempt %d of %d", message, u.Attempts, maxSSTablesAttempts)
+	return c.persistStatus(cc)
+}
+
+// sstablesRetry returns true when upgradesstables can run again on the node which failed it: after a delay doubled
+// with each attempt, then once the node restarted when the attempts are exhausted
+func (c *Controller) sstablesRetry(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus) (bool, error) {
+	if u.Attempts == 0 || u.PodStartTime == nil {
+		return true, nil
+	}
+	if u.Attempts < maxSSTablesAttempts {
+		wait := sstablesRetryDelay<<uint(u.Attempts-1) - time.Since(u.PodStartTime.Time)
+		if wait <= 0 {
+			return true, nil
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(cc); err == nil {
+			c.workqueue.AddAfter(key, wait)
+		}
+		return false, nil
+	}
+	pod, err := c.podLister.Pods(c.namespace).Get(u.Pod)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if pod.Status.StartTime == nil || !pod.Status.StartTime.After(u.PodStartTime.Time) || podState(pod) != cassandrav1.NodeStateReady {
+		return false, nil
+	}
+	c.recorder.Eventf(cc, corev1.EventTypeNormal, "UpgradeResumed", "Node %s restarted, upgrading its sstables again", u.Pod)
+	u.Attempts = 0
+	u.Message = ""
+	return true, nil
+}
+
+// completeUpgrade records the image and configuration of the nodes and removes the previous configurations
+func (c *Controller) completeUpgrade(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus) error {
+	if u.FromImage == u.ToImage {
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "UpgradeCompleted", "All the nodes run the configuration %s", u.ToConfigHash)
+	} else {
+		c.recorder.Eventf(cc, corev1.EventTypeNormal, "UpgradeCompleted", "Upgraded from %s to %s", u.FromImage, u.ToImage)
+	}
+	cc.Status.Image = u.ToImage
+	cc.Status.ConfigHash = u.ToConfigHash
+	cc.Status.Upgrade = nil
+	if err := c.persistStatus(cc); err != nil {
+		return err
+	}
+	return c.deleteConfigs(cc.Name, cc.Status.ConfigHash)
+}
+
+// failUpgrade applies the failure policy: the upgrade waits for the failed node or the upgraded nodes are rolled back.
+// The sstables rewritten in the new format can't be read by the previous version, a failed upgradesstables waits
+func (c *Controller) failUpgrade(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus, message string) error {
+	u.Message = message
+	switch {
+	case u.Phase == cassandrav1.UpgradeSSTables:
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "UpgradePaused", "%s, waiting for the node to be restarted", message)
+	case cc.Spec.Upgrade.FailurePolicy == cassandrav1.UpgradeFailureRollback:
+		// the rack being upgraded is rolled back first, from its last pod
+		if u.StatefulSet != "" {
+			sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(u.StatefulSet)
+			if err != nil {
+				return err
+			}
+			u.Partition = *sts.Spec.Replicas
+		}
+		u.Phase = cassandrav1.UpgradeRollingBack
+		u.Pod = ""
+		u.PodStartTime = nil
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "UpgradeRollingBack", "%s, rolling back to %s", message, u.FromImage)
+	default:
+		u.Phase = cassandrav1.UpgradePaused
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "UpgradePaused", "%s, waiting for the node to come back", message)
+	}
+	return c.persistStatus(cc)
+}
+
+// rollbackNextNode restarts the upgraded nodes with the previous image one at a time, from the last pod of the rack
+// being upgraded back to the first upgraded rack, draining them like the upgrade. The pods still running the previous
+// image are skipped. The ring isn't checked as the failed node may be down, only the node rolled back before
+func (c *Controller) rollbackNextNode(cc *cassandrav1.CassandraCluster, u *cassandrav1.UpgradeStatus) error {
+	if u.Pod != "" {
+		rolledBack, err := c.nodeUpgraded(cc, u.Pod, u.FromImage, u.FromConfigHash)
+		if err != nil || !rolledBack {
+			return err
+		}
+		glog.Infof("node %s of CassandraCluster %s rolled back to %s", u.Pod, cc.Name, u.FromImage)
+		u.Pod = ""
+		u.PodStartTime = nil
+	}
+
+	if u.StatefulSet != "" && u.Partition == 0 {
+		u.StatefulSet = ""
+	}
+	if u.StatefulSet == "" {
+		last := len(u.UpgradedStatefulSets) - 1
+		if last < 0 {
+			u.Phase = cassandrav1.UpgradeRolledBack
+			c.recorder.Eventf(cc, corev1.EventTypeWarning, "UpgradeRolledBack", "All the nodes run %s again, the base image or the configuration must be changed to retry the upgrade", u.FromImage)
+			return c.persistStatus(cc)
+		}
+		sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(u.UpgradedStatefulSets[last])
+		if err != nil {
+			return err
+		}
+		u.StatefulSet = sts.Name
+		u.Partition = *sts.Spec.Replicas
+		u.UpgradedStatefulSets = u.UpgradedStatefulSets[:last]
+	}
+
+	podName := fmt.Sprintf("%s-%d", u.StatefulSet, u.Partition-1)
+	pod, err := c.podLister.Pods(c.namespace).Get(podName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if pod != nil && pod.Spec.Containers[0].Image == u.FromImage && pod.Annotations[ConfigHashAnnotation] == u.FromConfigHash {
+		u.Partition--
+		return c.persistStatus(cc)
+	}
+	// a node which is down can't be drained
+	if pod != nil && podState(pod) == cassandrav1.NodeStateReady {
+		glog.Infof("draining node %s of CassandraCluster %s before its rollback", podName, cc.Name)
+		client, err := c.adminClient(podName)
+		if err != nil {
+			return err
+		}
+		if err := client.Drain(); err != nil {
+			return fmt.Errorf("could not drain %s: %v", podName, err)
+		}
+	}
+	u.Partition--
+	u.Pod = podName
+	u.PodStartTime = now()
+	return c.persistStatus(cc)
+}
+
+// deployedImage returns the image and configuration hash of the existing statefulsets. A new cluster has the
+// base image and no configuration hash
+func (c *Controller) deployedImage(cc *cassandrav1.CassandraCluster) (string, *string, error) {
+	for _, rack := range getRacks(cc) {
+		sts, err := c.statefulsetsLister.StatefulSets(c.namespace).Get(rackStatefulSetName(cc, rack))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		hash := sts.Spec.Template.Annotations[ConfigHashAnnotation]
+		return sts.Spec.Template.Spec.Containers[0].Image, &hash, nil
+	}
+	return cc.Spec.BaseImage, nil, nil
+}
+
+// clusterImage returns the image of the statefulsets: the new image while upgrading, the previous one when the
+// upgrade is rejected or rolled back
+func clusterImage(cc *cassandrav1.CassandraCluster) string {
+	if u := cc.Status.Upgrade; u != nil {
+		switch u.Phase {
+		case cassandrav1.UpgradeRunning, cassandrav1.UpgradePaused, cassandrav1.UpgradeSSTables:
+			return u.ToImage
+		default:
+			return u.FromImage
+		}
+	}
+	if cc.Status.Image != "" {
+		return cc.Status.Image
+	}
+	return cc.Spec.BaseImage
+}
+
+// upgradePartition returns the partition of the statefulset of a rack: the pods with an ordinal greater or equal
+// run the image of the template. The racks not upgraded yet keep all their pods on the previous image, and while
+// rolling back the upgraded racks keep their pods on the new image until their turn
+func upgradePartition(cc *cassandrav1.CassandraCluster, stsName string, replicas int32) int32 {
+	u := cc.Status.Upgrade
+	if u == nil || u.Phase != cassandrav1.UpgradeRunning && u.Phase != cassandrav1.UpgradePaused && u.Phase != cassandrav1.UpgradeRollingBack {
+		return 0
+	}
+	if stsName == u.StatefulSet {
+		return u.Partition
+	}
+	upgraded := containsString(u.UpgradedStatefulSets, stsName)
+	if upgraded == (u.Phase == cassandrav1.UpgradeRollingBack) {
+		return replicas
+	}
+	return 0
+}
+
+// upgradeInProgress returns true while the nodes don't all run the same image and configuration
+func upgradeInProgress(cc *cassandrav1.CassandraCluster) bool {
+	u := cc.Status.Upgrade
+	return u != nil && u.Phase != cassandrav1.UpgradeRejected && u.Phase != cassandrav1.UpgradeRolledBack
+}
+
+// checkVersionJump only allows upgrades to a later version of the same major or of the next major version
+func checkVersionJump(from string, to string) error {
+	fromMajor, fromMinor, err := imageVersion(from)
+	if err != nil {
+		return err
+	}
+	toMajor, toMinor, err := imageVersion(to)
+	if err != nil {
+		return err
+	}
+	switch {
+	case toMajor < fromMajor || toMajor == fromMajor && toMinor < fromMinor:
+		return fmt.Errorf("downgrade from %d.%d to %d.%d isn't supported", fromMajor, fromMinor, toMajor, toMinor)
+	case toMajor > fromMajor+1:
+		return fmt.Errorf("upgrade from %d.%d to %d.%d skips a major version", fromMajor, fromMinor, toMajor, toMinor)
+	}
+	return nil
+}
+
+// imageVersion returns the Cassandra major and minor version from the tag of the image
+func imageVersion(image string) (int, int, error) {
+	tag := ""
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		tag = image[i+1:]
+	}
+	match := versionRegexp.FindStringSubmatch(tag)
+	if match == nil {
+		return 0, 0, fmt.Errorf("can't find the Cassandra version in the tag of image %s", image)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return major, minor, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
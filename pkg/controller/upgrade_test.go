@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+	"github.com/vgkowski/cassandra-operator/pkg/cassandra/admin"
+)
+
+// newNodePod returns a pod of the cluster running the image with the configuration
+func newNodePod(cc *cassandrav1.CassandraCluster, name string, image string, configHash string, ready bool) *corev1.Pod {
+	pod := newPod(cc, name)
+	pod.Annotations = map[string]string{ConfigHashAnnotation: configHash}
+	pod.Spec.Containers = []corev1.Container{{Name: "cassandra", Image: image}}
+	pod.Status.Phase = corev1.PodRunning
+	if ready {
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	}
+	return pod
+}
+
+func newRackStatefulSet(name string, replicas int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	}
+}
+
+func TestRollbackNextNode(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Upgrade.FailurePolicy = cassandrav1.UpgradeFailureRollback
+	// the second pod didn't come back with the new image
+	cc.Status.Upgrade = &cassandrav1.UpgradeStatus{
+		FromImage: "cassandra:3.0", ToImage: "cassandra:3.11", FromConfigHash: "h1", ToConfigHash: "h1",
+		Phase: cassandrav1.UpgradeRunning, StatefulSet: "test-dc1-rack1", Partition: 1, Pod: "test-dc1-rack1-1",
+	}
+	objects := []runtime.Object{
+		newRackStatefulSet("test-dc1-rack1", 3),
+		newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.0", "h1", true),
+		newNodePod(cc, "test-dc1-rack1-1", "cassandra:3.11", "h1", false),
+		newNodePod(cc, "test-dc1-rack1-2", "cassandra:3.11", "h1", true),
+	}
+	f := newFixture(t, cc, objects...)
+	u := cc.Status.Upgrade
+
+	if err := f.controller.failUpgrade(cc, u, "node test-dc1-rack1-1 not Up and Normal"); err != nil {
+		t.Fatal(err)
+	}
+	if u.Phase != cassandrav1.UpgradeRollingBack || u.Partition != 3 || u.Pod != "" {
+		t.Fatalf("got %+v, want the rollback starting from the last pod", u)
+	}
+	if partition := upgradePartition(cc, "test-dc1-rack1", 3); partition != 3 {
+		t.Errorf("got partition %d, want no pod restarted before its drain", partition)
+	}
+
+	steps := []struct {
+		pod       string
+		partition int32
+		drained   bool
+	}{
+		{"test-dc1-rack1-2", 2, true},
+		// the failed node is down
+		{"test-dc1-rack1-1", 1, false},
+		// the first pod was never upgraded
+		{"", 0, false},
+	}
+	for _, step := range steps {
+		u.Pod = ""
+		if err := f.controller.rollbackNextNode(cc, u); err != nil {
+			t.Fatal(err)
+		}
+		if u.Pod != step.pod || u.Partition != step.partition {
+			t.Fatalf("got pod %q and partition %d, want %q and %d", u.Pod, u.Partition, step.pod, step.partition)
+		}
+		if step.pod != "" && f.node(step.pod).Drained != step.drained {
+			t.Errorf("got node %s drained %v, want %v", step.pod, f.node(step.pod).Drained, step.drained)
+		}
+	}
+	if err := f.controller.rollbackNextNode(cc, u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Phase != cassandrav1.UpgradeRolledBack {
+		t.Errorf("got phase %s, want %s", u.Phase, cassandrav1.UpgradeRolledBack)
+	}
+}
+
+func TestUpgradeSSTablesRetries(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Status.Nodes = []cassandrav1.CassandraNodeStatus{{Name: "test-dc1-rack1-0"}}
+	cc.Status.Upgrade = &cassandrav1.UpgradeStatus{FromImage: "cassandra:3.0", ToImage: "cassandra:3.11", Phase: cassandrav1.UpgradeSSTables}
+	pod := newNodePod(cc, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	started := metav1.NewTime(time.Now().Add(-time.Hour))
+	pod.Status.StartTime = &started
+	f := newFixture(t, cc, pod)
+	u := cc.Status.Upgrade
+	node := f.node(pod.Name)
+
+	for attempt := int32(1); attempt <= maxSSTablesAttempts; attempt++ {
+		// the next command fails
+		node.Operations[len(node.Calls)+1] = admin.OperationFailed
+		if err := f.controller.upgradeSSTables(cc, u); err != nil {
+			t.Fatal(err)
+		}
+		if u.Pod != pod.Name || u.Command == 0 {
+			t.Fatalf("attempt %d: got %+v, want upgradesstables running", attempt, u)
+		}
+		if err := f.controller.upgradeSSTables(cc, u); err != nil {
+			t.Fatal(err)
+		}
+		if u.Attempts != attempt || u.Command != 0 {
+			t.Fatalf("attempt %d: got %+v, want the failure counted", attempt, u)
+		}
+		// the retry waits for the backoff
+		calls := len(node.Calls)
+		if err := f.controller.upgradeSSTables(cc, u); err != nil {
+			t.Fatal(err)
+		}
+		if len(node.Calls) != calls {
+			t.Fatalf("attempt %d: got %v, want no retry before the backoff", attempt, node.Calls[calls:])
+		}
+		past := metav1.NewTime(u.PodStartTime.Add(-sstablesRetryDelay << uint(attempt)))
+		u.PodStartTime = &past
+	}
+	if u.Phase != cassandrav1.UpgradeSSTables || !strings.Contains(u.Message, "3 attempts") {
+		t.Errorf("got %+v, want the upgrade waiting for the node", u)
+	}
+	var paused bool
+	for _, event := range f.events() {
+		paused = paused || strings.HasPrefix(event, "Warning UpgradePaused")
+	}
+	if !paused {
+		t.Error("no UpgradePaused event")
+	}
+}
+
+func TestUpgradePartition(t *testing.T) {
+	tests := []struct {
+		phase     cassandrav1.UpgradePhase
+		sts       string
+		partition int32
+	}{
+		{cassandrav1.UpgradeRunning, "test-dc1-a", 0},
+		{cassandrav1.UpgradeRunning, "test-dc1-b", 1},
+		{cassandrav1.UpgradeRunning, "test-dc1-c", 3},
+		{cassandrav1.UpgradeRollingBack, "test-dc1-a", 3},
+		{cassandrav1.UpgradeRollingBack, "test-dc1-b", 1},
+		{cassandrav1.UpgradeRollingBack, "test-dc1-c", 0},
+		{cassandrav1.UpgradeSSTables, "test-dc1-b", 0},
+	}
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		cc.Status.Upgrade = &cassandrav1.UpgradeStatus{Phase: test.phase, StatefulSet: "test-dc1-b", Partition: 1, UpgradedStatefulSets: []string{"test-dc1-a"}}
+		if partition := upgradePartition(cc, test.sts, 3); partition != test.partition {
+			t.Errorf("%s %s: got partition %d, want %d", test.phase, test.sts, partition, test.partition)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/robfig/cron"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+var (
+	// jvmSizeRegexp matches the JVM memory sizes like 4G or 800M
+	jvmSizeRegexp = regexp.MustCompile(`^(\d+)([kKmMgG]?)$`)
+	// replicationRegexp matches the entries of the replication map of a keyspace
+	replicationRegexp = regexp.MustCompile(`'([^']+)': '([^']+)'`)
+)
+
+// ValidateCassandraCluster checks the CassandraCluster submitted to the admission webhook.
+// old is the current version of the resource on update, nil on creation
+func (c *Controller) ValidateCassandraCluster(cc *cassandrav1.CassandraCluster, old *cassandrav1.CassandraCluster) []string {
+	errs := validateSpec(cc)
+	if old == nil {
+		return errs
+	}
+	errs = append(errs, validateUpdate(cc, old)...)
+	return append(errs, c.validateScaleDown(cc, old)...)
+}
+
+// validateScaleDown rejects the nodes removed below the replication factor of the keyspaces of the running cluster.
+// The controller checks it again before each decommission
+func (c *Controller) validateScaleDown(cc *cassandrav1.CassandraCluster, old *cassandrav1.CassandraCluster) []string {
+	oldNodes, newNodes := datacenterNodes(old), datacenterNodes(cc)
+	var dcs []string
+	for dc, nodes := range oldNodes {
+		if dc != "" && newNodes[dc] < nodes {
+			dcs = append(dcs, dc)
+		}
+	}
+	if len(dcs) == 0 {
+		return nil
+	}
+	replication, err := c.getReplication(old)
+	if err != nil {
+		// the cluster may not be running, the replication can't be checked
+		glog.Warningf("could not check the replication of CassandraCluster %s: %v", old.Name, err)
+		return nil
+	}
+	// the operator lowers the replication of system_auth with the number of nodes
+	if passwordAuthentication(old) {
+		delete(replication, "system_auth")
+	}
+	sort.Strings(dcs)
+	var errs []string
+	for _, dc := range dcs {
+		if reason := replicationBlocking(replication, dc, newNodes); reason != "" && !containsString(errs, reason) {
+			errs = append(errs, reason)
+		}
+	}
+	return errs
+}
+
+// rejectCassandraCluster reports the invalid spec of a cluster in its status and in a warning event. Nothing is
+// deployed until the spec is fixed, which requeues the cluster
+func (c *Controller) rejectCassandraCluster(cc *cassandrav1.CassandraCluster, ccCopy *cassandrav1.CassandraCluster, errs []string) error {
+	message := strings.Join(errs, "; ")
+	ccCopy.Status.Phase = cassandrav1.ClusterPhaseFailed
+	setCondition(&ccCopy.Status, cassandrav1.ClusterSpecInvalid, true, "InvalidSpec", message)
+	if equality.Semantic.DeepEqual(cc.Status, ccCopy.Status) {
+		return nil
+	}
+	glog.Warningf("CassandraCluster %s rejected: %s", cc.Name, message)
+	c.recorder.Event(cc, corev1.EventTypeWarning, "InvalidSpec", message)
+	_, err := c.cassandraClusterClientset.CassandraV1().CassandraClusters(ccCopy.Namespace).UpdateStatus(ccCopy)
+	return err
+}
+
+// DefaultCassandraCluster fills the defaults of the CassandraCluster submitted to the admission webhook.
+// old is the current version of the resource on update, nil on creation when the authentication is defaulted
+func (c *Controller) DefaultCassandraCluster(cc *cassandrav1.CassandraCluster, old *cassandrav1.CassandraCluster) {
+	cassandrav1.SetDefaults(cc, c.baseImage)
+	if old == nil {
+		cassandrav1.SetAuthDefaults(cc)
+	}
+}
+
+// validateSpec checks the quantities of the spec and that the heap fits in the memory of the containers
+func validateSpec(cc *cassandrav1.CassandraCluster) []string {
+	var errs []string
+	maxHeap, err := parseJVMSize(cc.Spec.CassandraSpec.MaxHeapSize)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("invalid maxHeapSize: %v", err))
+	}
+	heapNew, err := parseJVMSize(cc.Spec.CassandraSpec.HeapNewSize)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("invalid heapNewSize: %v", err))
+	}
+	if heapNew > maxHeap && maxHeap > 0 {
+		errs = append(errs, fmt.Sprintf("heapNewSize %s is larger than maxHeapSize %s", cc.Spec.CassandraSpec.HeapNewSize, cc.Spec.CassandraSpec.MaxHeapSize))
+	}
+	if cc.Spec.CassandraSpec.NbToken < 0 {
+		errs = append(errs, "nbToken can't be negative")
+	}
+	if cc.Spec.NbNodes != nil && *cc.Spec.NbNodes < 0 {
+		errs = append(errs, "nbNodes can't be negative")
+	}
+	if cc.Spec.SeedsPerDatacenter < 0 {
+		errs = append(errs, "seedsPerDatacenter can't be negative")
+	}
+	switch cc.Spec.ClientService.Type {
+	case "", corev1.ServiceTypeClusterIP:
+		if cc.Spec.ClientService.NodePort != 0 {
+			errs = append(errs, "clientService.nodePort needs the NodePort or LoadBalancer type")
+		}
+	case corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported clientService.type %s", cc.Spec.ClientService.Type))
+	}
+	if cc.Spec.DisruptionBudget.MaxUnavailable < 0 {
+		errs = append(errs, "disruptionBudget.maxUnavailable can't be negative")
+	}
+	switch cc.Spec.DeletionPolicy {
+	case "", cassandrav1.DeletionRetain, cassandrav1.DeletionDelete:
+	case cassandrav1.DeletionSnapshotThenDelete:
+		if cc.Spec.FinalBackup == nil {
+			errs = append(errs, "deletionPolicy SnapshotThenDelete needs the finalBackup storage")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported deletionPolicy %s", cc.Spec.DeletionPolicy))
+	}
+	if cc.Spec.Repair.Schedule != "" {
+		if _, err := cron.ParseStandard(cc.Spec.Repair.Schedule); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid repair.schedule %q: %v", cc.Spec.Repair.Schedule, err))
+		}
+	}
+	for key, value := range cc.Spec.CassandraSpec.Config.Overrides {
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid value for the %s override: %v", key, err))
+		}
+	}
+
+	// the names end up in the names of the statefulsets, the services and the labels
+	dcNames := map[string]bool{}
+	for _, dc := range getDatacenters(cc) {
+		for _, msg := range validation.IsDNS1123Label(dc.Name) {
+			errs = append(errs, fmt.Sprintf("invalid datacenter name %q: %s", dc.Name, msg))
+		}
+		if dcNames[dc.Name] {
+			errs = append(errs, fmt.Sprintf("datacenter %s is declared twice", dc.Name))
+		}
+		dcNames[dc.Name] = true
+		rackNames := map[string]bool{}
+		for _, rack := range dc.Racks {
+			for _, msg := range validation.IsDNS1123Label(rack.Name) {
+				errs = append(errs, fmt.Sprintf("invalid rack name %q in datacenter %s: %s", rack.Name, dc.Name, msg))
+			}
+			if rackNames[rack.Name] {
+				errs = append(errs, fmt.Sprintf("rack %s is declared twice in datacenter %s", rack.Name, dc.Name))
+			}
+			rackNames[rack.Name] = true
+		}
+
+		cpu, memory, data := cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data
+		if dc.Cpu != "" {
+			cpu = dc.Cpu
+		}
+		if dc.Memory != "" {
+			memory = dc.Memory
+		}
+		if dc.Data != nil {
+			data = *dc.Data
+		}
+		if _, err := resource.ParseQuantity(cpu); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid cpu %q for datacenter %s: %v", cpu, dc.Name, err))
+		}
+		if _, err := resource.ParseQuantity(data.StorageVolume); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid storageVolume %q for datacenter %s: %v", data.StorageVolume, dc.Name, err))
+		}
+		mem, err := resource.ParseQuantity(memory)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid memory %q for datacenter %s: %v", memory, dc.Name, err))
+		} else if maxHeap > mem.Value() {
+			errs = append(errs, fmt.Sprintf("maxHeapSize %s is larger than the memory %s of datacenter %s", cc.Spec.CassandraSpec.MaxHeapSize, memory, dc.Name))
+		}
+		if dc.NbNodes != nil && *dc.NbNodes < 0 {
+			errs = append(errs, fmt.Sprintf("nbNodes of datacenter %s can't be negative", dc.Name))
+		}
+	}
+	return errs
+}
+
+// validateUpdate rejects the changes which can't be applied to a deployed cluster
+func validateUpdate(cc *cassandrav1.CassandraCluster, old *cassandrav1.CassandraCluster) []string {
+	var errs []string
+	// an unset nbToken is defaulted once
+	if old.Spec.CassandraSpec.NbToken != 0 && cc.Spec.CassandraSpec.NbToken != old.Spec.CassandraSpec.NbToken {
+		errs = append(errs, "nbToken can't be changed after the creation of the cluster")
+	}
+	// the nodes restarted with a different internode encryption can't talk to the others
+	if cc.Spec.CassandraSpec.InterNodeTLS != old.Spec.CassandraSpec.InterNodeTLS {
+		errs = append(errs, "interNodeTLS can't be changed after the creation of the cluster")
+	}
+	oldData := map[string]cassandrav1.Storage{}
+	for _, dc := range getDatacenters(old) {
+		oldData[dc.Name] = old.Spec.Data
+		if dc.Data != nil {
+			oldData[dc.Name] = *dc.Data
+		}
+	}
+	for _, dc := range getDatacenters(cc) {
+		before, ok := oldData[dc.Name]
+		if !ok {
+			continue
+		}
+		data := cc.Spec.Data
+		if dc.Data != nil {
+			data = *dc.Data
+		}
+		if data.StorageClass != before.StorageClass {
+			errs = append(errs, fmt.Sprintf("storageClass of datacenter %s can't be changed", dc.Name))
+		}
+		size, err := resource.ParseQuantity(data.StorageVolume)
+		if err != nil {
+			continue
+		}
+		if oldSize, err := resource.ParseQuantity(before.StorageVolume); err == nil && size.Cmp(oldSize) < 0 {
+			errs = append(errs, fmt.Sprintf("storageVolume of datacenter %s can't shrink from %s to %s", dc.Name, before.StorageVolume, data.StorageVolume))
+		}
+	}
+	return errs
+}
+
+// datacenterNodes returns the desired number of nodes of each datacenter, and of the whole cluster under the empty key
+func datacenterNodes(cc *cassandrav1.CassandraCluster) map[string]int32 {
+	nodes := map[string]int32{}
+	for _, rack := range getRacks(cc) {
+		nodes[rack.DC.Name] += rack.Nodes
+		nodes[""] += rack.Nodes
+	}
+	return nodes
+}
+
+// scaleDownAllowed checks that the nodes left once a node is removed from the datacenter are enough for the
+// replication factor of the keyspaces of the running cluster. A blocked scale down raises a warning event
+func (c *Controller) scaleDownAllowed(cc *cassandrav1.CassandraCluster, dc string, nodes map[string]int32) (bool, error) {
+	replication, err := c.getReplication(cc)
+	if err != nil {
+		return false, err
+	}
+	// the operator lowers the replication of system_auth with the number of nodes
+	if passwordAuthentication(cc) {
+		delete(replication, "system_auth")
+	}
+	if reason := replicationBlocking(replication, dc, nodes); reason != "" {
+		c.recorder.Eventf(cc, corev1.EventTypeWarning, "ScaleDownBlocked", "Node not removed: %s", reason)
+		return false, nil
+	}
+	return true, nil
+}
+
+// replicationBlocking returns why the datacenter and the cluster can't be left with the given nodes, or an empty
+// string if the replication factor of no keyspace is larger
+func replicationBlocking(replication map[string]map[string]int32, dc string, nodes map[string]int32) string {
+	keyspaces := make([]string, 0, len(replication))
+	for keyspace := range replication {
+		keyspaces = append(keyspaces, keyspace)
+	}
+	sort.Strings(keyspaces)
+	for _, keyspace := range keyspaces {
+		for _, location := range []string{dc, ""} {
+			rf, ok := replication[keyspace][location]
+			if !ok || nodes[location] >= rf {
+				continue
+			}
+			name := "datacenter " + location
+			if location == "" {
+				name = "the cluster"
+			}
+			return fmt.Sprintf("%s would have %d nodes, lower than the replication factor %d of keyspace %s", name, nodes[location], rf, keyspace)
+		}
+	}
+	return ""
+}
+
+// getReplication returns the replication factor of the keyspaces of a running cluster per datacenter.
+// The factor of the SimpleStrategy is under the empty datacenter name
+func (c *Controller) getReplication(cc *cassandrav1.CassandraCluster) (map[string]map[string]int32, error) {
+	pod, err := c.readyPod(cc)
+	if err != nil {
+		return nil, err
+	}
+	// the schema tables moved to system_schema in Cassandra 3.0
+	query, parse := "SELECT keyspace_name, replication FROM system_schema.keyspaces", parseReplication
+	if majorVersion(pod.Spec.Containers[0].Image) < 3 {
+		query, parse = "SELECT keyspace_name, strategy_class, strategy_options FROM system.schema_keyspaces", parseLegacyReplication
+	}
+	stdout, stderr, err := c.ExecCmd(pod.Name, cqlshCmd(pod.Status.PodIP, "-e", query))
+	if err != nil {
+		return nil, fmt.Errorf("could not get the replication from %s: %v %s", pod.Name, err, stderr)
+	}
+	return parse(stdout), nil
+}
+
+// parseReplication extracts the replication factors from the cqlsh output of the replication of the keyspaces
+func parseReplication(output string) map[string]map[string]int32 {
+	replication := map[string]map[string]int32{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "|", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		options := map[string]string{}
+		for _, match := range replicationRegexp.FindAllStringSubmatch(fields[1], -1) {
+			options[match[1]] = match[2]
+		}
+		addReplication(replication, strings.TrimSpace(fields[0]), options["class"], options)
+	}
+	return replication
+}
+
+// parseLegacyReplication extracts the replication factors from the cqlsh output of system.schema_keyspaces of
+// Cassandra 2.x, where the options are a JSON map like {"dc1":"3"}
+func parseLegacyReplication(output string) map[string]map[string]int32 {
+	replication := map[string]map[string]int32{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "|", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		options := map[string]string{}
+		// skips the header
+		if err := json.Unmarshal([]byte(strings.TrimSpace(fields[2])), &options); err != nil {
+			continue
+		}
+		addReplication(replication, strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1]), options)
+	}
+	return replication
+}
+
+// addReplication adds the replication factors of a keyspace from its strategy options
+func addReplication(replication map[string]map[string]int32, keyspace string, strategy string, options map[string]string) {
+	// the LocalStrategy and EverywhereStrategy don't depend on the number of nodes
+	if !strings.HasSuffix(strategy, "SimpleStrategy") && !strings.HasSuffix(strategy, "NetworkTopologyStrategy") {
+		return
+	}
+	factors := map[string]int32{}
+	for option, value := range options {
+		rf, err := strconv.Atoi(value)
+		if err != nil || option == "class" {
+			continue
+		}
+		if option == "replication_factor" {
+			factors[""] = int32(rf)
+		} else {
+			factors[option] = int32(rf)
+		}
+	}
+	replication[keyspace] = factors
+}
+
+// parseJVMSize returns the number of bytes of a JVM memory size like 4G or 800M. An empty size is 0
+func parseJVMSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	match := jvmSizeRegexp.FindStringSubmatch(size)
+	if match == nil {
+		return 0, fmt.Errorf("%q isn't a JVM memory size like 4G or 800M", size)
+	}
+	value, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToUpper(match[2]) {
+	case "K":
+		value <<= 10
+	case "M":
+		value <<= 20
+	case "G":
+		value <<= 30
+	}
+	return value, nil
+}
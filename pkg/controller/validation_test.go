@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+func TestValidateSpec(t *testing.T) {
+	tests := []struct {
+		update func(cc *cassandrav1.CassandraCluster)
+		errs   []string
+	}{
+		{func(cc *cassandrav1.CassandraCluster) {}, nil},
+		{func(cc *cassandrav1.CassandraCluster) { cc.Spec.Cpu = "lots" }, []string{"invalid cpu"}},
+		{func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.Datacenters = []cassandrav1.Datacenter{{Name: "dc1", Data: &cassandrav1.Storage{StorageVolume: "big"}}}
+		}, []string{"invalid storageVolume"}},
+		{func(cc *cassandrav1.CassandraCluster) { cc.Spec.CassandraSpec.MaxHeapSize = "8G" }, []string{"larger than the memory"}},
+		{func(cc *cassandrav1.CassandraCluster) { cc.Spec.Repair.Schedule = "0 2 * * 0" }, nil},
+		{func(cc *cassandrav1.CassandraCluster) { cc.Spec.Repair.Schedule = "every sunday" }, []string{"invalid repair.schedule"}},
+		{func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.Datacenters = []cassandrav1.Datacenter{{Name: "dc1"}, {Name: "analytics", Racks: []cassandrav1.Rack{{Name: "a"}, {Name: "b"}}}}
+		}, nil},
+		{func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.Datacenters = []cassandrav1.Datacenter{{Name: "DC_1"}, {Name: "dc2", Racks: []cassandrav1.Rack{{Name: "rack.1"}}}}
+		}, []string{`invalid datacenter name "DC_1"`, `invalid rack name "rack.1" in datacenter dc2`}},
+		{func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.Datacenters = []cassandrav1.Datacenter{{Name: "dc1"}, {Name: "dc1"}}
+		}, []string{"datacenter dc1 is declared twice"}},
+		{func(cc *cassandrav1.CassandraCluster) {
+			cc.Spec.Racks = []cassandrav1.Rack{{Name: "a"}, {Name: "b"}, {Name: "a"}}
+		}, []string{"rack a is declared twice in datacenter dc1"}},
+	}
+	for _, test := range tests {
+		cc := newCassandraCluster("test")
+		cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data.StorageVolume = "1", "4Gi", "10Gi"
+		test.update(cc)
+		errs := validateSpec(cc)
+		if len(errs) != len(test.errs) {
+			t.Errorf("got %v, want %v", errs, test.errs)
+			continue
+		}
+		for i, err := range errs {
+			if !strings.Contains(err, test.errs[i]) {
+				t.Errorf("got %q, want %q", err, test.errs[i])
+			}
+		}
+	}
+}
+
+func TestSyncHandlerInvalidSpec(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Finalizers = []string{clusterFinalizer}
+	cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data.StorageVolume = "1", "lots", "10Gi"
+	cassandrav1.SetDefaults(cc, "cassandra:3.11")
+	cassandrav1.SetAuthDefaults(cc)
+	f := newFixture(t, cc)
+
+	if err := f.controller.syncHandler(testNamespace + "/test"); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := f.client.CassandraV1().CassandraClusters(testNamespace).Get("test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.Phase != cassandrav1.ClusterPhaseFailed || !conditionTrue(&updated.Status, cassandrav1.ClusterSpecInvalid) {
+		t.Errorf("got status %+v, want the cluster failed on its spec", updated.Status)
+	}
+	if events := f.events(); len(events) != 1 || !strings.HasPrefix(events[0], "Warning InvalidSpec invalid memory") {
+		t.Errorf("got events %v, want an InvalidSpec warning", events)
+	}
+	stss, err := f.kubeClient.AppsV1().StatefulSets(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stss.Items) != 0 {
+		t.Errorf("got %d statefulsets deployed for an invalid spec", len(stss.Items))
+	}
+}
+
+func TestBuildStatefulSetInvalidQuantity(t *testing.T) {
+	cc := newCassandraCluster("test")
+	cc.Spec.Cpu, cc.Spec.Memory, cc.Spec.Data.StorageVolume = "1", "4Gi", "ten"
+	f := newFixture(t, cc)
+	if _, err := f.controller.BuildStatefulSet(cc, getRacks(cc)[0], 3); err == nil {
+		t.Error("expected an error for the invalid storageVolume")
+	}
+}
+
+func TestReplicationBlocking(t *testing.T) {
+	replication := map[string]map[string]int32{
+		"orders": {"dc1": 3, "dc2": 2},
+		"users":  {"": 3},
+	}
+	tests := []struct {
+		dc     string
+		nodes  map[string]int32
+		reason string
+	}{
+		{"dc1", map[string]int32{"dc1": 3, "dc2": 2, "": 5}, ""},
+		{"dc1", map[string]int32{"dc1": 2, "dc2": 2, "": 4}, "datacenter dc1 would have 2 nodes, lower than the replication factor 3 of keyspace orders"},
+		{"dc2", map[string]int32{"dc1": 3, "dc2": 1, "": 4}, "datacenter dc2 would have 1 nodes, lower than the replication factor 2 of keyspace orders"},
+		{"dc3", map[string]int32{"dc1": 1, "dc2": 1, "dc3": 0, "": 2}, "the cluster would have 2 nodes, lower than the replication factor 3 of keyspace users"},
+	}
+	for _, test := range tests {
+		if reason := replicationBlocking(replication, test.dc, test.nodes); reason != test.reason {
+			t.Errorf("%s %v: got %q, want %q", test.dc, test.nodes, reason, test.reason)
+		}
+	}
+}
+
+func TestValidateScaleDown(t *testing.T) {
+	old := newRunningCluster("test")
+	old.Spec.Cpu, old.Spec.Memory, old.Spec.Data.StorageVolume = "1", "4Gi", "10Gi"
+	pod := newNodePod(old, "test-dc1-rack1-0", "cassandra:3.11", "", true)
+	f := newFixture(t, old, pod)
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		return replicationOutput(map[string]string{
+			"orders":      "{'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'dc1': '3'}",
+			"system_auth": "{'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'dc1': '3'}",
+		}), "", nil
+	}
+
+	cc := old.DeepCopy()
+	nbNodes := int32(2)
+	cc.Spec.NbNodes = &nbNodes
+	errs := f.controller.ValidateCassandraCluster(cc, old)
+	if len(errs) != 1 || errs[0] != "datacenter dc1 would have 2 nodes, lower than the replication factor 3 of keyspace orders" {
+		t.Errorf("got %v, want the scale down below the replication factor of orders rejected", errs)
+	}
+
+	// the nodes added aren't checked
+	f.commands = nil
+	nbNodes = 4
+	if errs := f.controller.ValidateCassandraCluster(cc, old); len(errs) != 0 || len(f.commands) != 0 {
+		t.Errorf("got %v after %v, want the scale up accepted", errs, f.commands)
+	}
+
+	// the replication of a cluster not running can't be checked
+	nbNodes = 2
+	f.cqlsh = func(podName string, cmd []string) (string, string, error) {
+		return "", "Connection refused", errors.New("command terminated with exit code 1")
+	}
+	if errs := f.controller.ValidateCassandraCluster(cc, old); len(errs) != 0 {
+		t.Errorf("got %v, want the scale down accepted", errs)
+	}
+}
+
+func TestParseReplication(t *testing.T) {
+	replication := parseReplication(replicationOutput(map[string]string{
+		"orders":      "{'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'dc1': '3', 'dc2': '1'}",
+		"system_auth": "{'class': 'org.apache.cassandra.locator.SimpleStrategy', 'replication_factor': '2'}",
+		"system":      "{'class': 'org.apache.cassandra.locator.LocalStrategy'}",
+	}))
+	legacy := parseLegacyReplication(" keyspace_name | strategy_class | strategy_options\n" +
+		"---------------+----------------+------------------\n" +
+		" orders | org.apache.cassandra.locator.NetworkTopologyStrategy | {\"dc1\":\"3\",\"dc2\":\"1\"}\n" +
+		" system_auth | org.apache.cassandra.locator.SimpleStrategy | {\"replication_factor\":\"2\"}\n" +
+		" system | org.apache.cassandra.locator.LocalStrategy | {}\n")
+	for _, got := range []map[string]map[string]int32{replication, legacy} {
+		if len(got) != 2 || len(got["orders"]) != 2 || got["orders"]["dc1"] != 3 || got["orders"]["dc2"] != 1 ||
+			len(got["system_auth"]) != 1 || got["system_auth"][""] != 2 {
+			t.Errorf("got %v", got)
+		}
+	}
+}
+
+func TestDefaultCassandraCluster(t *testing.T) {
+	cc := newCassandraCluster("test")
+	f := newFixture(t, cc)
+
+	created := cc.DeepCopy()
+	f.controller.DefaultCassandraCluster(created, nil)
+	if config := created.Spec.CassandraSpec.Config; config.Authenticator != cassandrav1.PasswordAuthenticator || config.Authorizer != cassandrav1.CassandraAuthorizer {
+		t.Errorf("got %s and %s, want the authentication of the new clusters", config.Authenticator, config.Authorizer)
+	}
+	updated := cc.DeepCopy()
+	f.controller.DefaultCassandraCluster(updated, cc)
+	if config := updated.Spec.CassandraSpec.Config; config.Authenticator != "" || config.Authorizer != "" {
+		t.Errorf("got %s and %s, want the authentication of the deployed cluster unchanged", config.Authenticator, config.Authorizer)
+	}
+	if updated.Spec.CassandraSpec.NbToken != cassandrav1.DefaultNbToken {
+		t.Errorf("got nbToken %d, want the default", updated.Spec.CassandraSpec.NbToken)
+	}
+}
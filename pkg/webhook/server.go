@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+const (
+	// ValidatePath is the path of the validating webhook
+	ValidatePath = "/validate"
+	// MutatePath is the path of the defaulting webhook
+	MutatePath = "/mutate"
+)
+
+// pointerEscaper escapes the keys in the paths of a JSON patch
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Validator checks the CassandraCluster resources submitted to the API server
+type Validator interface {
+	// ValidateCassandraCluster returns the reasons why the CassandraCluster is rejected.
+	// old is the current version of the resource on update, nil on creation
+	ValidateCassandraCluster(cc *cassandrav1.CassandraCluster, old *cassandrav1.CassandraCluster) []string
+}
+
+// Defaulter fills the fields of the CassandraCluster resources left empty
+type Defaulter interface {
+	// DefaultCassandraCluster fills the defaults of the CassandraCluster.
+	// old is the current version of the resource on update, nil on creation
+	DefaultCassandraCluster(cc *cassandrav1.CassandraCluster, old *cassandrav1.CassandraCluster)
+}
+
+// Server is the HTTPS server receiving the admission reviews of the API server
+type Server struct {
+	server    *http.Server
+	certFile  string
+	keyFile   string
+	validator Validator
+	defaulter Defaulter
+}
+
+// NewServer returns a webhook server listening on the address with the certificate and key files
+func NewServer(addr string, certFile string, keyFile string, validator Validator, defaulter Defaulter) *Server {
+	s := &Server{
+		certFile:  certFile,
+		keyFile:   keyFile,
+		validator: validator,
+		defaulter: defaulter,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, s.serve(s.validate))
+	mux.HandleFunc(MutatePath, s.serve(s.mutate))
+	s.server = &http.Server{Addr: addr, Handler: mux}
+	return s
+}
+
+// Run serves the admission reviews until the stop channel is closed
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	}()
+	glog.Infof("Starting the admission webhook on %s", s.server.Addr)
+	if err := s.server.ListenAndServeTLS(s.certFile, s.keyFile); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// serve decodes the admission reviews and answers them with the response of the admit function
+func (s *Server) serve(admit func(*admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		review := admissionv1beta1.AdmissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("invalid admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+		review.Response = admit(review.Request)
+		review.Response.UID = review.Request.UID
+		review.Request = nil
+
+		resp, err := json.Marshal(review)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+func (s *Server) validate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if req.Kind.Kind != "CassandraCluster" {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+	cc, old, err := decode(req)
+	if err != nil {
+		return denied(err.Error())
+	}
+	if errs := s.validator.ValidateCassandraCluster(cc, old); len(errs) > 0 {
+		glog.Infof("CassandraCluster %s/%s rejected: %s", req.Namespace, cc.Name, strings.Join(errs, "; "))
+		return denied(strings.Join(errs, "; "))
+	}
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+// mutate returns a JSON patch setting the defaulted fields of the spec, the fields set concurrently by other
+// mutating webhooks are left untouched
+func (s *Server) mutate(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if req.Kind.Kind != "CassandraCluster" {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+	cc, old, err := decode(req)
+	if err != nil {
+		return denied(err.Error())
+	}
+	before, err := toJSONObject(cc.Spec)
+	if err != nil {
+		return denied(err.Error())
+	}
+	s.defaulter.DefaultCassandraCluster(cc, old)
+	after, err := toJSONObject(cc.Spec)
+	if err != nil {
+		return denied(err.Error())
+	}
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(req.Object.Raw, &raw); err != nil {
+		return denied(fmt.Sprintf("could not decode the CassandraCluster: %v", err))
+	}
+	operations := specPatch(raw, before, after)
+	if len(operations) == 0 {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+	patch, err := json.Marshal(operations)
+	if err != nil {
+		return denied(err.Error())
+	}
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
+
+// decode returns the CassandraCluster of the request, and its current version on update
+func decode(req *admissionv1beta1.AdmissionRequest) (*cassandrav1.CassandraCluster, *cassandrav1.CassandraCluster, error) {
+	cc := &cassandrav1.CassandraCluster{}
+	if err := json.Unmarshal(req.Object.Raw, cc); err != nil {
+		return nil, nil, fmt.Errorf("could not decode the CassandraCluster: %v", err)
+	}
+	if req.Operation != admissionv1beta1.Update {
+		return cc, nil, nil
+	}
+	old := &cassandrav1.CassandraCluster{}
+	if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+		return nil, nil, fmt.Errorf("could not decode the current CassandraCluster: %v", err)
+	}
+	return cc, old, nil
+}
+
+// patchOperation is an operation of a JSON patch
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// toJSONObject returns the JSON object of the value
+func toJSONObject(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	object := map[string]interface{}{}
+	return object, json.Unmarshal(data, &object)
+}
+
+// specPatch returns the operations adding the fields of the spec changed by the defaults to the raw resource
+func specPatch(raw map[string]interface{}, before map[string]interface{}, after map[string]interface{}) []patchOperation {
+	spec, ok := raw["spec"].(map[string]interface{})
+	if !ok {
+		return []patchOperation{{Op: "add", Path: "/spec", Value: after}}
+	}
+	return objectPatch("/spec", spec, before, after)
+}
+
+// objectPatch returns the operations adding the fields of after which differ from before. The fields are added to
+// the objects existing in the raw resource, the missing objects are added whole
+func objectPatch(path string, raw map[string]interface{}, before map[string]interface{}, after map[string]interface{}) []patchOperation {
+	keys := make([]string, 0, len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var operations []patchOperation
+	for _, key := range keys {
+		if reflect.DeepEqual(before[key], after[key]) {
+			continue
+		}
+		fieldPath := path + "/" + pointerEscaper.Replace(key)
+		rawField, rawOK := raw[key].(map[string]interface{})
+		beforeField, beforeOK := before[key].(map[string]interface{})
+		afterField, afterOK := after[key].(map[string]interface{})
+		if rawOK && beforeOK && afterOK {
+			operations = append(operations, objectPatch(fieldPath, rawField, beforeField, afterField)...)
+			continue
+		}
+		// add replaces the value of an existing field
+		operations = append(operations, patchOperation{Op: "add", Path: fieldPath, Value: after[key]})
+	}
+	return operations
+}
+
+func denied(message string) *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: message,
+		},
+	}
+}
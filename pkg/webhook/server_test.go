@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cassandrav1 "github.com/vgkowski/cassandra-operator/pkg/apis/cassandra/v1"
+)
+
+// fakeDefaulter sets the image, and the authenticator on creation
+type fakeDefaulter struct{}
+
+func (fakeDefaulter) DefaultCassandraCluster(cc *cassandrav1.CassandraCluster, old *cassandrav1.CassandraCluster) {
+	if cc.Spec.BaseImage == "" {
+		cc.Spec.BaseImage = "cassandra:3.11"
+	}
+	if old == nil {
+		cc.Spec.CassandraSpec.Config.Authenticator = cassandrav1.PasswordAuthenticator
+	}
+}
+
+func mutateRequest(t *testing.T, operation admissionv1beta1.Operation, raw string) []patchOperation {
+	s := NewServer(":0", "", "", nil, fakeDefaulter{})
+	req := &admissionv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: "CassandraCluster"},
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: []byte(raw)},
+		OldObject: runtime.RawExtension{Raw: []byte(raw)},
+	}
+	resp := s.mutate(req)
+	if !resp.Allowed {
+		t.Fatalf("denied: %v", resp.Result)
+	}
+	var operations []patchOperation
+	if resp.Patch != nil {
+		if err := json.Unmarshal(resp.Patch, &operations); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return operations
+}
+
+func TestMutate(t *testing.T) {
+	tests := []struct {
+		operation admissionv1beta1.Operation
+		raw       string
+		paths     []string
+	}{
+		// the missing objects are added whole
+		{admissionv1beta1.Create, `{"spec": {"nbNodes": 3}}`, []string{"/spec/baseImage", "/spec/spec"}},
+		{admissionv1beta1.Create, `{"metadata": {"name": "test"}}`, []string{"/spec"}},
+		{
+			admissionv1beta1.Create,
+			`{"spec": {"baseImage": "cassandra:4.0", "spec": {"config": {"authorizer": "AllowAllAuthorizer"}}}}`,
+			[]string{"/spec/spec/config/authenticator"},
+		},
+		// the authentication of the deployed clusters isn't changed
+		{admissionv1beta1.Update, `{"spec": {"baseImage": "cassandra:4.0"}}`, nil},
+		{admissionv1beta1.Update, `{"spec": {"nbNodes": 3}}`, []string{"/spec/baseImage"}},
+	}
+	for _, test := range tests {
+		operations := mutateRequest(t, test.operation, test.raw)
+		var paths []string
+		for _, operation := range operations {
+			if operation.Op != "add" {
+				t.Errorf("%s %s: unexpected %s operation", test.operation, test.raw, operation.Op)
+			}
+			paths = append(paths, operation.Path)
+		}
+		if !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("%s %s: got the patch of %v, want %v", test.operation, test.raw, paths, test.paths)
+		}
+	}
+}
+
+func TestMutateValues(t *testing.T) {
+	operations := mutateRequest(t, admissionv1beta1.Create, `{"spec": {"nbNodes": 3, "spec": {"config": {}}}}`)
+	want := []patchOperation{
+		{Op: "add", Path: "/spec/baseImage", Value: "cassandra:3.11"},
+		{Op: "add", Path: "/spec/spec/config/authenticator", Value: cassandrav1.PasswordAuthenticator},
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("got %+v, want %+v", operations, want)
+	}
+}